@@ -1,26 +1,71 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"os"
 
 	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/blockingcacheclient"
-	"gopkg.in/yaml.v3"
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/cli"
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/reloader"
 
 	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/config"
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/metrics"
 	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/namecache"
 	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/syncer"
 	"github.com/loft-sh/vcluster-sdk/plugin"
-	"github.com/loft-sh/vcluster-sdk/translate"
-	corev1 "k8s.io/api/core/v1"
+	sdksyncer "github.com/loft-sh/vcluster-sdk/syncer"
+	synccontext "github.com/loft-sh/vcluster-sdk/syncer/context"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/klog"
 )
 
 const (
+	// ConfigurationEnvVar holds the configuration itself, it cannot be changed at runtime
 	ConfigurationEnvVar = "CONFIG"
+	// ConfigurationFileEnvVar holds the path to a configuration file that is watched for changes
+	ConfigurationFileEnvVar = "CONFIG_FILE"
+	// ConfigurationConfigMapEnvVar holds the name of a ConfigMap in the vcluster namespace
+	// that contains the configuration and is watched for changes
+	ConfigurationConfigMapEnvVar = "CONFIG_CONFIGMAP"
+	// ConfigurationConfigMapKeyEnvVar holds the key of the configuration within the ConfigMap
+	ConfigurationConfigMapKeyEnvVar = "CONFIG_CONFIGMAP_KEY"
+
+	// MetricsBindAddressEnvVar holds the address the prometheus metrics are served on, e.g. :8080.
+	// Metrics are not served if it is empty
+	MetricsBindAddressEnvVar = "METRICS_BIND_ADDRESS"
+	// LogVerbosityEnvVar holds the klog verbosity of the plugin, e.g. 4 to log the patch traces
+	// of all objects
+	LogVerbosityEnvVar = "LOG_VERBOSITY"
+
+	DefaultConfigurationConfigMapKey = "config.yaml"
 )
 
+// eventForwarderState is shared by the event forwarders of all configurations, so that
+// host events are not forwarded again each time the configuration is reloaded
+var eventForwarderState = syncer.NewEventForwarderState()
+
 func main() {
+	// run the offline tools if a subcommand is given
+	if rootCmd := cli.NewRootCmd(); len(os.Args) > 1 && cli.IsSubcommand(rootCmd, os.Args[1]) {
+		err := rootCmd.Execute()
+		if err != nil {
+			klog.Fatal(err)
+		}
+		return
+	}
+
+	// set the log verbosity, as the plugin doesn't parse any flags
+	if verbosity := os.Getenv(LogVerbosityEnvVar); verbosity != "" {
+		flags := flag.NewFlagSet("klog", flag.ContinueOnError)
+		klog.InitFlags(flags)
+		err := flags.Set("v", verbosity)
+		if err != nil {
+			klog.Fatalf("Error setting log verbosity %s: %v", verbosity, err)
+		}
+	}
+
 	// init plugin
 	registerCtx, err := plugin.InitWithOptions(plugin.Options{
 		NewClient: blockingcacheclient.NewCacheClient,
@@ -29,133 +74,183 @@ func main() {
 		klog.Fatalf("Error initializing plugin: %v", err)
 	}
 
-	c := os.Getenv(ConfigurationEnvVar)
-	if c == "" {
-		klog.Warningf("The %s environment variable is empty, no configuration has been loaded", ConfigurationEnvVar)
-	} else {
-		klog.Infof("Loading configuration:\n%s", c) //dev
-		configuration, err := config.ParseConfig(c)
-		if err != nil {
-			klog.Fatal(err)
+	// load the configuration from a file, a config map or the environment
+	var source reloader.Source
+	if path := os.Getenv(ConfigurationFileEnvVar); path != "" {
+		source = reloader.NewFileSource(path)
+	} else if name := os.Getenv(ConfigurationConfigMapEnvVar); name != "" {
+		key := os.Getenv(ConfigurationConfigMapKeyEnvVar)
+		if key == "" {
+			key = DefaultConfigurationConfigMapKey
 		}
-		loadedConfig, err := yaml.Marshal(configuration)
+
+		source = reloader.NewConfigMapSource(registerCtx.PhysicalManager, types.NamespacedName{Namespace: registerCtx.CurrentNamespace, Name: name}, key)
+	} else if c := os.Getenv(ConfigurationEnvVar); c != "" {
+		source = reloader.NewStaticSource(c)
+	}
+
+	if source == nil {
+		klog.Warningf("The %s, %s and %s environment variables are empty, no configuration has been loaded", ConfigurationEnvVar, ConfigurationFileEnvVar, ConfigurationConfigMapEnvVar)
+	} else {
+		err = plugin.Register(reloader.NewReloader(source, createSyncers, blockingcacheclient.NewCacheClient))
 		if err != nil {
-			klog.Fatal(err)
+			klog.Fatalf("Error registering config reloader: %v", err)
 		}
-		klog.Infof("Loaded configuration:\n%s", string(loadedConfig)) //dev
-
-		// TODO: efficiently sync all mapped CRDs from the host to vcluster or perhaps this should be a separate controller that will watch CRDs and sync changes
-		for _, m := range configuration.Mappings {
-			if m.FromVirtualCluster != nil {
-				if !plugin.Scheme.Recognizes(schema.FromAPIVersionAndKind(m.FromVirtualCluster.APIVersion, m.FromVirtualCluster.Kind)) {
-					err := translate.EnsureCRDFromPhysicalCluster(registerCtx.Context, registerCtx.PhysicalManager.GetConfig(), registerCtx.VirtualManager.GetConfig(), schema.FromAPIVersionAndKind(m.FromVirtualCluster.APIVersion, m.FromVirtualCluster.Kind))
-					if err != nil {
-						klog.Fatalf("Error syncronizing CRD %s(%s) from the host cluster into vcluster: %v", m.FromVirtualCluster.Kind, m.FromVirtualCluster.APIVersion, err)
-					}
-				}
+	}
 
-				for _, c := range m.FromVirtualCluster.SyncBack {
-					if !plugin.Scheme.Recognizes(schema.FromAPIVersionAndKind(m.FromVirtualCluster.APIVersion, m.FromVirtualCluster.Kind)) {
-						err := translate.EnsureCRDFromPhysicalCluster(registerCtx.Context, registerCtx.PhysicalManager.GetConfig(), registerCtx.VirtualManager.GetConfig(), schema.FromAPIVersionAndKind(c.APIVersion, c.Kind))
-						if err != nil {
-							klog.Fatalf("Error syncronizing CRD %s(%s) from the host cluster into vcluster: %v", m.FromVirtualCluster.Kind, m.FromVirtualCluster.APIVersion, err)
-						}
-					}
-				}
+	// serve metrics
+	if addr := os.Getenv(MetricsBindAddressEnvVar); addr != "" {
+		go func() {
+			err := metrics.ListenAndServe(addr)
+			if err != nil {
+				klog.Fatalf("Error serving metrics on %s: %v", addr, err)
 			}
-		}
+		}()
+	}
 
-		// create a single name cache
-		nc, err := namecache.NewNameCache(registerCtx.Context, registerCtx.VirtualManager, configuration)
+	// start plugin
+	err = plugin.Start()
+	if err != nil {
+		klog.Fatalf("Error starting plugin: %v", err)
+	}
+}
+
+// createSyncers creates all syncers for the given configuration. It is called
+// again with a new register context each time the configuration changes.
+func createSyncers(registerCtx *synccontext.RegisterContext, configuration *config.Config) ([]sdksyncer.Base, error) {
+	syncers := []sdksyncer.Base{}
+
+	// sync all mapped CRDs from the host to vcluster before the other syncers are created
+	// and keep them in sync with the host cluster
+	crdGVKs := mappedCRDs(configuration)
+	if len(crdGVKs) > 0 {
+		s, err := syncer.CreateCRDSyncer(registerCtx, crdGVKs)
 		if err != nil {
-			klog.Fatal("Error seting up namecache for a mapping ", err)
+			return nil, fmt.Errorf("error creating CRD syncer: %v", err)
 		}
 
-		forceSyncSecrets := []syncer.ForceSyncConfig{}
-		forceSyncConfigmaps := []syncer.ForceSyncConfig{}
+		syncers = append(syncers, s)
+	}
 
-		for _, m := range configuration.Mappings {
-			if m.FromVirtualCluster != nil {
-				s, err := syncer.CreateFromVirtualSyncer(registerCtx, m.FromVirtualCluster, nc)
-				if err != nil {
-					klog.Fatalf("Error creating %s(%s) syncer: %v", m.FromVirtualCluster.Kind, m.FromVirtualCluster.APIVersion, err)
-				}
+	// create a single name cache
+	nc, err := namecache.NewNameCache(registerCtx.Context, registerCtx.VirtualManager, configuration)
+	if err != nil {
+		return nil, fmt.Errorf("error seting up namecache for a mapping: %v", err)
+	}
 
-				err = plugin.Register(s)
-				if err != nil {
-					klog.Fatalf("Error registering %s(%s) syncer: %v", m.FromVirtualCluster.Kind, m.FromVirtualCluster.APIVersion, err)
-				}
+	// referenced objects that should be synced by vcluster, grouped by kind
+	forceSyncKinds := []schema.GroupVersionKind{}
+	forceSyncConfigs := map[schema.GroupVersionKind][]syncer.ForceSyncConfig{}
 
-				// check if this mapping uses Secret/Configmap sync
-				for _, p := range m.FromVirtualCluster.Patches {
-					if p.Sync != nil && p.Sync.Secret != nil && *p.Sync.Secret {
-						forceSyncSecrets = append(forceSyncSecrets, syncer.ForceSyncConfig{
-							Parent: *m.FromVirtualCluster,
-							Patch:  *p,
-						})
-					}
-					if p.Sync != nil && p.Sync.ConfigMap != nil && *p.Sync.ConfigMap {
-						forceSyncConfigmaps = append(forceSyncConfigmaps, syncer.ForceSyncConfig{
-							Parent: *m.FromVirtualCluster,
-							Patch:  *p,
-						})
-					}
+	for _, m := range configuration.Mappings {
+		if m.FromVirtualCluster != nil {
+			s, err := syncer.CreateFromVirtualSyncer(registerCtx, m.FromVirtualCluster, nc, configuration.IsDryRun(m))
+			if err != nil {
+				return nil, fmt.Errorf("error creating %s(%s) syncer: %v", m.FromVirtualCluster.Kind, m.FromVirtualCluster.APIVersion, err)
+			}
+
+			syncers = append(syncers, s)
+
+			// check if this mapping uses the sync of referenced objects
+			for _, p := range m.FromVirtualCluster.Patches {
+				if p.Sync == nil {
+					continue
 				}
 
-				for _, c := range m.FromVirtualCluster.SyncBack {
-					backSyncer, err := syncer.CreateBackSyncer(registerCtx, c, m.FromVirtualCluster, nc)
-					if err != nil {
-						klog.Fatalf("Error creating %s(%s) backsyncer: %v", m.FromVirtualCluster.Kind, m.FromVirtualCluster.APIVersion, err)
+				// a kind might be listed twice, e.g. with secret: true and kind: Secret
+				added := map[schema.GroupVersionKind]bool{}
+				for _, gvk := range p.Sync.GVKs() {
+					if added[gvk] {
+						continue
 					}
+					added[gvk] = true
 
-					err = plugin.Register(backSyncer)
-					if err != nil {
-						klog.Fatalf("Error registering %s(%s) syncer: %v", m.FromVirtualCluster.Kind, m.FromVirtualCluster.APIVersion, err)
+					if _, ok := forceSyncConfigs[gvk]; !ok {
+						forceSyncKinds = append(forceSyncKinds, gvk)
 					}
+					forceSyncConfigs[gvk] = append(forceSyncConfigs[gvk], syncer.ForceSyncConfig{
+						Parent: *m.FromVirtualCluster,
+						Patch:  *p,
+					})
 				}
 			}
-		}
 
-		if len(forceSyncSecrets) > 0 {
-			if containsStr(registerCtx.Options.Controllers, "-secrets") {
-				klog.Fatalf("The Secret sync is being used in the configuration, but vcluster Secret syncer is disabled.")
-			}
-			s, err := syncer.CreateForceSyncController(registerCtx, corev1.SchemeGroupVersion.WithKind("Secret"), forceSyncSecrets, nc)
-			if err != nil {
-				klog.Fatalf("Error creating Secret ForceSyncController : %v", err)
+			for _, c := range m.FromVirtualCluster.SyncBack {
+				backSyncer, err := syncer.CreateBackSyncer(registerCtx, c, m.FromVirtualCluster, nc, configuration.IsDryRun(m))
+				if err != nil {
+					return nil, fmt.Errorf("error creating %s(%s) backsyncer: %v", m.FromVirtualCluster.Kind, m.FromVirtualCluster.APIVersion, err)
+				}
+
+				syncers = append(syncers, backSyncer)
 			}
-			err = plugin.Register(s)
+		} else if m.FromHostCluster != nil {
+			s, err := syncer.CreateFromHostSyncer(registerCtx, m.FromHostCluster, nc, configuration.IsDryRun(m))
 			if err != nil {
-				klog.Fatalf("Error registering Secret ForceSyncController: %v", err)
+				return nil, fmt.Errorf("error creating %s(%s) syncer: %v", m.FromHostCluster.Kind, m.FromHostCluster.APIVersion, err)
 			}
+
+			syncers = append(syncers, s)
 		}
-		if len(forceSyncConfigmaps) > 0 {
-			if containsStr(registerCtx.Options.Controllers, "-configmaps") {
-				klog.Fatalf("The ConfigMap sync is being used in the configuration, but vcluster ConfigMap syncer is disabled.")
-			}
-			s, err := syncer.CreateForceSyncController(registerCtx, corev1.SchemeGroupVersion.WithKind("ConfigMap"), forceSyncSecrets, nc)
-			if err != nil {
-				klog.Fatalf("Error creating ConfigMap ForceSyncController: %v", err)
-			}
-			err = plugin.Register(s)
-			if err != nil {
-				klog.Fatalf("Error registering ConfigMap ForceSyncController: %v", err)
-			}
+	}
+
+	for _, gvk := range forceSyncKinds {
+		err := syncer.ValidateForceSyncKind(registerCtx.Options, gvk)
+		if err != nil {
+			return nil, err
+		}
+
+		// kinds that vcluster always syncs don't need the force sync annotation
+		if !syncer.NeedsForceSyncController(gvk) {
+			continue
+		}
+
+		s, err := syncer.CreateForceSyncController(registerCtx, gvk, forceSyncConfigs[gvk], nc)
+		if err != nil {
+			return nil, fmt.Errorf("error creating %s ForceSyncController: %v", gvk.Kind, err)
 		}
+		syncers = append(syncers, s)
 	}
 
-	// start plugin
-	err = plugin.Start()
-	if err != nil {
-		klog.Fatalf("Error starting plugin: %v", err)
+	// forward the events of managed host objects to the virtual objects
+	if len(configuration.Mappings) > 0 {
+		s, err := syncer.CreateEventForwarder(registerCtx, configuration, nc, eventForwarderState)
+		if err != nil {
+			return nil, fmt.Errorf("error creating event forwarder: %v", err)
+		}
+
+		syncers = append(syncers, s)
 	}
+
+	return syncers, nil
 }
 
-func containsStr(arr []string, s string) bool {
-	for _, item := range arr {
-		if item == s {
-			return true
+// mappedCRDs returns the group version kinds of all mapped resources that are not
+// known to the plugin scheme and therefore need to be synced from the host cluster
+func mappedCRDs(configuration *config.Config) []schema.GroupVersionKind {
+	gvks := []schema.GroupVersionKind{}
+	add := func(apiVersion, kind string) {
+		gvk := schema.FromAPIVersionAndKind(apiVersion, kind)
+		if plugin.Scheme.Recognizes(gvk) {
+			return
+		}
+		for _, existing := range gvks {
+			if existing == gvk {
+				return
+			}
+		}
+		gvks = append(gvks, gvk)
+	}
+
+	for _, m := range configuration.Mappings {
+		if m.FromVirtualCluster != nil {
+			add(m.FromVirtualCluster.APIVersion, m.FromVirtualCluster.Kind)
+			for _, c := range m.FromVirtualCluster.SyncBack {
+				add(c.APIVersion, c.Kind)
+			}
+		} else if m.FromHostCluster != nil {
+			add(m.FromHostCluster.APIVersion, m.FromHostCluster.Kind)
 		}
 	}
-	return false
+
+	return gvks
 }
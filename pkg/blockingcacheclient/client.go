@@ -137,10 +137,23 @@ func (c *CacheClient) blockDelete(ctx context.Context, obj runtime.Object) error
 	})
 }
 
+// isDryRun returns true if the write was only dry run, which means the cache never
+// receives the object and there is nothing to wait for
+func isDryRun(dryRun []string) bool {
+	for _, d := range dryRun {
+		if d == metav1.DryRunAll {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *CacheClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
 	err := c.Client.Create(ctx, obj, opts...)
 	if err != nil {
 		return err
+	} else if isDryRun((&client.CreateOptions{}).ApplyOptions(opts).DryRun) {
+		return nil
 	}
 
 	return c.blockCreate(ctx, obj)
@@ -150,6 +163,8 @@ func (c *CacheClient) Patch(ctx context.Context, obj client.Object, patch client
 	err := c.Client.Patch(ctx, obj, patch, opts...)
 	if err != nil {
 		return err
+	} else if isDryRun((&client.PatchOptions{}).ApplyOptions(opts).DryRun) {
+		return nil
 	}
 
 	return c.blockUpdate(ctx, obj)
@@ -159,6 +174,8 @@ func (c *CacheClient) Update(ctx context.Context, obj client.Object, opts ...cli
 	err := c.Client.Update(ctx, obj, opts...)
 	if err != nil {
 		return err
+	} else if isDryRun((&client.UpdateOptions{}).ApplyOptions(opts).DryRun) {
+		return nil
 	}
 
 	return c.blockUpdate(ctx, obj)
@@ -168,6 +185,8 @@ func (c *CacheClient) Delete(ctx context.Context, obj client.Object, opts ...cli
 	err := c.Client.Delete(ctx, obj, opts...)
 	if err != nil {
 		return err
+	} else if isDryRun((&client.DeleteOptions{}).ApplyOptions(opts).DryRun) {
+		return nil
 	}
 
 	return c.blockDelete(ctx, obj)
@@ -190,6 +209,8 @@ func (c *CacheStatusClient) Update(ctx context.Context, obj client.Object, opts
 	err := c.Cache.Client.Status().Update(ctx, obj, opts...)
 	if err != nil {
 		return err
+	} else if isDryRun((&client.UpdateOptions{}).ApplyOptions(opts).DryRun) {
+		return nil
 	}
 
 	return c.Cache.blockUpdate(ctx, obj)
@@ -199,6 +220,8 @@ func (c *CacheStatusClient) Patch(ctx context.Context, obj client.Object, patch
 	err := c.Cache.Client.Status().Patch(ctx, obj, patch, opts...)
 	if err != nil {
 		return err
+	} else if isDryRun((&client.PatchOptions{}).ApplyOptions(opts).DryRun) {
+		return nil
 	}
 
 	return c.Cache.blockUpdate(ctx, obj)
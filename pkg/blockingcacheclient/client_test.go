@@ -0,0 +1,53 @@
+package blockingcacheclient
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDryRun(t *testing.T) {
+	ctx := context.Background()
+	existing := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "existing"}}
+	c := &CacheClient{
+		Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(existing).Build(),
+		scheme: scheme.Scheme,
+	}
+
+	// dry run writes never show up in the cache, so they must not block
+	start := time.Now()
+	err := c.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "missing"}}, client.DryRunAll)
+	assert.NilError(t, err)
+	err = c.Get(ctx, types.NamespacedName{Name: "missing"}, &corev1.Namespace{})
+	assert.Assert(t, kerrors.IsNotFound(err))
+
+	updated := existing.DeepCopy()
+	updated.Labels = map[string]string{"key": "value"}
+	err = c.Update(ctx, updated, client.DryRunAll)
+	assert.NilError(t, err)
+	err = c.Patch(ctx, updated, client.MergeFrom(existing), client.DryRunAll)
+	assert.NilError(t, err)
+	err = c.Status().Update(ctx, updated, client.DryRunAll)
+	assert.NilError(t, err)
+	err = c.Delete(ctx, existing, client.DryRunAll)
+	assert.NilError(t, err)
+	assert.Assert(t, time.Since(start) < time.Second, "dry run writes should not wait for the cache")
+
+	actual := &corev1.Namespace{}
+	assert.NilError(t, c.Get(ctx, types.NamespacedName{Name: "existing"}, actual))
+	assert.Equal(t, len(actual.Labels), 0)
+
+	// real writes wait until the object is in the cache
+	err = c.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "missing"}})
+	assert.NilError(t, err)
+	assert.NilError(t, c.Get(ctx, types.NamespacedName{Name: "missing"}, &corev1.Namespace{}))
+}
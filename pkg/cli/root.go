@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/config"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// NewRootCmd returns the command that holds all subcommands that can be used
+// without a cluster, e.g. to test a configuration before deploying it
+func NewRootCmd() *cobra.Command {
+	rootCmd := &cobra.Command{
+		Use:           "vcluster-generic-crd-plugin",
+		Short:         "Offline tools for the generic CRD plugin configuration",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	rootCmd.AddCommand(NewTranslateCmd())
+	rootCmd.AddCommand(NewValidateCmd())
+	return rootCmd
+}
+
+// IsSubcommand returns true if the given argument names a subcommand of the root command.
+// Other arguments, e.g. flags passed by vcluster to the plugin, are not meant for the cli
+func IsSubcommand(rootCmd *cobra.Command, arg string) bool {
+	for _, cmd := range rootCmd.Commands() {
+		if cmd.Name() == arg || cmd.HasAlias(arg) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// loadConfig reads and validates the plugin configuration from the given file
+func loadConfig(path string) (*config.Config, error) {
+	if path == "" {
+		return nil, fmt.Errorf("please specify a configuration file via --config")
+	}
+
+	rawConfig, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config %s: %v", path, err)
+	}
+
+	configuration, err := config.ParseConfig(string(rawConfig))
+	if err != nil {
+		return nil, fmt.Errorf("parse config %s: %v", path, err)
+	}
+
+	return configuration, nil
+}
+
+// readObject reads a single kubernetes object from the given yaml file
+func readObject(path string) (*unstructured.Unstructured, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read object %s: %v", path, err)
+	}
+
+	obj := &unstructured.Unstructured{}
+	err = yaml.Unmarshal(raw, &obj.Object)
+	if err != nil {
+		return nil, fmt.Errorf("parse object %s: %v", path, err)
+	} else if obj.GetAPIVersion() == "" || obj.GetKind() == "" {
+		return nil, fmt.Errorf("object %s is missing apiVersion or kind", path)
+	}
+
+	return obj, nil
+}
@@ -0,0 +1,19 @@
+package cli
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestIsSubcommand(t *testing.T) {
+	rootCmd := NewRootCmd()
+	for _, arg := range []string{"translate", "validate"} {
+		assert.Assert(t, IsSubcommand(rootCmd, arg), "%s is a subcommand", arg)
+	}
+
+	// arguments that vcluster passes to the plugin are not meant for the cli
+	for _, arg := range []string{"", "--v=4", "-v", "start", "help"} {
+		assert.Assert(t, !IsSubcommand(rootCmd, arg), "%s is not a subcommand", arg)
+	}
+}
@@ -0,0 +1,162 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/config"
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/patches"
+	patchesregex "github.com/loft-sh/vcluster-generic-crd-plugin/pkg/patches/regex"
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/plugin"
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/syncer"
+	"github.com/loft-sh/vcluster-sdk/syncer/translator"
+	"github.com/loft-sh/vcluster-sdk/translate"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// TranslateCmd holds the flags of the translate command
+type TranslateCmd struct {
+	Config  string
+	Virtual string
+	Host    string
+	Reverse bool
+	ID      string
+
+	TargetNamespace string
+	Suffix          string
+}
+
+// NewTranslateCmd creates a new translate command
+func NewTranslateCmd() *cobra.Command {
+	cmd := &TranslateCmd{}
+	translateCmd := &cobra.Command{
+		Use:   "translate",
+		Short: "Previews the result of the patches of a fromVirtualCluster mapping",
+		Long: `Applies the patches of the fromVirtualCluster mapping that matches the
+given virtual object and prints the resulting host object. If --reverse is
+set, the reverse patches are applied to the virtual object with the given
+host object instead and the resulting virtual object is printed.
+
+No cluster is needed, names are translated the same way as in a vcluster
+with the given target namespace and suffix (the name of the vcluster).`,
+		Args: cobra.NoArgs,
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return cmd.Run(cobraCmd.OutOrStdout())
+		},
+	}
+
+	translateCmd.Flags().StringVar(&cmd.Config, "config", "", "The plugin configuration file")
+	translateCmd.Flags().StringVar(&cmd.Virtual, "virtual", "", "The virtual object yaml file")
+	translateCmd.Flags().StringVar(&cmd.Host, "host", "", "The host object yaml file, if the object already exists in the host cluster")
+	translateCmd.Flags().BoolVar(&cmd.Reverse, "reverse", false, "If enabled, applies the reverse patches and prints the virtual object")
+	translateCmd.Flags().StringVar(&cmd.ID, "id", "", "The id of the mapping to use, if there are multiple mappings for the kind")
+	translateCmd.Flags().StringVar(&cmd.TargetNamespace, "target-namespace", "vcluster", "The host namespace the vcluster syncs objects to")
+	translateCmd.Flags().StringVar(&cmd.Suffix, "suffix", "vcluster", "The name of the vcluster that is appended to translated names")
+	_ = translateCmd.MarkFlagRequired("config")
+	_ = translateCmd.MarkFlagRequired("virtual")
+	return translateCmd
+}
+
+// Run executes the command logic
+func (cmd *TranslateCmd) Run(out io.Writer) error {
+	translate.Suffix = cmd.Suffix
+
+	configuration, err := loadConfig(cmd.Config)
+	if err != nil {
+		return err
+	}
+
+	vObj, err := readObject(cmd.Virtual)
+	if err != nil {
+		return err
+	}
+
+	// make sure we don't pass a typed nil as other object
+	var pObj client.Object
+	if cmd.Host != "" {
+		pObj, err = readObject(cmd.Host)
+		if err != nil {
+			return err
+		}
+	} else if cmd.Reverse {
+		return fmt.Errorf("--reverse requires a host object via --host")
+	}
+
+	mapping, err := findFromVirtualMapping(configuration, vObj, cmd.ID)
+	if err != nil {
+		return err
+	}
+
+	var result client.Object
+	if cmd.Reverse {
+		result = vObj.DeepCopy()
+		err = patches.ApplyPatches(result, pObj, mapping.ReversePatches, nil, syncer.NewPhysicalNameResolver(syncer.NewHostToVirtualTranslator(vObj.GetNamespace(), cmd.TargetNamespace, vObj)), cmd.templateContext(vObj.GetNamespace(), mapping))
+		if err != nil {
+			return fmt.Errorf("error applying reverse patches: %v", err)
+		}
+	} else {
+		result = translator.TranslateMetadata(cmd.TargetNamespace, vObj)
+		err = patches.ApplyPatches(result, pObj, mapping.Patches, mapping.ReversePatches, syncer.NewVirtualToHostNameResolver(vObj.GetNamespace(), cmd.TargetNamespace), cmd.templateContext(vObj.GetNamespace(), mapping))
+		if err != nil {
+			return fmt.Errorf("error applying patches: %v", err)
+		}
+	}
+
+	raw, err := yaml.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	_, err = out.Write(raw)
+	return err
+}
+
+// templateContext returns the context for template patches as the syncer would create it
+func (cmd *TranslateCmd) templateContext(virtualNamespace string, mapping *config.FromVirtualCluster) *patches.TemplateContext {
+	controllerID := mapping.ID
+	if controllerID == "" {
+		controllerID = plugin.GetPluginName()
+	}
+
+	return &patches.TemplateContext{
+		VirtualNamespace: virtualNamespace,
+		HostNamespace:    cmd.TargetNamespace,
+		PluginName:       plugin.GetPluginName(),
+		ControllerID:     controllerID,
+	}
+}
+
+// findFromVirtualMapping returns the fromVirtualCluster mapping for the kind of the given object
+func findFromVirtualMapping(configuration *config.Config, obj *unstructured.Unstructured, id string) (*config.FromVirtualCluster, error) {
+	var found *config.FromVirtualCluster
+	for _, m := range configuration.Mappings {
+		if m.FromVirtualCluster == nil || m.FromVirtualCluster.APIVersion != obj.GetAPIVersion() || m.FromVirtualCluster.Kind != obj.GetKind() {
+			continue
+		} else if id != "" && m.FromVirtualCluster.ID != id {
+			continue
+		} else if found != nil {
+			return nil, fmt.Errorf("found multiple fromVirtualCluster mappings for %s(%s), please specify the mapping via --id", obj.GetKind(), obj.GetAPIVersion())
+		}
+
+		found = m.FromVirtualCluster
+	}
+	if found == nil {
+		return nil, fmt.Errorf("couldn't find a fromVirtualCluster mapping for %s(%s)", obj.GetKind(), obj.GetAPIVersion())
+	}
+
+	// the syncer parses the regular expressions when it is created
+	for _, p := range append(found.Patches, found.ReversePatches...) {
+		if p.Regex != "" {
+			parsed, err := patchesregex.PrepareRegex(p.Regex)
+			if err != nil {
+				return nil, fmt.Errorf("invalid Regex: %v", err)
+			}
+			p.ParsedRegex = parsed
+		}
+	}
+
+	return found, nil
+}
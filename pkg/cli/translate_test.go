@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/loft-sh/vcluster-sdk/translate"
+	"gotest.tools/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+const translateTestConfig = `version: v1beta1
+mappings:
+  - fromVirtualCluster:
+      apiVersion: cert-manager.io/v1
+      kind: Certificate
+      patches:
+        - op: rewriteName
+          path: spec.secretName
+      reversePatches:
+        - op: copyFromObject
+          fromPath: status
+          path: status
+`
+
+const translateTestVirtual = `apiVersion: cert-manager.io/v1
+kind: Certificate
+metadata:
+  name: certificate
+  namespace: default
+spec:
+  secretName: certificate-tls
+`
+
+// writeFile writes the content into a file within the temporary directory of the test
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.NilError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+// runTranslate runs the translate command and returns the printed object
+func runTranslate(t *testing.T, args ...string) *unstructured.Unstructured {
+	out := &bytes.Buffer{}
+	cmd := NewTranslateCmd()
+	cmd.SetArgs(args)
+	cmd.SetOut(out)
+	assert.NilError(t, cmd.Execute())
+
+	obj := &unstructured.Unstructured{}
+	assert.NilError(t, yaml.Unmarshal(out.Bytes(), &obj.Object))
+	return obj
+}
+
+func TestTranslateRoundTrip(t *testing.T) {
+	configPath := writeFile(t, "config.yaml", translateTestConfig)
+	virtualPath := writeFile(t, "virtual.yaml", translateTestVirtual)
+
+	// the virtual object is translated into the host object
+	pObj := runTranslate(t, "--config", configPath, "--virtual", virtualPath, "--target-namespace", "vcluster", "--suffix", "vcluster")
+	assert.Equal(t, pObj.GetNamespace(), "vcluster")
+	assert.Equal(t, pObj.GetName(), translate.PhysicalName("certificate", "default"))
+	secretName, _, _ := unstructured.NestedString(pObj.Object, "spec", "secretName")
+	assert.Equal(t, secretName, translate.PhysicalName("certificate-tls", "default"))
+
+	// the status of the host object is copied back onto the virtual object
+	assert.NilError(t, unstructured.SetNestedField(pObj.Object, "True", "status", "ready"))
+	raw, err := yaml.Marshal(pObj.Object)
+	assert.NilError(t, err)
+	hostPath := writeFile(t, "host.yaml", string(raw))
+
+	vObj := runTranslate(t, "--config", configPath, "--virtual", virtualPath, "--host", hostPath, "--reverse")
+	assert.Equal(t, vObj.GetNamespace(), "default")
+	assert.Equal(t, vObj.GetName(), "certificate")
+	secretName, _, _ = unstructured.NestedString(vObj.Object, "spec", "secretName")
+	assert.Equal(t, secretName, "certificate-tls")
+	ready, _, _ := unstructured.NestedString(vObj.Object, "status", "ready")
+	assert.Equal(t, ready, "True")
+}
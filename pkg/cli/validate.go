@@ -0,0 +1,191 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/config"
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/patches"
+	utilyaml "github.com/loft-sh/vcluster-generic-crd-plugin/pkg/util/yaml"
+	"github.com/spf13/cobra"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// ValidateCmd holds the flags of the validate command
+type ValidateCmd struct {
+	Config string
+	CRDs   []string
+}
+
+// NewValidateCmd creates a new validate command
+func NewValidateCmd() *cobra.Command {
+	cmd := &ValidateCmd{}
+	validateCmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Checks the patch paths of a configuration against CRD schemas",
+		Long: `Checks every path, fromPath, namePath, namespacePath and condition path
+of all patches against the structural schema of the CRD the mapping
+belongs to. Paths that can never match and rewriteName targets that are
+not strings are reported with their line in the configuration.
+
+Mappings for kinds without a given CRD, e.g. Secrets, are skipped.`,
+		Args: cobra.NoArgs,
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return cmd.Run(cobraCmd.OutOrStdout())
+		},
+	}
+
+	validateCmd.Flags().StringVar(&cmd.Config, "config", "", "The plugin configuration file")
+	validateCmd.Flags().StringSliceVar(&cmd.CRDs, "crd", []string{}, "The CRD manifest files or directories to load the schemas from")
+	_ = validateCmd.MarkFlagRequired("config")
+	_ = validateCmd.MarkFlagRequired("crd")
+	return validateCmd
+}
+
+// Run executes the command logic
+func (cmd *ValidateCmd) Run(out io.Writer) error {
+	configuration, err := loadConfig(cmd.Config)
+	if err != nil {
+		return err
+	}
+
+	rawConfig, err := os.ReadFile(cmd.Config)
+	if err != nil {
+		return err
+	}
+	lines, err := utilyaml.Lines(rawConfig)
+	if err != nil {
+		return err
+	}
+
+	schemas, err := loadCRDSchemas(cmd.CRDs)
+	if err != nil {
+		return err
+	}
+
+	problems := []string{}
+	check := func(typeInformation config.TypeInformation, field string, patchLists map[string][]*config.Patch) {
+		gvk := schema.FromAPIVersionAndKind(typeInformation.APIVersion, typeInformation.Kind)
+		crdSchema, ok := schemas[gvk]
+		if !ok {
+			fmt.Fprintf(out, "Skipping %s, because there is no CRD for %s(%s)\n", field, typeInformation.Kind, typeInformation.APIVersion)
+			return
+		}
+
+		for _, list := range []string{"patches", "reversePatches"} {
+			for idx, patch := range patchLists[list] {
+				patchField := fmt.Sprintf("%s.%s[%d]", field, list, idx)
+				for _, schemaErr := range patches.ValidatePatchSchema(crdSchema, patch) {
+					errorField := patchField + "." + schemaErr.Field
+					line, ok := lines[errorField]
+					if !ok {
+						line = lines[patchField]
+					}
+
+					problems = append(problems, fmt.Sprintf("line %d: %s: %s", line, errorField, schemaErr.Message))
+				}
+			}
+		}
+	}
+
+	for idx, mapping := range configuration.Mappings {
+		if mapping.FromVirtualCluster != nil {
+			field := fmt.Sprintf("mappings[%d].fromVirtualCluster", idx)
+			check(mapping.FromVirtualCluster.TypeInformation, field, map[string][]*config.Patch{
+				"patches":        mapping.FromVirtualCluster.Patches,
+				"reversePatches": mapping.FromVirtualCluster.ReversePatches,
+			})
+
+			for syncBackIdx, syncBack := range mapping.FromVirtualCluster.SyncBack {
+				check(syncBack.TypeInformation, fmt.Sprintf("%s.syncBack[%d]", field, syncBackIdx), map[string][]*config.Patch{
+					"patches":        syncBack.Patches,
+					"reversePatches": syncBack.ReversePatches,
+				})
+			}
+		} else if mapping.FromHostCluster != nil {
+			check(mapping.FromHostCluster.TypeInformation, fmt.Sprintf("mappings[%d].fromHostCluster", idx), map[string][]*config.Patch{
+				"patches":        mapping.FromHostCluster.Patches,
+				"reversePatches": mapping.FromHostCluster.ReversePatches,
+			})
+		}
+	}
+
+	if len(problems) > 0 {
+		fmt.Fprintln(out, strings.Join(problems, "\n"))
+		return fmt.Errorf("found %d problem(s) in configuration %s", len(problems), cmd.Config)
+	}
+
+	fmt.Fprintf(out, "Configuration %s is valid\n", cmd.Config)
+	return nil
+}
+
+// loadCRDSchemas loads the schemas of all CRD versions within the given files and directories
+func loadCRDSchemas(paths []string) (map[schema.GroupVersionKind]*apiextensionsv1.JSONSchemaProps, error) {
+	schemas := map[schema.GroupVersionKind]*apiextensionsv1.JSONSchemaProps{}
+	for _, path := range paths {
+		err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			} else if info.IsDir() {
+				return nil
+			}
+
+			ext := filepath.Ext(file)
+			if file != path && ext != ".yaml" && ext != ".yml" && ext != ".json" {
+				return nil
+			}
+
+			return loadCRDSchemasFromFile(file, schemas)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return schemas, nil
+}
+
+func loadCRDSchemasFromFile(file string, schemas map[schema.GroupVersionKind]*apiextensionsv1.JSONSchemaProps) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	decoder := kyaml.NewYAMLOrJSONDecoder(f, 4096)
+	for {
+		obj := map[string]interface{}{}
+		err := decoder.Decode(&obj)
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("parse %s: %v", file, err)
+		} else if obj["kind"] != "CustomResourceDefinition" {
+			continue
+		} else if obj["apiVersion"] != apiextensionsv1.SchemeGroupVersion.String() {
+			return fmt.Errorf("parse %s: only CRDs with apiVersion %s are supported", file, apiextensionsv1.SchemeGroupVersion.String())
+		}
+
+		crd := &apiextensionsv1.CustomResourceDefinition{}
+		err = runtime.DefaultUnstructuredConverter.FromUnstructured(obj, crd)
+		if err != nil {
+			return fmt.Errorf("parse %s: %v", file, err)
+		}
+
+		for _, version := range crd.Spec.Versions {
+			if version.Schema == nil || version.Schema.OpenAPIV3Schema == nil {
+				continue
+			}
+
+			gvk := schema.GroupVersionKind{Group: crd.Spec.Group, Version: version.Name, Kind: crd.Spec.Names.Kind}
+			schemas[gvk] = version.Schema.OpenAPIV3Schema
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"google.golang.org/protobuf/proto"
+)
+
+// CompileCEL compiles the given condition expression. Within the expression self is the
+// object that is patched, other is the other object and match is the currently selected
+// value of the patch path. Each of them is null if it doesn't exist.
+func CompileCEL(expression string) (cel.Program, error) {
+	env, err := cel.NewEnv(cel.Declarations(
+		decls.NewVar("self", decls.Dyn),
+		decls.NewVar("other", decls.Dyn),
+		decls.NewVar("match", decls.Dyn),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	} else if !proto.Equal(ast.ResultType(), decls.Bool) && !proto.Equal(ast.ResultType(), decls.Dyn) {
+		return nil, fmt.Errorf("expression needs to return a bool, but returns %v", ast.ResultType())
+	}
+
+	return env.Program(ast)
+}
@@ -1,6 +1,14 @@
 package config
 
-import "regexp"
+import (
+	"fmt"
+	"regexp"
+	"text/template"
+
+	"github.com/google/cel-go/cel"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
 
 const Version = "v1beta1"
 
@@ -10,9 +18,16 @@ type Config struct {
 
 	// Mappings defines a way to map a resource to another resource
 	Mappings []Mapping `yaml:"mappings,omitempty" json:"mappings,omitempty"`
+
+	// DryRun runs all writes of the syncers as server side dry run and only logs the
+	// resulting changes, e.g. to preview a new configuration. Can be overridden per mapping
+	DryRun bool `yaml:"dryRun,omitempty" json:"dryRun,omitempty"`
 }
 
 type Mapping struct {
+	// DryRun overrides the global dry run setting for this mapping
+	DryRun *bool `yaml:"dryRun,omitempty" json:"dryRun,omitempty"`
+
 	// FromHost syncs a resource from the host to the virtual cluster
 	FromHostCluster *FromHostCluster `yaml:"fromHostCluster,omitempty" json:"fromHostCluster,omitempty"`
 
@@ -34,6 +49,10 @@ type SyncBase struct {
 	// ReversePatches are the patches to apply to host cluster objects
 	// after it has been synced to the virtual cluster
 	ReversePatches []*Patch `yaml:"reversePatches,omitempty" json:"reversePatches,omitempty"`
+
+	// StatusSubresource defines if the status of the object is a subresource and has to be
+	// written separately. If empty, this is detected via the discovery of the host cluster.
+	StatusSubresource *bool `yaml:"statusSubresource,omitempty" json:"statusSubresource,omitempty"`
 }
 
 type FromVirtualCluster struct {
@@ -58,6 +77,31 @@ type SyncBack struct {
 type SyncBackSelector struct {
 	// Select object to sync based on its .metadata.name
 	Name *NameSyncBackSelector `yaml:"name,omitempty" json:"name,omitempty"`
+
+	// Select objects to sync based on their labels
+	LabelSelector *LabelSyncBackSelector `yaml:"labelSelector,omitempty" json:"labelSelector,omitempty"`
+
+	// Select objects to sync based on an owner reference to the parent host object
+	OwnerReference *OwnerReferenceSyncBackSelector `yaml:"ownerReference,omitempty" json:"ownerReference,omitempty"`
+}
+
+type OwnerReferenceSyncBackSelector struct {
+	// Controller only selects objects where the parent host object is the controller
+	Controller bool `yaml:"controller,omitempty" json:"controller,omitempty"`
+}
+
+type LabelSyncBackSelector struct {
+	// MatchLabels are labels with fixed values the host object needs to have
+	MatchLabels map[string]string `yaml:"matchLabels,omitempty" json:"matchLabels,omitempty"`
+
+	// FromParentLabels maps label keys of the host object to label keys of the
+	// parent host object. The host object is only selected if the label values
+	// are equal, e.g. for labels that an operator copies from the parent
+	FromParentLabels map[string]string `yaml:"fromParentLabels,omitempty" json:"fromParentLabels,omitempty"`
+
+	// ParentNameLabel is a label key of the host object that holds the name of
+	// the parent host object
+	ParentNameLabel string `yaml:"parentNameLabel,omitempty" json:"parentNameLabel,omitempty"`
 }
 
 type NameSyncBackSelector struct {
@@ -87,19 +131,52 @@ type TypeInformation struct {
 }
 
 type NameMapping struct {
-	// RewriteName defines
+	// RewriteName defines how the name and namespace of a host object are translated
+	// into the virtual cluster. Defaults to RewriteNameTypeKeepName
 	RewriteName RewriteNameType `yaml:"rewriteName,omitempty" json:"rewriteName,omitempty"`
 
 	// Namespace allows you to define a namespace the objects should get written to
 	// if policy is RewriteNameTypeKeepName
 	Namespace string `yaml:"namespace,omitempty" json:"namespace,omitempty"`
+
+	// Namespaces are the host namespaces that are synced and the virtual namespaces
+	// they are mapped to if policy is RewriteNameTypeFromHostToVirtualNamespace
+	Namespaces []NamespaceMapping `yaml:"namespaces,omitempty" json:"namespaces,omitempty"`
+}
+
+type NamespaceMapping struct {
+	// Host is the name of the host namespace
+	Host string `yaml:"host,omitempty" json:"host,omitempty"`
+
+	// Virtual is the name of the virtual namespace the objects of the host namespace
+	// are synced to. Defaults to the name of the host namespace
+	Virtual string `yaml:"virtual,omitempty" json:"virtual,omitempty"`
+}
+
+// VirtualNamespace returns the virtual namespace the host namespace is mapped to
+func (n NamespaceMapping) VirtualNamespace() string {
+	if n.Virtual != "" {
+		return n.Virtual
+	}
+
+	return n.Host
 }
 
 type RewriteNameType string
 
 const (
-	RewriteNameTypeKeepName                   = "KeepName"
+	// RewriteNameTypeKeepName keeps the name of the host object and writes it into
+	// NameMapping.Namespace within the virtual cluster. Only host objects within the
+	// vcluster target namespace or cluster scoped objects are synced.
+	RewriteNameTypeKeepName = "KeepName"
+	// RewriteNameTypeFromVirtualToHostNamespace reverses the vcluster name translation
+	// for host objects in the vcluster target namespace, e.g. a host object called
+	// NAME-x-NAMESPACE-x-VCLUSTER will be synced as NAMESPACE/NAME into the virtual cluster.
 	RewriteNameTypeFromVirtualToHostNamespace = "FromVirtualToHostNamespace"
+	// RewriteNameTypeFromHostToVirtualNamespace keeps the name of the host object and maps its
+	// namespace with NameMapping.Namespaces, e.g. a host object NAMESPACE/NAME will be synced as
+	// VIRTUAL_NAMESPACE/NAME into the virtual cluster. Only host objects from the mapped
+	// namespaces are synced.
 	RewriteNameTypeFromHostToVirtualNamespace = "FromHostToVirtualNamespace"
 )
 
@@ -124,9 +201,28 @@ type Patch struct {
 	// NamespacePath is path to the namespace of a child resource within Path
 	NamespacePath string `yaml:"namespacePath,omitempty" json:"namespacePath,omitempty"`
 
+	// Target is the kind of the objects referenced by a rewriteName patch, e.g. the
+	// CertificateRequest referenced in the status of a Certificate. Reverse patches
+	// resolve the names through the name cache of the fromVirtualCluster mapping of
+	// this kind or, if the kind is not mapped, e.g. for Secrets, by reversing the
+	// vcluster physical name translation. Names of a target are always resolved by the
+	// metadata.name of the target objects, the path of the patch is only used for the
+	// kind of the mapping itself. Defaults to the kind of the mapping
+	Target *TypeInformation `yaml:"target,omitempty" json:"target,omitempty"`
+
 	// Value is the new value to be set to the path
 	Value interface{} `yaml:"value,omitempty" json:"value,omitempty"`
 
+	// Template is a go template with sprig functions that is rendered by the template
+	// operation. The current object is the template root, the other object is available
+	// as .Other and information about the sync as .Context
+	Template       string             `yaml:"template,omitempty" json:"template,omitempty"`
+	ParsedTemplate *template.Template `yaml:"-" json:"-"`
+
+	// MergeKey is used by the merge operation to merge arrays of objects by the
+	// value of this key instead of replacing them, e.g. name for containers
+	MergeKey string `yaml:"mergeKey,omitempty" json:"mergeKey,omitempty"`
+
 	// Regex - is regular expresion used to identify the Name,
 	// and optionally Namespace, parts of the field value that
 	// will be replaced with the rewritten Name and/or Namespace
@@ -141,8 +237,51 @@ type Patch struct {
 	Ignore *bool `yaml:"ignore,omitempty" json:"ignore,omitempty"`
 
 	// Sync defines if a specialized syncer should be initialized using values
-	// from the rewriteName operation as Secret/Confgimap names to be synced
+	// from the rewriteName operation as names of objects to be synced by vcluster
 	Sync *PatchSync `yaml:"sync,omitempty" json:"sync,omitempty"`
+
+	// JSONPatch is a list of RFC 6902 operations applied by the jsonPatch operation
+	JSONPatch []*JSONPatchOperation `yaml:"jsonPatch,omitempty" json:"jsonPatch,omitempty"`
+}
+
+type JSONPatchOperation struct {
+	// Operation is one of add, remove, replace, move, copy or test
+	Operation string `yaml:"op" json:"op"`
+
+	// Path is the JSON pointer the operation is applied to
+	Path string `yaml:"path" json:"path"`
+
+	// From is the JSON pointer to the source value of move and copy
+	From string `yaml:"from,omitempty" json:"from,omitempty"`
+
+	// Value is the value for add, replace and test, which might be null as well
+	Value interface{} `yaml:"value" json:"value"`
+
+	// HasValue is true if the value was set in the configuration, even if it is null
+	HasValue bool `yaml:"-" json:"-"`
+}
+
+// UnmarshalYAML decodes the operation and records if the value key is present, as
+// yaml decodes a missing value and an explicit null value the same way
+func (o *JSONPatchOperation) UnmarshalYAML(node *yaml.Node) error {
+	type plain JSONPatchOperation
+	err := node.Decode((*plain)(o))
+	if err != nil {
+		return err
+	}
+
+	// the strict decoding of the configuration doesn't apply to custom unmarshalers
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		switch key := node.Content[i]; key.Value {
+		case "op", "path", "from":
+		case "value":
+			o.HasValue = true
+		default:
+			return fmt.Errorf("line %d: field %s not found in type config.JSONPatchOperation", key.Line, key.Value)
+		}
+	}
+
+	return nil
 }
 
 type PatchType string
@@ -154,6 +293,9 @@ const (
 	PatchTypeRewriteLabelExpressionsSelector = "rewriteLabelExpressionsSelector"
 
 	PatchTypeCopyFromObject = "copyFromObject"
+	PatchTypeJSONPatch      = "jsonPatch"
+	PatchTypeMerge          = "merge"
+	PatchTypeTemplate       = "template"
 	PatchTypeAdd            = "add"
 	PatchTypeReplace        = "replace"
 	PatchTypeRemove         = "remove"
@@ -163,6 +305,11 @@ type PatchCondition struct {
 	// Path is the path within the object to select
 	Path string `yaml:"path,omitempty" json:"path,omitempty"`
 
+	// Source is the object Path is evaluated against. Either self (default), which is
+	// the object that is patched, or other, which is the other object, e.g. the host
+	// object for reverse patches. If the other object doesn't exist, the path is missing
+	Source ConditionSource `yaml:"source,omitempty" json:"source,omitempty"`
+
 	// SubPath is the path below the selected object to select
 	SubPath string `yaml:"subPath,omitempty" json:"subPath,omitempty"`
 
@@ -174,8 +321,79 @@ type PatchCondition struct {
 
 	// Empty means that the path value should be empty or unset
 	Empty *bool `yaml:"empty,omitempty" json:"empty,omitempty"`
+
+	// Matches is a regular expression the path value should match
+	Matches       string         `yaml:"matches,omitempty" json:"matches,omitempty"`
+	ParsedMatches *regexp.Regexp `yaml:"-" json:"-"`
+
+	// In are the values of which the path value should be equal to one
+	In []interface{} `yaml:"in,omitempty" json:"in,omitempty"`
+
+	// NotIn are the values the path value should not be equal to
+	NotIn []interface{} `yaml:"notIn,omitempty" json:"notIn,omitempty"`
+
+	// Exists means that the path should exist if true or should not exist if false
+	Exists *bool `yaml:"exists,omitempty" json:"exists,omitempty"`
+
+	// AllOf are conditions that all need to be true
+	AllOf []*PatchCondition `yaml:"allOf,omitempty" json:"allOf,omitempty"`
+
+	// AnyOf are conditions of which at least one needs to be true
+	AnyOf []*PatchCondition `yaml:"anyOf,omitempty" json:"anyOf,omitempty"`
+
+	// Not is a condition that needs to be false
+	Not *PatchCondition `yaml:"not,omitempty" json:"not,omitempty"`
+
+	// CEL is a common expression language expression that needs to return true, e.g.
+	// self.spec.replicas > 1 && has(self.spec.tls). Besides self, the other object
+	// can be accessed via other and the value selected by the patch path via match.
+	// If the expression fails to evaluate, e.g. because a field is missing, the
+	// condition is not met
+	CEL       string      `yaml:"cel,omitempty" json:"cel,omitempty"`
+	ParsedCEL cel.Program `yaml:"-" json:"-"`
 }
+type ConditionSource string
+
+const (
+	ConditionSourceSelf  = "self"
+	ConditionSourceOther = "other"
+)
+
 type PatchSync struct {
 	Secret    *bool `yaml:"secret,omitempty" json:"secret,omitempty"`
 	ConfigMap *bool `yaml:"configmap,omitempty" json:"configmap,omitempty"`
+
+	// TypeInformation is the kind of the referenced objects, which needs
+	// to be a kind that is synced by vcluster itself, e.g. v1 ServiceAccount
+	TypeInformation `yaml:",inline" json:",inline"`
+}
+
+// IsDryRun returns if the writes of the syncers of the given mapping should only be dry run
+func (c *Config) IsDryRun(mapping Mapping) bool {
+	if mapping.DryRun != nil {
+		return *mapping.DryRun
+	}
+
+	return c.DryRun
+}
+
+// GVK returns the group version kind of the type
+func (t TypeInformation) GVK() schema.GroupVersionKind {
+	return schema.FromAPIVersionAndKind(t.APIVersion, t.Kind)
+}
+
+// GVKs returns the kinds of all objects that should be synced
+func (s *PatchSync) GVKs() []schema.GroupVersionKind {
+	gvks := []schema.GroupVersionKind{}
+	if s.Secret != nil && *s.Secret {
+		gvks = append(gvks, schema.GroupVersionKind{Version: "v1", Kind: "Secret"})
+	}
+	if s.ConfigMap != nil && *s.ConfigMap {
+		gvks = append(gvks, schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"})
+	}
+	if s.Kind != "" {
+		gvks = append(gvks, schema.FromAPIVersionAndKind(s.APIVersion, s.Kind))
+	}
+
+	return gvks
 }
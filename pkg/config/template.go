@@ -0,0 +1,13 @@
+package config
+
+import (
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+)
+
+// ParseTemplate parses the given template of a template patch with all sprig functions. Missing
+// map keys fail the rendering, optional fields can be accessed with hasKey or dig instead.
+func ParseTemplate(text string) (*template.Template, error) {
+	return template.New("patch").Funcs(sprig.TxtFuncMap()).Option("missingkey=error").Parse(text)
+}
@@ -2,6 +2,8 @@ package config
 
 import (
 	"fmt"
+	"regexp"
+	"strings"
 
 	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/util/yaml"
 	"github.com/pkg/errors"
@@ -29,8 +31,17 @@ func validate(config *Config) error {
 	}
 
 	for idx, mapping := range config.Mappings {
-		if mapping.FromVirtualCluster == nil {
-			return fmt.Errorf("mappings[%d].fromVirtualCluster is required", idx)
+		if mapping.FromVirtualCluster != nil && mapping.FromHostCluster != nil {
+			return fmt.Errorf("mappings[%d] can only define one of fromVirtualCluster or fromHostCluster", idx)
+		} else if mapping.FromHostCluster != nil {
+			err := validateFromHostCluster(mapping.FromHostCluster)
+			if err != nil {
+				return errors.Wrapf(err, "mappings[%d].fromHostCluster", idx)
+			}
+
+			continue
+		} else if mapping.FromVirtualCluster == nil {
+			return fmt.Errorf("mappings[%d].fromVirtualCluster or mappings[%d].fromHostCluster is required", idx, idx)
 		}
 		if mapping.FromVirtualCluster.Kind == "" {
 			return fmt.Errorf("mappings[%d].fromVirtualCluster.kind is required", idx)
@@ -65,6 +76,77 @@ func validate(config *Config) error {
 	return nil
 }
 
+func validateNamespaceMappings(namespaces []NamespaceMapping) error {
+	if len(namespaces) == 0 {
+		return fmt.Errorf("at least one namespace is required for nameMapping.rewriteName %s", RewriteNameTypeFromHostToVirtualNamespace)
+	}
+
+	// the mapping needs to be reversible, so every namespace may only be used once
+	hostNamespaces := map[string]bool{}
+	virtualNamespaces := map[string]bool{}
+	for idx, namespace := range namespaces {
+		if namespace.Host == "" {
+			return fmt.Errorf("[%d].host is required", idx)
+		} else if hostNamespaces[namespace.Host] {
+			return fmt.Errorf("[%d].host %s is mapped more than once", idx, namespace.Host)
+		} else if virtualNamespaces[namespace.VirtualNamespace()] {
+			return fmt.Errorf("[%d].virtual %s is mapped more than once", idx, namespace.VirtualNamespace())
+		}
+
+		hostNamespaces[namespace.Host] = true
+		virtualNamespaces[namespace.VirtualNamespace()] = true
+	}
+
+	return nil
+}
+
+func validateFromHostCluster(fromHost *FromHostCluster) error {
+	if fromHost.Kind == "" {
+		return fmt.Errorf("kind is required")
+	}
+	if fromHost.APIVersion == "" {
+		return fmt.Errorf("apiVersion is required")
+	}
+
+	switch fromHost.NameMapping.RewriteName {
+	case "", RewriteNameTypeKeepName:
+		if len(fromHost.NameMapping.Namespaces) > 0 {
+			return fmt.Errorf("nameMapping.namespaces is only supported for nameMapping.rewriteName %s", RewriteNameTypeFromHostToVirtualNamespace)
+		}
+	case RewriteNameTypeFromVirtualToHostNamespace:
+		if fromHost.NameMapping.Namespace != "" {
+			return fmt.Errorf("nameMapping.namespace is only supported for nameMapping.rewriteName %s", RewriteNameTypeKeepName)
+		} else if len(fromHost.NameMapping.Namespaces) > 0 {
+			return fmt.Errorf("nameMapping.namespaces is only supported for nameMapping.rewriteName %s", RewriteNameTypeFromHostToVirtualNamespace)
+		}
+	case RewriteNameTypeFromHostToVirtualNamespace:
+		if fromHost.NameMapping.Namespace != "" {
+			return fmt.Errorf("nameMapping.namespace is only supported for nameMapping.rewriteName %s", RewriteNameTypeKeepName)
+		}
+
+		err := validateNamespaceMappings(fromHost.NameMapping.Namespaces)
+		if err != nil {
+			return errors.Wrap(err, "nameMapping.namespaces")
+		}
+	default:
+		return fmt.Errorf("unsupported nameMapping.rewriteName %s", fromHost.NameMapping.RewriteName)
+	}
+
+	for patchIdx, patch := range fromHost.Patches {
+		err := validatePatch(patch)
+		if err != nil {
+			return errors.Wrapf(err, "patches[%d]", patchIdx)
+		}
+	}
+	for patchIdx, patch := range fromHost.ReversePatches {
+		err := validatePatch(patch)
+		if err != nil {
+			return errors.Wrapf(err, "reversePatches[%d]", patchIdx)
+		}
+	}
+	return nil
+}
+
 func validateSyncBack(syncBack *SyncBack, uniqueSyncBacks map[schema.GroupVersionKind]bool) error {
 	if syncBack.Kind == "" {
 		return fmt.Errorf("kind is required")
@@ -79,6 +161,13 @@ func validateSyncBack(syncBack *SyncBack, uniqueSyncBacks map[schema.GroupVersio
 	}
 	uniqueSyncBacks[gvk] = true
 
+	for selectorIdx, selector := range syncBack.Selectors {
+		err := validateSyncBackSelector(selector)
+		if err != nil {
+			return errors.Wrapf(err, "selectors[%d]", selectorIdx)
+		}
+	}
+
 	for patchIdx, patch := range syncBack.Patches {
 		err := validatePatch(patch)
 		if err != nil {
@@ -94,7 +183,45 @@ func validateSyncBack(syncBack *SyncBack, uniqueSyncBacks map[schema.GroupVersio
 	return nil
 }
 
+func validateSyncBackSelector(selector *SyncBackSelector) error {
+	selectorTypes := 0
+	for _, set := range []bool{selector.Name != nil, selector.LabelSelector != nil, selector.OwnerReference != nil} {
+		if set {
+			selectorTypes++
+		}
+	}
+	if selectorTypes > 1 {
+		return fmt.Errorf("only one of name, labelSelector or ownerReference can be used per selector")
+	}
+
+	if selector.LabelSelector != nil {
+		if len(selector.LabelSelector.FromParentLabels) == 0 && selector.LabelSelector.ParentNameLabel == "" {
+			return fmt.Errorf("labelSelector needs either fromParentLabels or parentNameLabel to find the parent object")
+		}
+	}
+
+	return nil
+}
+
 func validatePatch(patch *Patch) error {
+	if patch.Sync != nil && (patch.Sync.Kind == "") != (patch.Sync.APIVersion == "") {
+		return fmt.Errorf("sync.kind and sync.apiVersion need to be specified together")
+	}
+	if patch.Target != nil {
+		if patch.Operation != PatchTypeRewriteName {
+			return fmt.Errorf("target is only supported for the %s operation", PatchTypeRewriteName)
+		} else if patch.Target.Kind == "" || patch.Target.APIVersion == "" {
+			return fmt.Errorf("target.kind and target.apiVersion need to be specified")
+		}
+	}
+
+	for idx, condition := range patch.Conditions {
+		err := validateCondition(condition)
+		if err != nil {
+			return errors.Wrapf(err, "conditions[%d]", idx)
+		}
+	}
+
 	switch patch.Operation {
 	case PatchTypeRemove, PatchTypeReplace, PatchTypeAdd:
 		if patch.FromPath != "" {
@@ -109,8 +236,132 @@ func validatePatch(patch *Patch) error {
 			return fmt.Errorf("fromPath is required for this operation")
 		}
 
+		return nil
+	case PatchTypeMerge:
+		if patch.FromPath != "" {
+			return fmt.Errorf("fromPath is not supported for this operation")
+		} else if _, ok := patch.Value.(map[string]interface{}); !ok {
+			return fmt.Errorf("value needs to be an object for this operation")
+		}
+
+		return nil
+	case PatchTypeTemplate:
+		if patch.FromPath != "" {
+			return fmt.Errorf("fromPath is not supported for this operation")
+		} else if patch.Path == "" {
+			return fmt.Errorf("path is required for this operation")
+		} else if patch.Template == "" {
+			return fmt.Errorf("template is required for this operation")
+		}
+
+		tpl, err := ParseTemplate(patch.Template)
+		if err != nil {
+			return errors.Wrap(err, "parse template")
+		}
+
+		patch.ParsedTemplate = tpl
+		return nil
+	case PatchTypeJSONPatch:
+		if patch.Path != "" || patch.FromPath != "" {
+			return fmt.Errorf("path and fromPath are not supported for this operation, use the paths within jsonPatch instead")
+		} else if len(patch.JSONPatch) == 0 {
+			return fmt.Errorf("jsonPatch is required for this operation")
+		}
+
+		for idx, operation := range patch.JSONPatch {
+			err := validateJSONPatchOperation(operation)
+			if err != nil {
+				return errors.Wrapf(err, "jsonPatch[%d]", idx)
+			}
+		}
+
 		return nil
 	default:
 		return fmt.Errorf("unsupported patch type %s", patch.Operation)
 	}
 }
+
+func validateCondition(condition *PatchCondition) error {
+	if condition == nil {
+		return nil
+	}
+
+	switch condition.Source {
+	case "", ConditionSourceSelf:
+	case ConditionSourceOther:
+		if condition.SubPath != "" {
+			return fmt.Errorf("subPath cannot be used with source %s, use path instead", ConditionSourceOther)
+		}
+	default:
+		return fmt.Errorf("unsupported source %s", condition.Source)
+	}
+
+	// compile expressions only once here, so they don't need to be compiled
+	// each time the condition is evaluated
+	if condition.CEL != "" {
+		program, err := CompileCEL(condition.CEL)
+		if err != nil {
+			return errors.Wrap(err, "compile cel")
+		}
+
+		condition.ParsedCEL = program
+	}
+	if condition.Matches != "" {
+		regex, err := regexp.Compile(condition.Matches)
+		if err != nil {
+			return errors.Wrap(err, "compile matches")
+		}
+
+		condition.ParsedMatches = regex
+	}
+
+	for idx, subCondition := range condition.AllOf {
+		err := validateCondition(subCondition)
+		if err != nil {
+			return errors.Wrapf(err, "allOf[%d]", idx)
+		}
+	}
+	for idx, subCondition := range condition.AnyOf {
+		err := validateCondition(subCondition)
+		if err != nil {
+			return errors.Wrapf(err, "anyOf[%d]", idx)
+		}
+	}
+	if condition.Not != nil {
+		err := validateCondition(condition.Not)
+		if err != nil {
+			return errors.Wrap(err, "not")
+		}
+	}
+
+	return nil
+}
+
+func validateJSONPatchOperation(operation *JSONPatchOperation) error {
+	if operation == nil {
+		return fmt.Errorf("operation is empty")
+	} else if operation.Path != "" && !strings.HasPrefix(operation.Path, "/") {
+		return fmt.Errorf("path %s needs to be a JSON pointer starting with /", operation.Path)
+	}
+
+	switch operation.Operation {
+	case "add", "replace", "test":
+		if operation.From != "" {
+			return fmt.Errorf("from is not supported for op %s", operation.Operation)
+		} else if !operation.HasValue {
+			return fmt.Errorf("value is required for op %s", operation.Operation)
+		}
+	case "remove":
+		if operation.From != "" {
+			return fmt.Errorf("from is not supported for op %s", operation.Operation)
+		}
+	case "move", "copy":
+		if operation.From == "" {
+			return fmt.Errorf("from is required for op %s", operation.Operation)
+		}
+	default:
+		return fmt.Errorf("unsupported op %s", operation.Operation)
+	}
+
+	return nil
+}
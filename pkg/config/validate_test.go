@@ -0,0 +1,35 @@
+package config
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestParseJSONPatchValue(t *testing.T) {
+	rawConfig := `version: v1beta1
+mappings:
+- fromVirtualCluster:
+    apiVersion: cert-manager.io/v1
+    kind: Certificate
+    patches:
+    - op: jsonPatch
+      jsonPatch:
+      - op: replace
+        path: /spec/duration
+        value: null
+`
+	configuration, err := ParseConfig(rawConfig)
+	assert.NilError(t, err)
+	operation := configuration.Mappings[0].FromVirtualCluster.Patches[0].JSONPatch[0]
+	assert.Assert(t, operation.HasValue)
+	assert.Assert(t, operation.Value == nil)
+
+	// add, replace and test need a value, even if it is null
+	_, err = ParseConfig(rawConfig[:len(rawConfig)-len("        value: null\n")])
+	assert.ErrorContains(t, err, "value is required for op replace")
+
+	// unknown fields are rejected
+	_, err = ParseConfig(rawConfig + "        other: value\n")
+	assert.ErrorContains(t, err, "field other not found")
+}
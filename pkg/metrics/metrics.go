@@ -0,0 +1,150 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	namespace = "vcluster_generic_crd_plugin"
+
+	ControllerFromVirtual = "from-virtual"
+	ControllerFromHost    = "from-host"
+	ControllerBackSync    = "back-sync"
+	ControllerForceSync   = "force-sync"
+
+	ResultSuccess = "success"
+	ResultRequeue = "requeue"
+	ResultError   = "error"
+)
+
+var (
+	reconcileTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reconcile_total",
+		Help:      "Total number of reconciles per controller and result.",
+	}, []string{"controller", "gvk", "id", "result"})
+
+	reconcileDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "reconcile_duration_seconds",
+		Help:      "Duration of the reconciles per controller.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"controller", "gvk", "id"})
+
+	invalidRequeuesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "invalid_requeues_total",
+		Help:      "Total number of requeues, because the api server rejected a patched object as invalid.",
+	}, []string{"controller", "gvk", "id"})
+
+	patchFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "patch_failures_total",
+		Help:      "Total number of patches that failed to apply per operation.",
+	}, []string{"controller", "gvk", "id", "op"})
+
+	updateConflictsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "update_conflicts_total",
+		Help:      "Total number of conflicts while updating reverse patched objects.",
+	}, []string{"controller", "gvk", "id"})
+
+	nameCacheIndexSize = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "namecache_index_size",
+		Help:      "Number of keys in a name cache index.",
+	}, []string{"gvk", "index"})
+
+	nameCacheHookExecutionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "namecache_hook_executions_total",
+		Help:      "Total number of name cache change hook executions.",
+	}, []string{"gvk", "index"})
+)
+
+func init() {
+	mustRegister(ctrlmetrics.Registry)
+}
+
+// mustRegister registers the metrics of the plugin with the given registry
+func mustRegister(registry prometheus.Registerer) {
+	registry.MustRegister(
+		reconcileTotal,
+		reconcileDuration,
+		invalidRequeuesTotal,
+		patchFailuresTotal,
+		updateConflictsTotal,
+		nameCacheIndexSize,
+		nameCacheHookExecutionsTotal,
+	)
+}
+
+// ListenAndServe serves the metrics of the plugin and of controller-runtime on the given
+// address, as the metrics servers of the managers are disabled by the vcluster sdk
+func ListenAndServe(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(ctrlmetrics.Registry, promhttp.HandlerOpts{}))
+	return http.ListenAndServe(addr, mux)
+}
+
+// GVKLabel returns the value of the gvk label, e.g. cert-manager.io/v1/Certificate
+func GVKLabel(gvk schema.GroupVersionKind) string {
+	return gvk.GroupVersion().String() + "/" + gvk.Kind
+}
+
+// Controller holds the labels of the metrics that are recorded for a single controller
+type Controller struct {
+	name string
+	gvk  string
+	id   string
+}
+
+func NewController(name string, gvk schema.GroupVersionKind, id string) *Controller {
+	return &Controller{
+		name: name,
+		gvk:  GVKLabel(gvk),
+		id:   id,
+	}
+}
+
+// ObserveReconcile records the duration and the result of a reconcile that started at start
+func (c *Controller) ObserveReconcile(start time.Time, result ctrl.Result, err error) {
+	reconcileDuration.WithLabelValues(c.name, c.gvk, c.id).Observe(time.Since(start).Seconds())
+
+	label := ResultSuccess
+	if err != nil {
+		label = ResultError
+	} else if result.Requeue || result.RequeueAfter > 0 {
+		label = ResultRequeue
+	}
+	reconcileTotal.WithLabelValues(c.name, c.gvk, c.id, label).Inc()
+}
+
+func (c *Controller) InvalidRequeue() {
+	invalidRequeuesTotal.WithLabelValues(c.name, c.gvk, c.id).Inc()
+}
+
+func (c *Controller) PatchFailed(op string) {
+	patchFailuresTotal.WithLabelValues(c.name, c.gvk, c.id, op).Inc()
+}
+
+func (c *Controller) UpdateConflict() {
+	updateConflictsTotal.WithLabelValues(c.name, c.gvk, c.id).Inc()
+}
+
+// SetNameCacheIndexSize records the number of keys within an index of the name cache
+func SetNameCacheIndexSize(gvk schema.GroupVersionKind, index string, size int) {
+	nameCacheIndexSize.WithLabelValues(GVKLabel(gvk), index).Set(float64(size))
+}
+
+// NameCacheHookExecuted records a single execution of a name cache change hook
+func NameCacheHookExecuted(gvk schema.GroupVersionKind, index string) {
+	nameCacheHookExecutionsTotal.WithLabelValues(GVKLabel(gvk), index).Inc()
+}
@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"gotest.tools/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func TestController(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	mustRegister(registry)
+
+	c := NewController(ControllerFromVirtual, schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Certificate"}, "test-plugin")
+
+	// a successful sync
+	c.ObserveReconcile(time.Now(), ctrl.Result{}, nil)
+
+	// a sync whose update failed with a conflict
+	c.UpdateConflict()
+	c.ObserveReconcile(time.Now(), ctrl.Result{}, fmt.Errorf("update reverse: conflict"))
+
+	expected := `
+# HELP vcluster_generic_crd_plugin_reconcile_total Total number of reconciles per controller and result.
+# TYPE vcluster_generic_crd_plugin_reconcile_total counter
+vcluster_generic_crd_plugin_reconcile_total{controller="from-virtual",gvk="cert-manager.io/v1/Certificate",id="test-plugin",result="error"} 1
+vcluster_generic_crd_plugin_reconcile_total{controller="from-virtual",gvk="cert-manager.io/v1/Certificate",id="test-plugin",result="success"} 1
+# HELP vcluster_generic_crd_plugin_update_conflicts_total Total number of conflicts while updating reverse patched objects.
+# TYPE vcluster_generic_crd_plugin_update_conflicts_total counter
+vcluster_generic_crd_plugin_update_conflicts_total{controller="from-virtual",gvk="cert-manager.io/v1/Certificate",id="test-plugin"} 1
+`
+	err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "vcluster_generic_crd_plugin_reconcile_total", "vcluster_generic_crd_plugin_update_conflicts_total")
+	assert.NilError(t, err)
+
+	// every reconcile is observed
+	count, err := testutil.GatherAndCount(registry, "vcluster_generic_crd_plugin_reconcile_duration_seconds")
+	assert.NilError(t, err)
+	assert.Equal(t, count, 1)
+
+}
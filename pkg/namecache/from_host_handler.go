@@ -0,0 +1,67 @@
+package namecache
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// HostNameAnnotation is set on virtual objects that were synced from the host cluster
+	// and holds the name of the host object
+	HostNameAnnotation = "vcluster.loft.sh/host-name"
+	// HostNamespaceAnnotation is set on virtual objects that were synced from the host cluster
+	// and holds the namespace of the host object
+	HostNamespaceAnnotation = "vcluster.loft.sh/host-namespace"
+
+	// ControlledByLabel holds the id of the controller that manages an object
+	ControlledByLabel = "vcluster.loft.sh/controlled-by"
+)
+
+type fromHostClusterCacheHandler struct {
+	gvk       schema.GroupVersionKind
+	nameCache *nameCache
+
+	// controllerID is the id of the controller of the mapping
+	controllerID string
+}
+
+func (c *fromHostClusterCacheHandler) OnAdd(obj interface{}) {
+	unstructuredObj, ok := obj.(*unstructured.Unstructured)
+	if ok {
+		c.nameCache.ExchangeMapping(c.gvk, &IndexMappings{
+			Name:     unstructuredObj.GetNamespace() + "/" + unstructuredObj.GetName(),
+			Mappings: c.mappingsFromVirtualObject(unstructuredObj),
+		})
+	}
+}
+
+func (c *fromHostClusterCacheHandler) OnUpdate(oldObj, newObj interface{}) {
+	c.OnAdd(newObj)
+}
+
+func (c *fromHostClusterCacheHandler) OnDelete(obj interface{}) {
+	unstructuredObj, ok := obj.(*unstructured.Unstructured)
+	if ok {
+		c.nameCache.RemoveMapping(c.gvk, unstructuredObj.GetNamespace()+"/"+unstructuredObj.GetName())
+	}
+}
+
+func (c *fromHostClusterCacheHandler) mappingsFromVirtualObject(obj *unstructured.Unstructured) map[string]map[string]string {
+	mappings := map[string]map[string]string{}
+
+	// only objects that were synced from the host cluster by this mapping are indexed, as
+	// anyone could add the annotations to an object within the virtual cluster
+	if obj.GetLabels()[ControlledByLabel] != c.controllerID {
+		return mappings
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil || annotations[HostNameAnnotation] == "" {
+		return mappings
+	}
+
+	hostName := annotations[HostNamespaceAnnotation] + "/" + annotations[HostNameAnnotation]
+	virtualName := obj.GetNamespace() + "/" + obj.GetName()
+	mappings[IndexHostToVirtualName] = map[string]string{hostName: virtualName}
+	mappings[IndexVirtualToHostName] = map[string]string{virtualName: hostName}
+	return mappings
+}
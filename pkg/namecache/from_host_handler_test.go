@@ -0,0 +1,62 @@
+package namecache
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var testGVK = schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Certificate"}
+
+func newTestNameCache() *nameCache {
+	return &nameCache{
+		indices: map[schema.GroupVersionKind]map[string]map[string][]*Object{},
+		objects: map[schema.GroupVersionKind]map[string]*IndexMappings{},
+		hooks:   map[schema.GroupVersionKind]map[string][]HookFunc{},
+		watched: map[schema.GroupVersionKind]bool{},
+	}
+}
+
+func newFromHostObject(namespace, name string, labels, annotations map[string]string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(testGVK)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	obj.SetLabels(labels)
+	obj.SetAnnotations(annotations)
+	return obj
+}
+
+func TestFromHostClusterCacheHandler(t *testing.T) {
+	nc := newTestNameCache()
+	handler := &fromHostClusterCacheHandler{
+		gvk:          testGVK,
+		nameCache:    nc,
+		controllerID: "controller",
+	}
+	hostAnnotations := map[string]string{
+		HostNameAnnotation:      "host-cert",
+		HostNamespaceAnnotation: "host-ns",
+	}
+
+	// objects of the mapping are indexed in both directions
+	owned := newFromHostObject("default", "cert", map[string]string{ControlledByLabel: "controller"}, hostAnnotations)
+	handler.OnAdd(owned)
+	assert.Equal(t, nc.GetFirstByIndex(testGVK, IndexHostToVirtualName, "host-ns/host-cert"), "default/cert")
+	assert.Equal(t, nc.GetFirstByIndex(testGVK, IndexVirtualToHostName, "default/cert"), "host-ns/host-cert")
+
+	// objects that were created by a tenant with the annotations are ignored
+	foreign := newFromHostObject("tenant", "cert", nil, hostAnnotations)
+	handler.OnAdd(foreign)
+	otherController := newFromHostObject("tenant", "other", map[string]string{ControlledByLabel: "other"}, hostAnnotations)
+	handler.OnAdd(otherController)
+	assert.Equal(t, len(nc.GetByIndex(testGVK, IndexHostToVirtualName, "host-ns/host-cert")), 1)
+	assert.Equal(t, nc.GetFirstByIndex(testGVK, IndexVirtualToHostName, "tenant/cert"), "")
+	assert.Equal(t, nc.GetFirstByIndex(testGVK, IndexVirtualToHostName, "tenant/other"), "")
+
+	// removing the label removes the object from the index
+	handler.OnUpdate(owned, newFromHostObject("default", "cert", nil, hostAnnotations))
+	assert.Equal(t, nc.GetFirstByIndex(testGVK, IndexHostToVirtualName, "host-ns/host-cert"), "")
+}
@@ -85,21 +85,20 @@ func (c *fromVirtualClusterCacheHandler) mappingsFromVirtualObject(obj *unstruct
 		}
 
 		for _, m := range matches {
-			if m.Kind == yaml.ScalarNode {
-
-				if p.ParsedRegex != nil {
-					_ = patchesregex.ProcessRegex(p.ParsedRegex, m.Value, func(name, namespace string) types.NamespacedName {
-						// if the regex match doesn't contain namespace - use the namespace of the virtual object that is being handled
-						if namespace == "" {
-							namespace = obj.GetNamespace()
-						}
-						addSingleMapping(mappings, namespace+"/"+name, translate.PhysicalName(name, namespace), p.Path)
-
-						// return empty as return value will not be used, we only want to add the mappings above
-						return types.NamespacedName{}
-					})
-				} else {
-					addSingleMapping(mappings, obj.GetNamespace()+"/"+m.Value, translate.PhysicalName(m.Value, obj.GetNamespace()), p.Path)
+			switch m.Kind {
+			case yaml.ScalarNode:
+				addNameMappings(mappings, p, m.Value, obj.GetNamespace())
+			case yaml.SequenceNode:
+				for _, subNode := range m.Content {
+					err = addReferenceMappings(mappings, p, subNode, obj.GetNamespace())
+					if err != nil {
+						return nil, err
+					}
+				}
+			case yaml.MappingNode:
+				err = addReferenceMappings(mappings, p, m, obj.GetNamespace())
+				if err != nil {
+					return nil, err
 				}
 			}
 		}
@@ -108,6 +107,50 @@ func (c *fromVirtualClusterCacheHandler) mappingsFromVirtualObject(obj *unstruct
 	return mappings, nil
 }
 
+// addReferenceMappings adds the mappings for an object reference that is rewritten through
+// the namePath and namespacePath of the patch. References without a namespace belong to the
+// namespace of the virtual object.
+func addReferenceMappings(mappings map[string]map[string]string, p *config.Patch, reference *yaml.Node, namespace string) error {
+	if p.NamespacePath != "" {
+		referenceNamespace, err := patches.GetNamespace(reference, p)
+		if err != nil {
+			return err
+		} else if referenceNamespace != "" {
+			namespace = referenceNamespace
+		}
+	}
+
+	nameMatches, err := patches.FindMatches(reference, p.NamePath)
+	if err != nil {
+		return errors.Wrap(err, "find name matches")
+	}
+
+	for _, nameMatch := range nameMatches {
+		if nameMatch.Kind == yaml.ScalarNode {
+			addNameMappings(mappings, p, nameMatch.Value, namespace)
+		}
+	}
+
+	return nil
+}
+
+func addNameMappings(mappings map[string]map[string]string, p *config.Patch, name, namespace string) {
+	if p.ParsedRegex != nil {
+		_ = patchesregex.ProcessRegex(p.ParsedRegex, name, func(name, regexNamespace string) types.NamespacedName {
+			// if the regex match doesn't contain namespace - use the namespace of the reference
+			if regexNamespace == "" {
+				regexNamespace = namespace
+			}
+			addSingleMapping(mappings, regexNamespace+"/"+name, translate.PhysicalName(name, regexNamespace), p.Path)
+
+			// return empty as return value will not be used, we only want to add the mappings above
+			return types.NamespacedName{}
+		})
+	} else {
+		addSingleMapping(mappings, namespace+"/"+name, translate.PhysicalName(name, namespace), p.Path)
+	}
+}
+
 func addSingleMapping(mappings map[string]map[string]string, virtualName, hostName, path string) {
 	mappings[IndexPhysicalToVirtualName][hostName] = virtualName
 	mappings[IndexPhysicalToVirtualNamePath][hostName+"/"+path] = virtualName
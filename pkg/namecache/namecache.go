@@ -7,6 +7,8 @@ import (
 	"sync"
 
 	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/config"
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/metrics"
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/plugin"
 	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -21,6 +23,12 @@ const (
 const (
 	IndexPhysicalToVirtualName     = "indexphysicaltovirtualname"
 	IndexPhysicalToVirtualNamePath = "indexphysicaltovirtualnamepath"
+
+	// IndexHostToVirtualName and IndexVirtualToHostName are filled for objects
+	// that are synced from the host cluster into the virtual cluster. Keys and
+	// values are in the NAMESPACE/NAME format.
+	IndexHostToVirtualName = "indexhosttovirtualname"
+	IndexVirtualToHostName = "indexvirtualtohostname"
 )
 
 type HookFunc func(name, key, value string)
@@ -31,6 +39,9 @@ type NameCache interface {
 	ResolveNamePath(gvk schema.GroupVersionKind, hostName string, path string) types.NamespacedName
 	AddChangeHook(gvk schema.GroupVersionKind, index string, hookFunc HookFunc)
 
+	// Watches returns true if the virtual objects of the given kind are indexed by the cache
+	Watches(gvk schema.GroupVersionKind) bool
+
 	ExchangeMapping(gvk schema.GroupVersionKind, object *IndexMappings)
 	RemoveMapping(gvk schema.GroupVersionKind, name string)
 }
@@ -40,32 +51,15 @@ func NewNameCache(ctx context.Context, manager ctrl.Manager, mappings *config.Co
 		indices: map[schema.GroupVersionKind]map[string]map[string][]*Object{},
 		objects: map[schema.GroupVersionKind]map[string]*IndexMappings{},
 		hooks:   map[schema.GroupVersionKind]map[string][]HookFunc{},
+		watched: map[schema.GroupVersionKind]bool{},
 	}
 
+	targets := patchTargets(mappings)
 	for _, mapping := range mappings.Mappings {
 		if mapping.FromVirtualCluster != nil {
 			// add informer to cache
 			gvk := schema.FromAPIVersionAndKind(mapping.FromVirtualCluster.APIVersion, mapping.FromVirtualCluster.Kind)
-
-			// check if there is at least 1 reverse patch that would use the cache
-			found := false
-			for _, p := range mapping.FromVirtualCluster.ReversePatches {
-				if p.Operation == config.PatchTypeRewriteName {
-					found = true
-					break
-				}
-			}
-			// check if there is any built-in sync enabled, as those use cache hooks
-			for _, p := range mapping.FromVirtualCluster.Patches {
-				if p.Sync != nil && ((p.Sync.Secret != nil && *p.Sync.Secret) || (p.Sync.ConfigMap != nil && *p.Sync.ConfigMap)) {
-					found = true
-					break
-				}
-			}
-			if len(mapping.FromVirtualCluster.SyncBack) > 0 {
-				found = true
-			}
-			if !found {
+			if !needsIndex(mapping.FromVirtualCluster, targets) {
 				continue
 			}
 
@@ -78,19 +72,85 @@ func NewNameCache(ctx context.Context, manager ctrl.Manager, mappings *config.Co
 				return nil, fmt.Errorf("get informer for %v: %v", gvk, err)
 			}
 
+			nc.watched[gvk] = true
 			informer.AddEventHandler(&fromVirtualClusterCacheHandler{
 				gvk:       gvk,
 				mapping:   mapping.FromVirtualCluster,
 				nameCache: nc,
 			})
+		} else if mapping.FromHostCluster != nil {
+			// watch the virtual objects that were synced from the host cluster
+			gvk := schema.FromAPIVersionAndKind(mapping.FromHostCluster.APIVersion, mapping.FromHostCluster.Kind)
+			obj := &unstructured.Unstructured{}
+			obj.SetAPIVersion(mapping.FromHostCluster.APIVersion)
+			obj.SetKind(mapping.FromHostCluster.Kind)
+			informer, err := manager.GetCache().GetInformer(ctx, obj)
+			if err != nil {
+				return nil, fmt.Errorf("get informer for %v: %v", gvk, err)
+			}
+
+			informer.AddEventHandler(&fromHostClusterCacheHandler{
+				gvk:          gvk,
+				nameCache:    nc,
+				controllerID: controllerID(mapping.FromHostCluster.ID),
+			})
 		} else {
-			return nil, fmt.Errorf("currently expects fromVirtualCluster to be defined")
+			return nil, fmt.Errorf("currently expects fromVirtualCluster or fromHostCluster to be defined")
 		}
 	}
 
 	return nc, nil
 }
 
+// patchTargets returns the kinds that are referenced by patches of other mappings, which need to be watched as well
+func patchTargets(mappings *config.Config) map[schema.GroupVersionKind]bool {
+	targets := map[schema.GroupVersionKind]bool{}
+	for _, mapping := range mappings.Mappings {
+		if mapping.FromVirtualCluster == nil {
+			continue
+		}
+
+		hostToVirtualPatches := mapping.FromVirtualCluster.ReversePatches
+		for _, syncBack := range mapping.FromVirtualCluster.SyncBack {
+			hostToVirtualPatches = append(hostToVirtualPatches, syncBack.Patches...)
+		}
+		for _, p := range hostToVirtualPatches {
+			if p.Operation == config.PatchTypeRewriteName && p.Target != nil {
+				targets[p.Target.GVK()] = true
+			}
+		}
+	}
+
+	return targets
+}
+
+// needsIndex returns true if the virtual objects of the mapping need to be indexed
+func needsIndex(mapping *config.FromVirtualCluster, targets map[schema.GroupVersionKind]bool) bool {
+	// check if there is at least 1 reverse patch that would use the cache
+	for _, p := range mapping.ReversePatches {
+		if p.Operation == config.PatchTypeRewriteName {
+			return true
+		}
+	}
+	// check if there is any built-in sync enabled, as those use cache hooks
+	for _, p := range mapping.Patches {
+		if p.Sync != nil && len(p.Sync.GVKs()) > 0 {
+			return true
+		}
+	}
+
+	return len(mapping.SyncBack) > 0 || targets[mapping.GVK()]
+}
+
+// controllerID returns the id of the controller of a mapping, which defaults to the plugin name
+func controllerID(id string) string {
+	if id != "" {
+		return id
+	}
+
+	return plugin.GetPluginName()
+}
+
 type nameCache struct {
 	m sync.Mutex
 
@@ -100,6 +160,8 @@ type nameCache struct {
 	objects map[schema.GroupVersionKind]map[string]*IndexMappings
 	// GVK -> Index -> Hooks
 	hooks map[schema.GroupVersionKind]map[string][]HookFunc
+	// GVKs of the fromVirtualCluster mappings that are indexed
+	watched map[schema.GroupVersionKind]bool
 }
 
 type Object struct {
@@ -172,11 +234,16 @@ func (n *nameCache) ResolveNamePath(gvk schema.GroupVersionKind, hostName, field
 	return StringToNamespacedName(value)
 }
 
+func (n *nameCache) Watches(gvk schema.GroupVersionKind) bool {
+	return n.watched[gvk]
+}
+
 func (n *nameCache) RemoveMapping(gvk schema.GroupVersionKind, name string) {
 	n.m.Lock()
 	defer n.m.Unlock()
 
 	n.removeMapping(gvk, name)
+	n.recordIndexSizes(gvk)
 }
 
 func (n *nameCache) removeMapping(gvk schema.GroupVersionKind, name string) {
@@ -209,8 +276,6 @@ func (n *nameCache) removeMapping(gvk schema.GroupVersionKind, name string) {
 			objectMappings, ok := keyValueMappings[mappingKey]
 			if !ok || len(objectMappings) == 0 {
 				continue
-			} else if len(objectMappings) == 1 {
-				delete(n.indices[gvk][index], mappingKey)
 			}
 
 			otherMappings := []*Object{}
@@ -221,7 +286,11 @@ func (n *nameCache) removeMapping(gvk schema.GroupVersionKind, name string) {
 
 				otherMappings = append(otherMappings, objectMapping)
 			}
-			n.indices[gvk][index][mappingKey] = otherMappings
+			if len(otherMappings) == 0 {
+				delete(n.indices[gvk][index], mappingKey)
+			} else {
+				n.indices[gvk][index][mappingKey] = otherMappings
+			}
 
 			// execute hooks for this index
 			n.executeHooks(gvk, index, name, mappingKey, mappingValue)
@@ -274,6 +343,15 @@ func (n *nameCache) ExchangeMapping(gvk schema.GroupVersionKind, object *IndexMa
 			n.executeHooks(gvk, index, object.Name, key, value)
 		}
 	}
+
+	n.recordIndexSizes(gvk)
+}
+
+// recordIndexSizes updates the index size metrics of the given kind
+func (n *nameCache) recordIndexSizes(gvk schema.GroupVersionKind) {
+	for index, keys := range n.indices[gvk] {
+		metrics.SetNameCacheIndexSize(gvk, index, len(keys))
+	}
 }
 
 func (n *nameCache) executeHooks(gvk schema.GroupVersionKind, index string, name, key, value string) {
@@ -288,6 +366,7 @@ func (n *nameCache) executeHooks(gvk schema.GroupVersionKind, index string, name
 	}
 
 	for _, hook := range hooks {
+		metrics.NameCacheHookExecuted(gvk, index)
 		hook(name, key, value)
 	}
 }
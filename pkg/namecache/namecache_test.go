@@ -0,0 +1,115 @@
+package namecache
+
+import (
+	"testing"
+
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/config"
+	"github.com/loft-sh/vcluster-sdk/translate"
+	"gotest.tools/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const testConfig = `version: v1beta1
+mappings:
+  - fromVirtualCluster:
+      apiVersion: cert-manager.io/v1
+      kind: Certificate
+      reversePatches:
+        - op: rewriteName
+          path: status.requestName
+          target:
+            apiVersion: cert-manager.io/v1
+            kind: CertificateRequest
+  - fromVirtualCluster:
+      apiVersion: cert-manager.io/v1
+      kind: CertificateRequest
+  - fromVirtualCluster:
+      apiVersion: cert-manager.io/v1
+      kind: Issuer
+      patches:
+        - op: rewriteName
+          path: spec.ca.secretName
+  - fromVirtualCluster:
+      apiVersion: cert-manager.io/v1
+      kind: ClusterIssuer
+      patches:
+        - op: rewriteName
+          path: spec.ca.secretName
+          sync:
+            secret: true
+`
+
+func TestNeedsIndex(t *testing.T) {
+	configuration, err := config.ParseConfig(testConfig)
+	assert.NilError(t, err)
+
+	targets := patchTargets(configuration)
+	assert.DeepEqual(t, targets, map[schema.GroupVersionKind]bool{
+		{Group: "cert-manager.io", Version: "v1", Kind: "CertificateRequest"}: true,
+	})
+
+	indexed := map[string]bool{}
+	for _, mapping := range configuration.Mappings {
+		indexed[mapping.FromVirtualCluster.Kind] = needsIndex(mapping.FromVirtualCluster, targets)
+	}
+	assert.DeepEqual(t, indexed, map[string]bool{
+		// has a reverse rewriteName patch
+		"Certificate": true,
+		// is the target of a patch of another mapping
+		"CertificateRequest": true,
+		// names are only rewritten from virtual to host
+		"Issuer": false,
+		// force syncs the referenced secrets through hooks
+		"ClusterIssuer": true,
+	})
+}
+
+func TestFromVirtualClusterCacheHandler(t *testing.T) {
+	nc := newTestNameCache()
+	handler := &fromVirtualClusterCacheHandler{
+		gvk:       testGVK,
+		nameCache: nc,
+		mapping: &config.FromVirtualCluster{
+			SyncBase: config.SyncBase{
+				Patches: []*config.Patch{
+					{Operation: config.PatchTypeRewriteName, Path: "spec.secretName"},
+					{Operation: config.PatchTypeRewriteName, Path: "spec.refs", NamePath: "name", NamespacePath: "namespace"},
+				},
+			},
+		},
+	}
+
+	obj := newFromHostObject("default", "cert", nil, nil)
+	obj.Object["spec"] = map[string]interface{}{
+		"secretName": "secret",
+		"refs": []interface{}{
+			map[string]interface{}{"name": "local"},
+			map[string]interface{}{"name": "remote", "namespace": "other"},
+		},
+	}
+	handler.OnAdd(obj)
+
+	// the object itself is indexed by its name
+	hostName := translate.PhysicalName("cert", "default")
+	assert.Equal(t, nc.ResolveName(testGVK, hostName).String(), "default/cert")
+	assert.Equal(t, nc.ResolveNamePath(testGVK, hostName, MetadataFieldPath).String(), "default/cert")
+
+	// references are indexed by the path of the patch
+	secretHostName := translate.PhysicalName("secret", "default")
+	assert.Equal(t, nc.ResolveNamePath(testGVK, secretHostName, "spec.secretName").String(), "default/secret")
+	assert.Equal(t, nc.ResolveNamePath(testGVK, secretHostName, MetadataFieldPath).String(), "/")
+
+	// references without a namespace belong to the namespace of the object
+	assert.Equal(t, nc.ResolveNamePath(testGVK, translate.PhysicalName("local", "default"), "spec.refs").String(), "default/local")
+	assert.Equal(t, nc.ResolveNamePath(testGVK, translate.PhysicalName("remote", "other"), "spec.refs").String(), "other/remote")
+
+	// removed references are removed from the index
+	obj = obj.DeepCopy()
+	obj.Object["spec"] = map[string]interface{}{"secretName": "secret"}
+	handler.OnUpdate(nil, obj)
+	assert.Equal(t, nc.ResolveNamePath(testGVK, translate.PhysicalName("local", "default"), "spec.refs").String(), "/")
+	assert.Equal(t, nc.ResolveNamePath(testGVK, secretHostName, "spec.secretName").String(), "default/secret")
+
+	handler.OnDelete(obj)
+	assert.Equal(t, nc.ResolveName(testGVK, hostName).String(), "/")
+}
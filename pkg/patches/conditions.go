@@ -1,15 +1,18 @@
 package patches
 
 import (
+	"regexp"
+	"strings"
+
 	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/config"
 	"github.com/pkg/errors"
 	"github.com/vmware-labs/yaml-jsonpath/pkg/yamlpath"
 	yaml "gopkg.in/yaml.v3"
 )
 
-func ValidateAllConditions(obj *yaml.Node, match *yaml.Node, conditions []*config.PatchCondition) (bool, error) {
+func ValidateAllConditions(obj, other *yaml.Node, match *yaml.Node, conditions []*config.PatchCondition) (bool, error) {
 	for _, condition := range conditions {
-		matched, err := ValidateCondition(obj, match, condition)
+		matched, err := ValidateCondition(obj, other, match, condition)
 		if err != nil {
 			return false, err
 		} else if !matched {
@@ -20,19 +23,47 @@ func ValidateAllConditions(obj *yaml.Node, match *yaml.Node, conditions []*confi
 	return true, nil
 }
 
-func ValidateCondition(obj *yaml.Node, match *yaml.Node, condition *config.PatchCondition) (bool, error) {
+func ValidateCondition(obj, other *yaml.Node, match *yaml.Node, condition *config.PatchCondition) (bool, error) {
 	if condition == nil {
 		return true, nil
 	}
 
+	if condition.CEL != "" {
+		matched, err := validateCEL(obj, other, match, condition)
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+
+	// nested groups
+	if len(condition.AllOf) > 0 {
+		matched, err := ValidateAllConditions(obj, other, match, condition.AllOf)
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+	if len(condition.AnyOf) > 0 {
+		matched, err := validateAnyCondition(obj, other, match, condition.AnyOf)
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+	if condition.Not != nil {
+		matched, err := ValidateCondition(obj, other, match, condition.Not)
+		if err != nil || matched {
+			return false, err
+		}
+	}
+
+	// a condition that only consists of groups or an expression is fulfilled at this point
+	if condition.Path == "" && condition.SubPath == "" && (condition.CEL != "" || len(condition.AllOf) > 0 || len(condition.AnyOf) > 0 || condition.Not != nil) {
+		return true, nil
+	}
+
 	var matches []*yaml.Node
 	if condition.SubPath != "" {
 		if match == nil {
-			if (condition.Empty != nil && *condition.Empty) || condition.NotEqual != nil {
-				return true, nil
-			} else {
-				return false, nil
-			}
+			return matchesMissingPath(condition), nil
 		}
 
 		path, err := yamlpath.NewPath(condition.SubPath)
@@ -45,12 +76,21 @@ func ValidateCondition(obj *yaml.Node, match *yaml.Node, condition *config.Patch
 			return false, errors.Wrap(err, "find matches")
 		}
 	} else if condition.Path != "" {
+		root := obj
+		if condition.Source == config.ConditionSourceOther {
+			if other == nil {
+				return matchesMissingPath(condition), nil
+			}
+
+			root = other
+		}
+
 		path, err := yamlpath.NewPath(condition.Path)
 		if err != nil {
 			return false, errors.Wrap(err, "parsing path")
 		}
 
-		matches, err = path.Find(obj)
+		matches, err = path.Find(root)
 		if err != nil {
 			return false, errors.Wrap(err, "find matches")
 		}
@@ -58,11 +98,7 @@ func ValidateCondition(obj *yaml.Node, match *yaml.Node, condition *config.Patch
 
 	// no matches
 	if len(matches) == 0 {
-		if (condition.Empty != nil && *condition.Empty) || condition.NotEqual != nil {
-			return true, nil
-		}
-
-		return false, nil
+		return matchesMissingPath(condition), nil
 	}
 
 	// only one match needs to fulfill our condition
@@ -92,12 +128,106 @@ func ValidateCondition(obj *yaml.Node, match *yaml.Node, condition *config.Patch
 			}
 
 			continue
+		} else if condition.Matches != "" {
+			regex := condition.ParsedMatches
+			if regex == nil {
+				var err error
+				regex, err = regexp.Compile(condition.Matches)
+				if err != nil {
+					return false, errors.Wrap(err, "parsing matches")
+				}
+			}
+			if regex.MatchString(strings.TrimSuffix(stringValue, "\n")) {
+				return true, nil
+			}
+
+			continue
+		} else if condition.In != nil {
+			if containsStringValue(condition.In, strings.TrimSuffix(stringValue, "\n")) {
+				return true, nil
+			}
+
+			continue
+		} else if condition.NotIn != nil {
+			if !containsStringValue(condition.NotIn, strings.TrimSuffix(stringValue, "\n")) {
+				return true, nil
+			}
+
+			continue
+		} else if condition.Exists != nil {
+			return *condition.Exists, nil
+		}
+	}
+
+	return false, nil
+}
+
+func validateAnyCondition(obj, other *yaml.Node, match *yaml.Node, conditions []*config.PatchCondition) (bool, error) {
+	for _, condition := range conditions {
+		matched, err := ValidateCondition(obj, other, match, condition)
+		if err != nil {
+			return false, err
+		} else if matched {
+			return true, nil
 		}
 	}
 
 	return false, nil
 }
 
+// matchesMissingPath returns true if the condition is fulfilled when the path cannot be found
+func matchesMissingPath(condition *config.PatchCondition) bool {
+	return (condition.Empty != nil && *condition.Empty) ||
+		condition.NotEqual != nil ||
+		condition.NotIn != nil ||
+		(condition.Exists != nil && !*condition.Exists)
+}
+
+// containsStringValue compares the values without the trailing newline of the yaml encoding,
+// so that e.g. numbers can be listed in in and notIn conditions
+func containsStringValue(values []interface{}, stringValue string) bool {
+	for _, value := range values {
+		if strings.TrimSuffix(getStringValue(value), "\n") == stringValue {
+			return true
+		}
+	}
+
+	return false
+}
+
+func validateCEL(obj, other *yaml.Node, match *yaml.Node, condition *config.PatchCondition) (bool, error) {
+	program := condition.ParsedCEL
+	if program == nil {
+		var err error
+		program, err = config.CompileCEL(condition.CEL)
+		if err != nil {
+			return false, errors.Wrap(err, "compile cel")
+		}
+	}
+
+	vars := map[string]interface{}{}
+	for name, node := range map[string]*yaml.Node{"self": obj, "other": other, "match": match} {
+		var value interface{}
+		if node != nil {
+			err := node.Decode(&value)
+			if err != nil {
+				return false, errors.Wrapf(err, "decode %s", name)
+			}
+		}
+
+		vars[name] = value
+	}
+
+	out, _, err := program.Eval(vars)
+	if err != nil {
+		// evaluation errors such as missing fields mean the condition is not met
+		return false, nil
+	}
+
+	matched, ok := out.Value().(bool)
+	return ok && matched, nil
+}
+
 func getStringValue(value interface{}) string {
 	strValue, ok := value.(string)
 	if ok {
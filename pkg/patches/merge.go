@@ -0,0 +1,75 @@
+package patches
+
+import (
+	"reflect"
+)
+
+// MergeValues merges the patch into the target following RFC 7386, which means objects are merged
+// recursively, null values remove the key and all other values replace the target. If mergeKey is
+// set, arrays that only contain objects are merged by the value of that key instead of being replaced.
+func MergeValues(target, patch interface{}, mergeKey string) interface{} {
+	if mergeKey != "" {
+		targetList, targetIsList := target.([]interface{})
+		patchList, patchIsList := patch.([]interface{})
+		if targetIsList && patchIsList && isObjectList(targetList, mergeKey) && isObjectList(patchList, mergeKey) {
+			return mergeLists(targetList, patchList, mergeKey)
+		}
+	}
+
+	patchMap, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetMap, ok := target.(map[string]interface{})
+	if !ok {
+		targetMap = map[string]interface{}{}
+	}
+
+	for key, value := range patchMap {
+		if value == nil {
+			delete(targetMap, key)
+			continue
+		}
+
+		targetMap[key] = MergeValues(targetMap[key], value, mergeKey)
+	}
+
+	return targetMap
+}
+
+func mergeLists(target, patch []interface{}, mergeKey string) []interface{} {
+	for _, patchItem := range patch {
+		patchMap := patchItem.(map[string]interface{})
+
+		merged := false
+		for idx, targetItem := range target {
+			targetMap := targetItem.(map[string]interface{})
+			if reflect.DeepEqual(targetMap[mergeKey], patchMap[mergeKey]) {
+				target[idx] = MergeValues(targetMap, patchMap, mergeKey)
+				merged = true
+				break
+			}
+		}
+
+		if !merged {
+			target = append(target, MergeValues(nil, patchMap, mergeKey))
+		}
+	}
+
+	return target
+}
+
+// isObjectList checks if all items of the list are objects that contain the merge key
+func isObjectList(list []interface{}, mergeKey string) bool {
+	for _, item := range list {
+		itemMap, ok := item.(map[string]interface{})
+		if !ok {
+			return false
+		} else if _, ok := itemMap[mergeKey]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
@@ -6,6 +6,8 @@ import (
 	"regexp"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 
 	jsonyaml "github.com/ghodss/yaml"
 	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/config"
@@ -23,7 +25,43 @@ type NameResolver interface {
 	TranslateNamespaceRef(namespace string) (string, error)
 }
 
-func ApplyPatches(obj1, obj2 client.Object, patchesConf []*config.Patch, reversePatchesConf []*config.Patch, nameResolver NameResolver) error {
+// NamespacedNameResolver can be implemented by a NameResolver that resolves a name together with
+// its namespace, e.g. because references from the host cluster might point to objects in different
+// virtual namespaces. For references with a namespace it is preferred over TranslateNameWithNamespace
+// and TranslateNamespaceRef.
+type NamespacedNameResolver interface {
+	TranslateNamespacedName(name string, namespace string, path string) (types.NamespacedName, error)
+}
+
+// TargetNameResolver can be implemented by a NameResolver whose translation depends on the kind
+// of the referenced objects. It returns the resolver for rewriteName patches with a target kind.
+type TargetNameResolver interface {
+	ForTarget(gvk schema.GroupVersionKind) (NameResolver, error)
+}
+
+// PatchError is returned by ApplyPatches if a single patch couldn't be applied
+type PatchError struct {
+	// Operation is the operation of the failed patch
+	Operation config.PatchType
+
+	Err error
+}
+
+func (e *PatchError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PatchError) Unwrap() error {
+	return e.Err
+}
+
+func ApplyPatches(obj1, obj2 client.Object, patchesConf []*config.Patch, reversePatchesConf []*config.Patch, nameResolver NameResolver, templateContext *TemplateContext) error {
+	return ApplyPatchesWithTrace(obj1, obj2, patchesConf, reversePatchesConf, nameResolver, templateContext, nil)
+}
+
+// ApplyPatchesWithTrace applies the patches like ApplyPatches and records every step in the
+// given trace, if it isn't nil
+func ApplyPatchesWithTrace(obj1, obj2 client.Object, patchesConf []*config.Patch, reversePatchesConf []*config.Patch, nameResolver NameResolver, templateContext *TemplateContext, trace *Trace) error {
 	node1, err := NewJSONNode(obj1)
 	if err != nil {
 		return errors.Wrap(err, "new json yaml node")
@@ -38,9 +76,9 @@ func ApplyPatches(obj1, obj2 client.Object, patchesConf []*config.Patch, reverse
 	}
 
 	for _, p := range patchesConf {
-		err := applyPatch(node1, node2, p, nameResolver)
+		err := tracePatch(trace, node1, node2, p, nameResolver, templateContext)
 		if err != nil {
-			return errors.Wrap(err, "apply patch")
+			return errors.Wrap(&PatchError{Operation: p.Operation, Err: err}, "apply patch")
 		}
 	}
 
@@ -50,12 +88,12 @@ func ApplyPatches(obj1, obj2 client.Object, patchesConf []*config.Patch, reverse
 			continue
 		}
 
-		err := applyPatch(node1, node2, &config.Patch{
+		err := tracePatch(trace, node1, node2, &config.Patch{
 			Operation: config.PatchTypeRemove,
 			Path:      p.Path,
-		}, nameResolver)
+		}, nameResolver, templateContext)
 		if err != nil {
-			return errors.Wrap(err, "apply patch")
+			return errors.Wrap(&PatchError{Operation: config.PatchTypeRemove, Err: err}, "apply patch")
 		}
 	}
 
@@ -72,29 +110,65 @@ func ApplyPatches(obj1, obj2 client.Object, patchesConf []*config.Patch, reverse
 	return nil
 }
 
-func applyPatch(obj1, obj2 *yaml.Node, patch *config.Patch, resolver NameResolver) error {
+// tracePatch applies the patch and records it in the trace, if tracing is enabled
+func tracePatch(trace *Trace, obj1, obj2 *yaml.Node, patch *config.Patch, resolver NameResolver, templateContext *TemplateContext) error {
+	if trace == nil {
+		return applyPatch(obj1, obj2, patch, resolver, templateContext)
+	}
+
+	return trace.traceApplyPatch(obj1, obj2, patch, func() error {
+		return applyPatch(obj1, obj2, patch, resolver, templateContext)
+	})
+}
+
+func applyPatch(obj1, obj2 *yaml.Node, patch *config.Patch, resolver NameResolver, templateContext *TemplateContext) error {
 	switch patch.Operation {
 	case config.PatchTypeRewriteName:
-		return RewriteName(obj1, patch, resolver)
+		targetResolver, err := resolverForTarget(patch, resolver)
+		if err != nil {
+			return err
+		}
+
+		return RewriteName(obj1, obj2, patch, targetResolver)
 	case config.PatchTypeRewriteLabelKey:
-		return RewriteLabelKey(obj1, patch, resolver)
+		return RewriteLabelKey(obj1, obj2, patch, resolver)
 	case config.PatchTypeRewriteLabelExpressionsSelector:
-		return RewriteLabelExpressionsSelector(obj1, patch, resolver)
+		return RewriteLabelExpressionsSelector(obj1, obj2, patch, resolver)
 	case config.PatchTypeRewriteLabelSelector:
-		return RewriteLabelSelector(obj1, patch, resolver)
+		return RewriteLabelSelector(obj1, obj2, patch, resolver)
 	case config.PatchTypeReplace:
-		return Replace(obj1, patch)
+		return Replace(obj1, obj2, patch)
 	case config.PatchTypeRemove:
-		return Remove(obj1, patch)
+		return Remove(obj1, obj2, patch)
 	case config.PatchTypeAdd:
-		return Add(obj1, patch)
+		return Add(obj1, obj2, patch)
+	case config.PatchTypeMerge:
+		return Merge(obj1, obj2, patch)
 	case config.PatchTypeCopyFromObject:
 		return CopyFromObject(obj1, obj2, patch)
+	case config.PatchTypeTemplate:
+		return Template(obj1, obj2, patch, templateContext)
+	case config.PatchTypeJSONPatch:
+		return JSONPatch(obj1, obj2, patch)
 	}
 
 	return fmt.Errorf("patch operation is missing or is not recognized (%s)", patch.Operation)
 }
 
+// resolverForTarget returns the resolver for the kind referenced by the patch
+func resolverForTarget(patch *config.Patch, resolver NameResolver) (NameResolver, error) {
+	if patch.Target == nil {
+		return resolver, nil
+	}
+
+	targetNameResolver, ok := resolver.(TargetNameResolver)
+	if !ok {
+		return resolver, nil
+	}
+
+	return targetNameResolver.ForTarget(patch.Target.GVK())
+}
+
 func NewNodeFromString(in string) (*yaml.Node, error) {
 	var node yaml.Node
 	err := yaml.Unmarshal([]byte(in), &node)
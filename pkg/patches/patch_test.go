@@ -6,8 +6,11 @@ import (
 	"regexp"
 	"strings"
 	"testing"
+	"text/template"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 
 	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/config"
@@ -30,10 +33,18 @@ type patchTestCase struct {
 	expectedErr  error
 }
 
+var testTemplateContext = &TemplateContext{
+	VirtualNamespace: "test",
+	HostNamespace:    "vcluster",
+	PluginName:       "plugin",
+	ControllerID:     "controller",
+}
+
 // TODO update tests
 
 func TestPatch(t *testing.T) {
 	True := true
+	False := false
 
 	testCases := []*patchTestCase{
 		{
@@ -189,6 +200,28 @@ test2: {}`,
     status:
         test: test
     abc: test`,
+		},
+		{
+			name: "condition equal compares the yaml encoding",
+			patch: &config.Patch{
+				Operation: config.PatchTypeReplace,
+				Path:      "test.abc",
+				Value:     "def",
+				Conditions: []*config.PatchCondition{
+					{
+						Path:  "test.status",
+						Equal: "test: test\n",
+					},
+				},
+			},
+			obj1: `test: 
+    status:
+        test: test
+    abc: test`,
+			expected: `test:
+    status:
+        test: test
+    abc: def`,
 		},
 		{
 			name: "resolve label selector",
@@ -369,6 +402,96 @@ test2: {}`,
         - ns: xyz`,
 			expectedErr: errors.New("found multiple namespace references"),
 		},
+		{
+			name: "rewrite name - namespaced name resolver",
+			patch: &config.Patch{
+				Operation:     config.PatchTypeRewriteName,
+				FromPath:      "root.list",
+				Path:          "root.list",
+				NamePath:      "nm",
+				NamespacePath: "ns",
+			},
+			nameResolver: &fakeHostToVirtualNameResolver{
+				targetNamespace: "vcluster",
+				names: map[string]types.NamespacedName{
+					"abc-x-pqr-x-suffix": {Namespace: "pqr", Name: "abc"},
+					"def-x-xyz-x-suffix": {Namespace: "xyz", Name: "def"},
+				},
+			},
+			obj1: `root:
+  list:
+    - nm: abc-x-pqr-x-suffix
+      ns: vcluster
+    - nm: def-x-xyz-x-suffix
+      ns: vcluster`,
+			expected: `root:
+    list:
+        - nm: abc
+          ns: pqr
+        - nm: def
+          ns: xyz`,
+		},
+		{
+			name: "rewrite name - namespaced name resolver - different namespaces",
+			patch: &config.Patch{
+				Operation:     config.PatchTypeRewriteName,
+				Path:          "root.includes",
+				NamePath:      "names..nm",
+				NamespacePath: "namespace",
+			},
+			nameResolver: &fakeHostToVirtualNameResolver{
+				targetNamespace: "vcluster",
+				names: map[string]types.NamespacedName{
+					"abc-x-pqr-x-suffix": {Namespace: "pqr", Name: "abc"},
+					"def-x-xyz-x-suffix": {Namespace: "xyz", Name: "def"},
+				},
+			},
+			obj1: `root:
+  includes:
+    - names:
+        - nm: abc-x-pqr-x-suffix
+        - nm: def-x-xyz-x-suffix
+      namespace: vcluster`,
+			expectedErr: errors.New("names of the same reference resolve to different namespaces pqr and xyz"),
+		},
+		{
+			name: "rewrite name - target",
+			patch: &config.Patch{
+				Operation: config.PatchTypeRewriteName,
+				Path:      "status.request",
+				Target: &config.TypeInformation{
+					APIVersion: "cert-manager.io/v1",
+					Kind:       "CertificateRequest",
+				},
+			},
+			nameResolver: &fakeTargetNameResolver{
+				targets: map[schema.GroupVersionKind]NameResolver{
+					{Group: "cert-manager.io", Version: "v1", Kind: "CertificateRequest"}: &fakeVirtualToHostNameResolver{
+						namespace:       "default",
+						targetNamespace: "vcluster",
+					},
+				},
+			},
+			obj1: `status:
+    request: abc`,
+			expected: `status:
+    request: abc-x-default-x-` + fmt.Sprint(translate.Suffix),
+		},
+		{
+			name: "rewrite name - unknown target",
+			patch: &config.Patch{
+				Operation: config.PatchTypeRewriteName,
+				Path:      "status.request",
+				Target: &config.TypeInformation{
+					APIVersion: "v1",
+					Kind:       "Secret",
+				},
+			},
+			nameResolver: &fakeTargetNameResolver{},
+			obj1: `status:
+    request: abc`,
+			expectedErr: errors.New("unknown target /v1, Kind=Secret"),
+		},
 		{
 			name: "rewrite label key",
 			patch: &config.Patch{
@@ -413,6 +536,421 @@ test2: {}`,
         - name: abc
         - name: def`,
 		},
+		{
+			name: "merge",
+			patch: &config.Patch{
+				Operation: config.PatchTypeMerge,
+				Path:      "spec",
+				Value: map[string]interface{}{
+					"replicas": 2,
+					"old":      nil,
+					"template": map[string]interface{}{
+						"labels": map[string]interface{}{"b": "c"},
+					},
+					"items": []interface{}{"c"},
+				},
+			},
+			obj1: `spec:
+    old: value
+    replicas: 1
+    template:
+        labels:
+            a: b
+    items:
+        - a
+        - b`,
+			expected: `spec:
+    items:
+        - c
+    replicas: 2
+    template:
+        labels:
+            a: b
+            b: c`,
+		},
+		{
+			name: "merge by key",
+			patch: &config.Patch{
+				Operation: config.PatchTypeMerge,
+				Path:      "spec",
+				MergeKey:  "name",
+				Value: map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"name": "b", "image": "new"},
+						map[string]interface{}{"name": "c", "image": "c"},
+					},
+				},
+			},
+			obj1: `spec:
+    containers:
+        - name: a
+          image: a
+        - name: b
+          image: b
+          args: [test]`,
+			expected: `spec:
+    containers:
+        - image: a
+          name: a
+        - args:
+            - test
+          image: new
+          name: b
+        - image: c
+          name: c`,
+		},
+		{
+			name: "merge missing path with condition",
+			patch: &config.Patch{
+				Operation: config.PatchTypeMerge,
+				Path:      "spec.template",
+				Value: map[string]interface{}{
+					"labels": map[string]interface{}{"a": "b", "c": nil},
+				},
+				Conditions: []*config.PatchCondition{
+					{
+						Path:  "spec.mode",
+						Equal: "on",
+					},
+				},
+			},
+			obj1: `spec:
+    mode: on`,
+			expected: `spec:
+    mode: on
+    template:
+        labels:
+            a: b`,
+		},
+		{
+			name: "merge condition not matched",
+			patch: &config.Patch{
+				Operation: config.PatchTypeMerge,
+				Path:      "spec",
+				Value:     map[string]interface{}{"a": "b"},
+				Conditions: []*config.PatchCondition{
+					{
+						SubPath: "mode",
+						Equal:   "on",
+					},
+				},
+			},
+			obj1: `spec:
+    mode: off`,
+			expected: `spec:
+    mode: off`,
+		},
+		{
+			name: "template",
+			patch: &config.Patch{
+				Operation: config.PatchTypeTemplate,
+				Path:      "spec.name",
+				Template:  `{{ .metadata.namespace }}-{{ .Other.spec.name | upper }}-{{ .Context.HostNamespace }}-{{ .Context.ControllerID }}{{ if hasKey .spec "missing" }}{{ .spec.missing }}{{ end }}`,
+			},
+			obj1: `metadata:
+    namespace: test
+spec:
+    name: abc`,
+			obj2: `spec:
+    name: def`,
+			expected: `metadata:
+    namespace: test
+spec:
+    name: test-DEF-vcluster-controller`,
+		},
+		{
+			name: "parsed template",
+			patch: &config.Patch{
+				Operation:      config.PatchTypeTemplate,
+				Path:           "spec.name",
+				Template:       `{{ .metadata.namespace`,
+				ParsedTemplate: template.Must(config.ParseTemplate(`{{ .metadata.namespace }}-parsed`)),
+			},
+			obj1: `metadata:
+    namespace: test
+spec:
+    name: abc`,
+			expected: `metadata:
+    namespace: test
+spec:
+    name: test-parsed`,
+		},
+		{
+			name: "template create path with condition",
+			patch: &config.Patch{
+				Operation: config.PatchTypeTemplate,
+				Path:      "metadata.annotations['vcluster.loft.sh/owner']",
+				Template:  `{{ .Context.VirtualNamespace }}/{{ .metadata.name }}`,
+				Conditions: []*config.PatchCondition{
+					{
+						Path:  "metadata.name",
+						Equal: "abc",
+					},
+				},
+			},
+			obj1: `metadata:
+    name: abc`,
+			expected: `metadata:
+    name: abc
+    annotations:
+        vcluster.loft.sh/owner: test/abc`,
+		},
+		{
+			name: "template missing key",
+			patch: &config.Patch{
+				Operation: config.PatchTypeTemplate,
+				Path:      "spec.name",
+				Template:  `{{ .spec.missing }}`,
+			},
+			obj1:        `spec: {}`,
+			expectedErr: errors.New(`map has no entry for key "missing"`),
+		},
+		{
+			name: "template literal no value",
+			patch: &config.Patch{
+				Operation: config.PatchTypeTemplate,
+				Path:      "spec.name",
+				Template:  `<no value>-{{ dig "spec" "missing" "default" . }}`,
+			},
+			obj1:     `spec: {}`,
+			expected: `spec: {name: <no value>-default}`,
+		},
+		{
+			name: "template not rendered if conditions don't match",
+			patch: &config.Patch{
+				Operation: config.PatchTypeTemplate,
+				Path:      "spec.name",
+				Template:  `{{ .spec.missing }}`,
+				Conditions: []*config.PatchCondition{
+					{
+						Path:  "spec.name",
+						Equal: "other",
+					},
+				},
+			},
+			obj1: `spec:
+    name: abc`,
+			expected: `spec:
+    name: abc`,
+		},
+		{
+			name: "template error",
+			patch: &config.Patch{
+				Operation: config.PatchTypeTemplate,
+				Path:      "spec.name",
+				Template:  `{{ fail "not allowed" }}`,
+			},
+			obj1:        `spec: {}`,
+			expectedErr: errors.New("not allowed"),
+		},
+		{
+			name: "cel condition",
+			patch: &config.Patch{
+				Operation: config.PatchTypeReplace,
+				Path:      "spec.ports[*].name",
+				Value:     "tls",
+				Conditions: []*config.PatchCondition{
+					{
+						CEL: `self.spec.replicas > 1 && has(self.spec.tls) && other.status.ready && match == "https"`,
+					},
+				},
+			},
+			obj1: `spec:
+    replicas: 2
+    tls: {}
+    ports:
+        - name: http
+        - name: https`,
+			obj2: `status:
+    ready: true`,
+			expected: `spec:
+    replicas: 2
+    tls: {}
+    ports:
+        - name: http
+        - name: tls`,
+		},
+		{
+			name: "cel condition missing field",
+			patch: &config.Patch{
+				Operation: config.PatchTypeReplace,
+				Path:      "spec.replicas",
+				Value:     3,
+				Conditions: []*config.PatchCondition{
+					{
+						CEL: `self.spec.missing.field == 1`,
+					},
+				},
+			},
+			obj1: `spec:
+    replicas: 2`,
+			expected: `spec:
+    replicas: 2`,
+		},
+		{
+			name: "condition groups",
+			patch: &config.Patch{
+				Operation: config.PatchTypeReplace,
+				Path:      "spec.ports[*]",
+				Value:     map[string]interface{}{"name": "tls"},
+				Conditions: []*config.PatchCondition{
+					{
+						AnyOf: []*config.PatchCondition{
+							{Path: "spec.tls", Empty: &True},
+							{Path: "spec.mode", Equal: "secure"},
+						},
+					},
+					{
+						AllOf: []*config.PatchCondition{
+							{SubPath: "name", Matches: "^http"},
+							{SubPath: "name", NotIn: []interface{}{"http"}},
+						},
+					},
+					{
+						Not: &config.PatchCondition{Path: "spec.disabled", Exists: &True},
+					},
+				},
+			},
+			obj1: `spec:
+    tls: abc
+    mode: secure
+    ports:
+        - name: http
+        - name: https
+        - name: grpc`,
+			expected: `spec:
+    tls: abc
+    mode: secure
+    ports:
+        - name: http
+        - name: tls
+        - name: grpc`,
+		},
+		{
+			name: "condition in and missing paths",
+			patch: &config.Patch{
+				Operation: config.PatchTypeReplace,
+				Path:      "spec.replicas",
+				Value:     1,
+				Conditions: []*config.PatchCondition{
+					{Path: "spec.replicas", In: []interface{}{2, 3}},
+					{Path: "spec.missing", NotIn: []interface{}{"a"}},
+					{Path: "spec.missing", Exists: &False},
+					{
+						Not: &config.PatchCondition{Path: "spec.missing", Matches: ".*"},
+					},
+				},
+			},
+			obj1: `spec:
+    replicas: 3`,
+			expected: `spec:
+    replicas: 1`,
+		},
+		{
+			name: "condition on other object",
+			patch: &config.Patch{
+				Operation: config.PatchTypeCopyFromObject,
+				FromPath:  "status",
+				Path:      "status",
+				Conditions: []*config.PatchCondition{
+					{Path: "status.phase", Source: config.ConditionSourceOther, Equal: "Ready"},
+					{Path: "status.phase", Source: config.ConditionSourceSelf, NotEqual: "Ready"},
+				},
+			},
+			obj1: `status:
+    phase: Pending`,
+			obj2: `status:
+    phase: Ready`,
+			expected: `status:
+    phase: Ready`,
+		},
+		{
+			name: "condition on missing other object",
+			patch: &config.Patch{
+				Operation: config.PatchTypeAdd,
+				Path:      "spec.created",
+				Value:     true,
+				Conditions: []*config.PatchCondition{
+					{Path: "metadata.name", Source: config.ConditionSourceOther, Exists: &False},
+				},
+			},
+			obj1: `spec:
+    name: test`,
+			expected: `spec:
+    name: test
+    created: true`,
+		},
+		{
+			name: "json patch",
+			patch: &config.Patch{
+				Operation: config.PatchTypeJSONPatch,
+				JSONPatch: []*config.JSONPatchOperation{
+					{Operation: "test", Path: "/spec/replicas", Value: 1},
+					{Operation: "copy", From: "/spec/name", Path: "/metadata/labels/name"},
+					{Operation: "move", From: "/spec/old", Path: "/spec/new"},
+					{Operation: "add", Path: "/spec/items/-", Value: "c"},
+					{Operation: "replace", Path: "/spec/replicas", Value: 2},
+				},
+			},
+			obj1: `metadata:
+    labels: {}
+spec:
+    name: test
+    old: value
+    replicas: 1
+    items:
+        - a
+        - b`,
+			expected: `metadata:
+    labels:
+        name: test
+spec:
+    items:
+        - a
+        - b
+        - c
+    name: test
+    new: value
+    replicas: 2`,
+		},
+		{
+			name: "json patch failed test",
+			patch: &config.Patch{
+				Operation: config.PatchTypeJSONPatch,
+				JSONPatch: []*config.JSONPatchOperation{
+					{Operation: "test", Path: "/spec/replicas", Value: 2},
+					{Operation: "remove", Path: "/spec/replicas"},
+				},
+			},
+			obj1: `spec:
+    replicas: 1`,
+			expected: `spec:
+    replicas: 1`,
+		},
+		{
+			name: "json patch null value",
+			patch: &config.Patch{
+				Operation: config.PatchTypeJSONPatch,
+				JSONPatch: []*config.JSONPatchOperation{
+					{Operation: "replace", Path: "/spec/replicas", Value: nil, HasValue: true},
+				},
+			},
+			obj1: `spec:
+    replicas: 1`,
+			expected: `spec:
+    replicas: null`,
+		},
+		{
+			name: "json patch missing path",
+			patch: &config.Patch{
+				Operation: config.PatchTypeJSONPatch,
+				JSONPatch: []*config.JSONPatchOperation{
+					{Operation: "remove", Path: "/spec/missing"},
+				},
+			},
+			obj1:        `spec: {}`,
+			expectedErr: errors.New("apply json patch"),
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -425,7 +963,7 @@ test2: {}`,
 			assert.NilError(t, err, "error in node creation in test case %s", testCase.name)
 		}
 
-		err = applyPatch(obj1, obj2, testCase.patch, testCase.nameResolver)
+		err = applyPatch(obj1, obj2, testCase.patch, testCase.nameResolver, testTemplateContext)
 		if testCase.expectedErr != nil {
 			assert.ErrorContains(t, err, testCase.expectedErr.Error())
 			continue
@@ -441,6 +979,48 @@ test2: {}`,
 	}
 }
 
+func TestApplyPatchesWithTrace(t *testing.T) {
+	obj1 := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Test",
+		"metadata": map[string]interface{}{
+			"name": "test",
+		},
+		"spec": map[string]interface{}{
+			"replicas": int64(1),
+		},
+	}}
+
+	trace := &Trace{}
+	err := ApplyPatchesWithTrace(obj1, nil, []*config.Patch{
+		{
+			Operation: config.PatchTypeReplace,
+			Path:      "spec.replicas",
+			Value:     2,
+		},
+		{
+			Operation: config.PatchTypeAdd,
+			Path:      "spec.paused",
+			Value:     true,
+			Conditions: []*config.PatchCondition{
+				{Path: "spec.replicas", Equal: 3},
+			},
+		},
+	}, nil, &fakeNameResolver{}, testTemplateContext, trace)
+	assert.NilError(t, err)
+	assert.Equal(t, len(trace.Steps), 2)
+
+	assert.Equal(t, trace.Steps[0].Operation, config.PatchType(config.PatchTypeReplace))
+	assert.Equal(t, trace.Steps[0].Path, "spec.replicas")
+	assert.Equal(t, trace.Steps[0].Matches, 1)
+	assert.Equal(t, trace.Steps[0].ConditionsMatched, true)
+	assert.Assert(t, strings.Contains(trace.Steps[0].Diff, "-  replicas: 1\n+  replicas: 2\n"), "unexpected diff %s", trace.Steps[0].Diff)
+
+	assert.Equal(t, trace.Steps[1].Matches, 0)
+	assert.Equal(t, trace.Steps[1].ConditionsMatched, false)
+	assert.Equal(t, trace.Steps[1].Diff, "")
+}
+
 type fakeNameResolver struct{}
 
 func (f *fakeNameResolver) TranslateName(name string, _ *regexp.Regexp, path string) (string, error) {
@@ -479,6 +1059,37 @@ func (f *fakeNameResolver) TranslateNamespaceRef(name string) (string, error) {
 	return "default", nil
 }
 
+type fakeHostToVirtualNameResolver struct {
+	fakeNameResolver
+
+	targetNamespace string
+	names           map[string]types.NamespacedName
+}
+
+func (r *fakeHostToVirtualNameResolver) TranslateNamespacedName(name string, namespace string, _ string) (types.NamespacedName, error) {
+	if namespace != r.targetNamespace {
+		return types.NamespacedName{}, fmt.Errorf("unexpected namespace %s", namespace)
+	} else if _, ok := r.names[name]; !ok {
+		return types.NamespacedName{}, fmt.Errorf("could not translate %s", name)
+	}
+
+	return r.names[name], nil
+}
+
+type fakeTargetNameResolver struct {
+	fakeNameResolver
+
+	targets map[schema.GroupVersionKind]NameResolver
+}
+
+func (r *fakeTargetNameResolver) ForTarget(gvk schema.GroupVersionKind) (NameResolver, error) {
+	if _, ok := r.targets[gvk]; !ok {
+		return nil, fmt.Errorf("unknown target %s", gvk.String())
+	}
+
+	return r.targets[gvk], nil
+}
+
 type fakeVirtualToHostNameResolver struct {
 	namespace       string
 	targetNamespace string
@@ -1,11 +1,14 @@
 package patches
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 
+	jsonpatch "github.com/evanphx/json-patch"
 	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/config"
 	yamlhelper "github.com/loft-sh/vcluster-generic-crd-plugin/pkg/util/yaml"
 	"github.com/pkg/errors"
@@ -35,7 +38,7 @@ func CopyFromObject(obj1, obj2 *yaml.Node, patch *config.Patch) error {
 	}
 
 	if len(fromMatches) == 1 && len(matches) == 0 {
-		validated, err := ValidateAllConditions(obj1, nil, patch.Conditions)
+		validated, err := ValidateAllConditions(obj1, obj2, nil, patch.Conditions)
 		if err != nil {
 			return errors.Wrap(err, "validate conditions")
 		} else if !validated {
@@ -46,7 +49,7 @@ func CopyFromObject(obj1, obj2 *yaml.Node, patch *config.Patch) error {
 	}
 
 	for _, m := range matches {
-		validated, err := ValidateAllConditions(obj1, m, patch.Conditions)
+		validated, err := ValidateAllConditions(obj1, obj2, m, patch.Conditions)
 		if err != nil {
 			return errors.Wrap(err, "validate conditions")
 		} else if !validated {
@@ -69,14 +72,14 @@ func CopyFromObject(obj1, obj2 *yaml.Node, patch *config.Patch) error {
 	return nil
 }
 
-func Remove(obj1 *yaml.Node, patch *config.Patch) error {
+func Remove(obj1, obj2 *yaml.Node, patch *config.Patch) error {
 	matches, err := FindMatches(obj1, patch.Path)
 	if err != nil {
 		return errors.Wrap(err, "find matches")
 	}
 
 	for _, m := range matches {
-		validated, err := ValidateAllConditions(obj1, m, patch.Conditions)
+		validated, err := ValidateAllConditions(obj1, obj2, m, patch.Conditions)
 		if err != nil {
 			return errors.Wrap(err, "validate conditions")
 		} else if !validated {
@@ -95,7 +98,7 @@ func Remove(obj1 *yaml.Node, patch *config.Patch) error {
 	return nil
 }
 
-func Add(obj1 *yaml.Node, patch *config.Patch) error {
+func Add(obj1, obj2 *yaml.Node, patch *config.Patch) error {
 	matches, err := FindMatches(obj1, patch.Path)
 	if err != nil {
 		return errors.Wrap(err, "find matches")
@@ -107,7 +110,7 @@ func Add(obj1 *yaml.Node, patch *config.Patch) error {
 	}
 
 	if len(matches) == 0 {
-		validated, err := ValidateAllConditions(obj1, nil, patch.Conditions)
+		validated, err := ValidateAllConditions(obj1, obj2, nil, patch.Conditions)
 		if err != nil {
 			return errors.Wrap(err, "validate conditions")
 		} else if !validated {
@@ -120,7 +123,7 @@ func Add(obj1 *yaml.Node, patch *config.Patch) error {
 		}
 	} else {
 		for _, m := range matches {
-			validated, err := ValidateAllConditions(obj1, m, patch.Conditions)
+			validated, err := ValidateAllConditions(obj1, obj2, m, patch.Conditions)
 			if err != nil {
 				return errors.Wrap(err, "validate conditions")
 			} else if !validated {
@@ -134,7 +137,7 @@ func Add(obj1 *yaml.Node, patch *config.Patch) error {
 	return nil
 }
 
-func Replace(obj1 *yaml.Node, patch *config.Patch) error {
+func Replace(obj1, obj2 *yaml.Node, patch *config.Patch) error {
 	matches, err := FindMatches(obj1, patch.Path)
 	if err != nil {
 		return errors.Wrap(err, "find matches")
@@ -146,7 +149,7 @@ func Replace(obj1 *yaml.Node, patch *config.Patch) error {
 	}
 
 	for _, m := range matches {
-		validated, err := ValidateAllConditions(obj1, m, patch.Conditions)
+		validated, err := ValidateAllConditions(obj1, obj2, m, patch.Conditions)
 		if err != nil {
 			return errors.Wrap(err, "validate conditions")
 		} else if !validated {
@@ -159,7 +162,54 @@ func Replace(obj1 *yaml.Node, patch *config.Patch) error {
 	return nil
 }
 
-func RewriteName(obj1 *yaml.Node, patch *config.Patch, resolver NameResolver) error {
+func Merge(obj1, obj2 *yaml.Node, patch *config.Patch) error {
+	matches, err := FindMatches(obj1, patch.Path)
+	if err != nil {
+		return errors.Wrap(err, "find matches")
+	}
+
+	if len(matches) == 0 {
+		validated, err := ValidateAllConditions(obj1, obj2, nil, patch.Conditions)
+		if err != nil {
+			return errors.Wrap(err, "validate conditions")
+		} else if !validated {
+			return nil
+		}
+
+		value, err := NewNode(MergeValues(nil, patch.Value, patch.MergeKey))
+		if err != nil {
+			return errors.Wrap(err, "new node from value")
+		}
+
+		return createPath(obj1, patch.Path, value)
+	}
+
+	for _, m := range matches {
+		validated, err := ValidateAllConditions(obj1, obj2, m, patch.Conditions)
+		if err != nil {
+			return errors.Wrap(err, "validate conditions")
+		} else if !validated {
+			continue
+		}
+
+		var target interface{}
+		err = m.Decode(&target)
+		if err != nil {
+			return errors.Wrap(err, "decode match")
+		}
+
+		value, err := NewNode(MergeValues(target, patch.Value, patch.MergeKey))
+		if err != nil {
+			return errors.Wrap(err, "new node from value")
+		}
+
+		ReplaceNode(obj1, m, value)
+	}
+
+	return nil
+}
+
+func RewriteName(obj1, obj2 *yaml.Node, patch *config.Patch, resolver NameResolver) error {
 	matches, err := FindMatches(obj1, patch.Path)
 	if err != nil {
 		return errors.Wrap(err, "find matches")
@@ -168,17 +218,17 @@ func RewriteName(obj1 *yaml.Node, patch *config.Patch, resolver NameResolver) er
 	for _, m := range matches {
 		switch m.Kind {
 		case yaml.ScalarNode:
-			err = ValidateAndTranslateName(obj1, m, patch, resolver, "")
+			_, err = ValidateAndTranslateName(obj1, obj2, m, patch, resolver, "")
 		case yaml.SequenceNode:
 			for _, subNode := range m.Content {
-				err = ProcessRewrite(subNode, patch, resolver)
+				err = ProcessRewrite(subNode, obj2, patch, resolver)
 
 				if err != nil {
 					return err
 				}
 			}
 		case yaml.MappingNode:
-			err = ProcessRewrite(m, patch, resolver)
+			err = ProcessRewrite(m, obj2, patch, resolver)
 		}
 
 		if err != nil {
@@ -189,7 +239,7 @@ func RewriteName(obj1 *yaml.Node, patch *config.Patch, resolver NameResolver) er
 	return nil
 }
 
-func ProcessRewrite(obj *yaml.Node, patch *config.Patch, resolver NameResolver) error {
+func ProcessRewrite(obj, obj2 *yaml.Node, patch *config.Patch, resolver NameResolver) error {
 	var namespace string
 	var err error
 
@@ -206,15 +256,21 @@ func ProcessRewrite(obj *yaml.Node, patch *config.Patch, resolver NameResolver)
 		return errors.Wrap(err, "find name matches")
 	}
 
+	resolvedNamespace := ""
 	for _, nameMatch := range nameMatches {
 		if nameMatch.Kind != yaml.ScalarNode {
 			continue
 		}
-		err = ValidateAndTranslateName(obj, nameMatch, patch, resolver, namespace)
-
+		translatedNamespace, err := ValidateAndTranslateName(obj, obj2, nameMatch, patch, resolver, namespace)
 		if err != nil {
 			return err
+		} else if translatedNamespace == "" {
+			continue
+		} else if resolvedNamespace != "" && resolvedNamespace != translatedNamespace {
+			return fmt.Errorf("names of the same reference resolve to different namespaces %s and %s", resolvedNamespace, translatedNamespace)
 		}
+
+		resolvedNamespace = translatedNamespace
 	}
 
 	// Translate namespace
@@ -228,7 +284,7 @@ func ProcessRewrite(obj *yaml.Node, patch *config.Patch, resolver NameResolver)
 			if namespaceMatch.Kind != yaml.ScalarNode {
 				continue
 			}
-			err = ValidateAndTranslateNamespace(obj, namespaceMatch, patch, resolver)
+			err = ValidateAndTranslateNamespace(obj, obj2, namespaceMatch, patch, resolver, resolvedNamespace)
 
 			if err != nil {
 				return err
@@ -239,47 +295,60 @@ func ProcessRewrite(obj *yaml.Node, patch *config.Patch, resolver NameResolver)
 	return nil
 }
 
-func ValidateAndTranslateName(obj *yaml.Node, match *yaml.Node, patch *config.Patch, resolver NameResolver, namespace string) error {
-	validated, err := ValidateAllConditions(obj, match, patch.Conditions)
+// ValidateAndTranslateName translates the name in match if the conditions of the patch are met.
+// If the resolver resolved the name together with its namespace, the namespace is returned.
+func ValidateAndTranslateName(obj, obj2 *yaml.Node, match *yaml.Node, patch *config.Patch, resolver NameResolver, namespace string) (string, error) {
+	validated, err := ValidateAllConditions(obj, obj2, match, patch.Conditions)
 	if err != nil {
-		return errors.Wrap(err, "validate conditions")
+		return "", errors.Wrap(err, "validate conditions")
 	} else if !validated {
-		return nil
+		return "", nil
 	}
 
 	var translatedName string
-
-	if namespace != "" {
+	var translatedNamespace string
+
+	namespacedNameResolver, ok := resolver.(NamespacedNameResolver)
+	if namespace != "" && patch.ParsedRegex == nil && ok {
+		var n types.NamespacedName
+		n, err = namespacedNameResolver.TranslateNamespacedName(match.Value, namespace, patch.FromPath)
+		translatedName, translatedNamespace = n.Name, n.Namespace
+	} else if namespace != "" {
 		translatedName, err = resolver.TranslateNameWithNamespace(match.Value, namespace, patch.ParsedRegex, patch.FromPath)
 	} else {
 		translatedName, err = resolver.TranslateName(match.Value, patch.ParsedRegex, patch.FromPath)
 	}
 
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	newNode, err := NewNode(translatedName)
 	if err != nil {
-		return errors.Wrap(err, "create node")
+		return "", errors.Wrap(err, "create node")
 	}
 
 	ReplaceNode(obj, match, newNode)
 
-	return nil
+	return translatedNamespace, nil
 }
 
-func ValidateAndTranslateNamespace(obj *yaml.Node, match *yaml.Node, patch *config.Patch, resolver NameResolver) error {
-	validated, err := ValidateAllConditions(obj, match, patch.Conditions)
+// ValidateAndTranslateNamespace translates the namespace in match if the conditions of the patch are met.
+// If the name of the reference was already resolved into a namespace, that namespace is used instead.
+func ValidateAndTranslateNamespace(obj, obj2 *yaml.Node, match *yaml.Node, patch *config.Patch, resolver NameResolver, resolvedNamespace string) error {
+	validated, err := ValidateAllConditions(obj, obj2, match, patch.Conditions)
 	if err != nil {
 		return errors.Wrap(err, "validate conditions")
 	} else if !validated {
 		return nil
 	}
 
-	translatedNamespace, err := resolver.TranslateNamespaceRef(match.Value)
-	if err != nil {
-		return err
+	translatedNamespace := resolvedNamespace
+	if translatedNamespace == "" {
+		translatedNamespace, err = resolver.TranslateNamespaceRef(match.Value)
+		if err != nil {
+			return err
+		}
 	}
 
 	newNode, err := NewNode(translatedNamespace)
@@ -311,7 +380,7 @@ func GetNamespace(obj *yaml.Node, patch *config.Patch) (string, error) {
 	return namespace, nil
 }
 
-func RewriteLabelKey(obj1 *yaml.Node, patch *config.Patch, resolver NameResolver) error {
+func RewriteLabelKey(obj1, obj2 *yaml.Node, patch *config.Patch, resolver NameResolver) error {
 	matches, err := FindMatches(obj1, patch.Path)
 	if err != nil {
 		return errors.Wrap(err, "find matches")
@@ -319,7 +388,7 @@ func RewriteLabelKey(obj1 *yaml.Node, patch *config.Patch, resolver NameResolver
 
 	for _, m := range matches {
 		if m.Kind == yaml.ScalarNode {
-			validated, err := ValidateAllConditions(obj1, m, patch.Conditions)
+			validated, err := ValidateAllConditions(obj1, obj2, m, patch.Conditions)
 			if err != nil {
 				return errors.Wrap(err, "validate conditions")
 			} else if !validated {
@@ -349,7 +418,7 @@ func RewriteLabelKey(obj1 *yaml.Node, patch *config.Patch, resolver NameResolver
 	return nil
 }
 
-func RewriteLabelSelector(obj1 *yaml.Node, patch *config.Patch, resolver NameResolver) error {
+func RewriteLabelSelector(obj1, obj2 *yaml.Node, patch *config.Patch, resolver NameResolver) error {
 	matches, err := FindMatches(obj1, patch.Path)
 	if err != nil {
 		return errors.Wrap(err, "find matches")
@@ -357,7 +426,7 @@ func RewriteLabelSelector(obj1 *yaml.Node, patch *config.Patch, resolver NameRes
 
 	for _, m := range matches {
 		if m.Kind == yaml.MappingNode {
-			validated, err := ValidateAllConditions(obj1, m, patch.Conditions)
+			validated, err := ValidateAllConditions(obj1, obj2, m, patch.Conditions)
 			if err != nil {
 				return errors.Wrap(err, "validate conditions")
 			} else if !validated {
@@ -395,7 +464,7 @@ func RewriteLabelSelector(obj1 *yaml.Node, patch *config.Patch, resolver NameRes
 	return nil
 }
 
-func RewriteLabelExpressionsSelector(obj1 *yaml.Node, patch *config.Patch, resolver NameResolver) error {
+func RewriteLabelExpressionsSelector(obj1, obj2 *yaml.Node, patch *config.Patch, resolver NameResolver) error {
 	matches, err := FindMatches(obj1, patch.Path)
 	if err != nil {
 		return errors.Wrap(err, "find matches")
@@ -403,7 +472,7 @@ func RewriteLabelExpressionsSelector(obj1 *yaml.Node, patch *config.Patch, resol
 
 	for _, m := range matches {
 		if m.Kind == yaml.MappingNode {
-			validated, err := ValidateAllConditions(obj1, m, patch.Conditions)
+			validated, err := ValidateAllConditions(obj1, obj2, m, patch.Conditions)
 			if err != nil {
 				return errors.Wrap(err, "validate conditions")
 			} else if !validated {
@@ -554,3 +623,54 @@ func AddNode(obj1 *yaml.Node, match *yaml.Node, value *yaml.Node) {
 		match.Content[0].Content = append(match.Content[0].Content, value.Content[0].Content...)
 	}
 }
+
+// JSONPatch applies the RFC 6902 operations of the patch on the whole document. If
+// a test operation fails, the document is left untouched.
+func JSONPatch(obj1, obj2 *yaml.Node, patch *config.Patch) error {
+	validated, err := ValidateAllConditions(obj1, obj2, nil, patch.Conditions)
+	if err != nil {
+		return errors.Wrap(err, "validate conditions")
+	} else if !validated {
+		return nil
+	}
+
+	rawPatch, err := json.Marshal(patch.JSONPatch)
+	if err != nil {
+		return errors.Wrap(err, "marshal json patch")
+	}
+	jsonPatch, err := jsonpatch.DecodePatch(rawPatch)
+	if err != nil {
+		return errors.Wrap(err, "decode json patch")
+	}
+
+	var doc interface{}
+	err = obj1.Decode(&doc)
+	if err != nil {
+		return errors.Wrap(err, "decode document")
+	}
+	rawDoc, err := json.Marshal(doc)
+	if err != nil {
+		return errors.Wrap(err, "marshal document")
+	}
+
+	rawDoc, err = jsonPatch.Apply(rawDoc)
+	if err != nil {
+		if errors.Is(err, jsonpatch.ErrTestFailed) {
+			return nil
+		}
+
+		return errors.Wrap(err, "apply json patch")
+	}
+
+	err = json.Unmarshal(rawDoc, &doc)
+	if err != nil {
+		return errors.Wrap(err, "unmarshal document")
+	}
+	node, err := NewNode(doc)
+	if err != nil {
+		return err
+	}
+
+	obj1.Content = node.Content
+	return nil
+}
@@ -0,0 +1,464 @@
+package patches
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/config"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+// SchemaError is a problem of a patch that was found by checking it against a schema
+type SchemaError struct {
+	// Field is the field of the patch the error belongs to, e.g. path or conditions[0].subPath
+	Field string
+
+	// Message describes the problem
+	Message string
+}
+
+// ValidatePatchSchema checks all paths of the patch against the given structural schema and
+// returns the paths that can never match as well as rewriteName targets that are not strings.
+func ValidatePatchSchema(schema *apiextensionsv1.JSONSchemaProps, patch *config.Patch) []SchemaError {
+	root := withObjectMeta(schema)
+	errs := []SchemaError{}
+
+	var targets *schemaMatch
+	if patch.Path != "" {
+		var err error
+		targets, err = resolveSchemaPath(&schemaMatch{schemas: []*apiextensionsv1.JSONSchemaProps{root}}, patch.Path)
+		if err != nil {
+			errs = append(errs, SchemaError{Field: "path", Message: err.Error()})
+		} else if patch.Operation == config.PatchTypeRewriteName {
+			errs = append(errs, validateRewriteNameSchema(targets, patch)...)
+		}
+	}
+
+	if patch.FromPath != "" {
+		_, err := resolveSchemaPath(&schemaMatch{schemas: []*apiextensionsv1.JSONSchemaProps{root}}, patch.FromPath)
+		if err != nil {
+			errs = append(errs, SchemaError{Field: "fromPath", Message: err.Error()})
+		}
+	}
+
+	for idx, condition := range patch.Conditions {
+		errs = append(errs, validateConditionSchema(root, targets, fmt.Sprintf("conditions[%d]", idx), condition)...)
+	}
+
+	return errs
+}
+
+func validateConditionSchema(root *apiextensionsv1.JSONSchemaProps, targets *schemaMatch, field string, condition *config.PatchCondition) []SchemaError {
+	if condition == nil {
+		return nil
+	}
+
+	errs := []SchemaError{}
+	if condition.SubPath != "" {
+		if targets != nil {
+			_, err := resolveSchemaPath(targets, condition.SubPath)
+			if err != nil {
+				errs = append(errs, SchemaError{Field: field + ".subPath", Message: err.Error()})
+			}
+		}
+	} else if condition.Path != "" {
+		_, err := resolveSchemaPath(&schemaMatch{schemas: []*apiextensionsv1.JSONSchemaProps{root}}, condition.Path)
+		if err != nil {
+			errs = append(errs, SchemaError{Field: field + ".path", Message: err.Error()})
+		}
+	}
+
+	for idx, subCondition := range condition.AllOf {
+		errs = append(errs, validateConditionSchema(root, targets, fmt.Sprintf("%s.allOf[%d]", field, idx), subCondition)...)
+	}
+	for idx, subCondition := range condition.AnyOf {
+		errs = append(errs, validateConditionSchema(root, targets, fmt.Sprintf("%s.anyOf[%d]", field, idx), subCondition)...)
+	}
+	errs = append(errs, validateConditionSchema(root, targets, field+".not", condition.Not)...)
+	return errs
+}
+
+func validateRewriteNameSchema(targets *schemaMatch, patch *config.Patch) []SchemaError {
+	if targets.unknown {
+		return nil
+	}
+
+	// without a name path the target itself is rewritten
+	if patch.NamePath == "" {
+		for _, s := range targets.schemas {
+			if s.Type == "array" && s.Items != nil && s.Items.Schema != nil {
+				s = s.Items.Schema
+			}
+			if !isStringSchema(s) {
+				return []SchemaError{{Field: "path", Message: fmt.Sprintf("rewriteName target %s is of type %s, but must be a string", patch.Path, schemaType(s))}}
+			}
+		}
+
+		return nil
+	}
+
+	// with a name path the target has to be an object or a list of objects
+	elements := &schemaMatch{}
+	for _, s := range targets.schemas {
+		if s.Type == "array" && s.Items != nil && s.Items.Schema != nil {
+			s = s.Items.Schema
+		}
+		if s.Type != "object" && !isUnknownSchema(s) {
+			return []SchemaError{{Field: "path", Message: fmt.Sprintf("rewriteName target %s is of type %s, but must be an object or an array of objects if namePath is used", patch.Path, schemaType(s))}}
+		}
+
+		elements.schemas = append(elements.schemas, s)
+	}
+
+	errs := []SchemaError{}
+	for _, field := range []struct{ name, path string }{{"namePath", patch.NamePath}, {"namespacePath", patch.NamespacePath}} {
+		path := field.path
+		if path == "" {
+			continue
+		}
+
+		match, err := resolveSchemaPath(elements, path)
+		if err != nil {
+			errs = append(errs, SchemaError{Field: field.name, Message: err.Error()})
+			continue
+		} else if match.unknown {
+			continue
+		}
+
+		for _, s := range match.schemas {
+			if !isStringSchema(s) {
+				errs = append(errs, SchemaError{Field: field.name, Message: fmt.Sprintf("rewriteName target %s is of type %s, but must be a string", path, schemaType(s))})
+				break
+			}
+		}
+	}
+
+	return errs
+}
+
+// schemaMatch holds the schemas a path resolves to. If unknown is true,
+// the path led into a part of the schema that cannot be checked.
+type schemaMatch struct {
+	schemas []*apiextensionsv1.JSONSchemaProps
+	unknown bool
+}
+
+func resolveSchemaPath(from *schemaMatch, path string) (*schemaMatch, error) {
+	segments, err := parseSchemaPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	current := from
+	for _, segment := range segments {
+		if current.unknown {
+			return current, nil
+		}
+
+		next := &schemaMatch{}
+		for _, s := range current.schemas {
+			if isUnknownSchema(s) {
+				next.unknown = true
+				continue
+			}
+
+			switch segment.segmentType {
+			case segmentRecursive:
+				next.unknown = true
+			case segmentChild:
+				for _, name := range segment.names {
+					child, unknown := childSchema(s, name)
+					if child != nil {
+						next.schemas = append(next.schemas, child)
+					}
+					next.unknown = next.unknown || unknown
+				}
+			case segmentWildcard, segmentFilter:
+				if s.Type == "array" {
+					if s.Items != nil && s.Items.Schema != nil {
+						next.schemas = append(next.schemas, s.Items.Schema)
+					} else {
+						next.unknown = true
+					}
+				} else if s.Type == "object" {
+					for name := range s.Properties {
+						prop := s.Properties[name]
+						next.schemas = append(next.schemas, &prop)
+					}
+					if s.AdditionalProperties != nil {
+						if s.AdditionalProperties.Schema != nil {
+							next.schemas = append(next.schemas, s.AdditionalProperties.Schema)
+						} else if s.AdditionalProperties.Allows {
+							next.unknown = true
+						}
+					}
+					next.unknown = next.unknown || (s.XPreserveUnknownFields != nil && *s.XPreserveUnknownFields)
+				}
+			case segmentIndex:
+				if s.Type == "array" {
+					if s.Items != nil && s.Items.Schema != nil {
+						next.schemas = append(next.schemas, s.Items.Schema)
+					} else {
+						next.unknown = true
+					}
+				}
+			}
+		}
+
+		if len(next.schemas) == 0 && !next.unknown {
+			return nil, fmt.Errorf("%s can never match, as %s does not exist in the schema", path, segment.prefix)
+		}
+
+		current = next
+	}
+
+	return current, nil
+}
+
+// childSchema returns the schema of the given property
+func childSchema(s *apiextensionsv1.JSONSchemaProps, name string) (*apiextensionsv1.JSONSchemaProps, bool) {
+	if s.Type != "object" {
+		return nil, false
+	}
+
+	if prop, ok := s.Properties[name]; ok {
+		return &prop, false
+	} else if s.AdditionalProperties != nil {
+		if s.AdditionalProperties.Schema != nil {
+			return s.AdditionalProperties.Schema, false
+		}
+
+		return nil, s.AdditionalProperties.Allows
+	}
+
+	return nil, s.XPreserveUnknownFields != nil && *s.XPreserveUnknownFields
+}
+
+// isUnknownSchema returns true if the schema doesn't tell anything about its content
+func isUnknownSchema(s *apiextensionsv1.JSONSchemaProps) bool {
+	if s.XPreserveUnknownFields != nil && *s.XPreserveUnknownFields && len(s.Properties) == 0 {
+		return true
+	}
+
+	return s.Type == "" && !s.XIntOrString && len(s.Properties) == 0
+}
+
+func isStringSchema(s *apiextensionsv1.JSONSchemaProps) bool {
+	return s.Type == "string" || isUnknownSchema(s)
+}
+
+func schemaType(s *apiextensionsv1.JSONSchemaProps) string {
+	if s.XIntOrString {
+		return "int-or-string"
+	}
+
+	return s.Type
+}
+
+// withObjectMeta adds the well known object metadata fields to the schema,
+// as the schema of a CRD doesn't contain them
+func withObjectMeta(schema *apiextensionsv1.JSONSchemaProps) *apiextensionsv1.JSONSchemaProps {
+	root := schema.DeepCopy()
+	if root.Properties == nil {
+		root.Properties = map[string]apiextensionsv1.JSONSchemaProps{}
+	}
+	if metadata, ok := root.Properties["metadata"]; !ok || len(metadata.Properties) == 0 {
+		root.Properties["metadata"] = objectMetaSchema
+	}
+	if _, ok := root.Properties["apiVersion"]; !ok {
+		root.Properties["apiVersion"] = apiextensionsv1.JSONSchemaProps{Type: "string"}
+	}
+	if _, ok := root.Properties["kind"]; !ok {
+		root.Properties["kind"] = apiextensionsv1.JSONSchemaProps{Type: "string"}
+	}
+
+	return root
+}
+
+var (
+	stringSchema    = apiextensionsv1.JSONSchemaProps{Type: "string"}
+	stringMapSchema = apiextensionsv1.JSONSchemaProps{
+		Type:                 "object",
+		AdditionalProperties: &apiextensionsv1.JSONSchemaPropsOrBool{Allows: true, Schema: &stringSchema},
+	}
+	preserveUnknownFields = true
+	objectMetaSchema      = apiextensionsv1.JSONSchemaProps{
+		Type: "object",
+		Properties: map[string]apiextensionsv1.JSONSchemaProps{
+			"name":              stringSchema,
+			"namespace":         stringSchema,
+			"generateName":      stringSchema,
+			"uid":               stringSchema,
+			"resourceVersion":   stringSchema,
+			"creationTimestamp": stringSchema,
+			"deletionTimestamp": stringSchema,
+			"generation":        {Type: "integer"},
+			"labels":            stringMapSchema,
+			"annotations":       stringMapSchema,
+			"finalizers": {
+				Type:  "array",
+				Items: &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &stringSchema},
+			},
+			"ownerReferences": {
+				Type: "array",
+				Items: &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &apiextensionsv1.JSONSchemaProps{
+					Type: "object",
+					Properties: map[string]apiextensionsv1.JSONSchemaProps{
+						"apiVersion":         stringSchema,
+						"kind":               stringSchema,
+						"name":               stringSchema,
+						"uid":                stringSchema,
+						"controller":         {Type: "boolean"},
+						"blockOwnerDeletion": {Type: "boolean"},
+					},
+				}},
+			},
+			"managedFields": {
+				Type:                   "array",
+				XPreserveUnknownFields: &preserveUnknownFields,
+			},
+		},
+	}
+)
+
+type segmentType int
+
+const (
+	// segmentChild selects the properties in names
+	segmentChild segmentType = iota
+	// segmentWildcard selects all properties or items
+	segmentWildcard
+	// segmentIndex selects items of an array by index or slice
+	segmentIndex
+	// segmentFilter selects items by a filter expression
+	segmentFilter
+	// segmentRecursive selects all descendants, which we cannot check
+	segmentRecursive
+)
+
+type pathSegment struct {
+	segmentType segmentType
+	names       []string
+
+	// prefix is the path up to and including this segment
+	prefix string
+}
+
+// parseSchemaPath splits a yaml-jsonpath into its segments
+func parseSchemaPath(path string) ([]pathSegment, error) {
+	segments := []pathSegment{}
+	i := 0
+	if strings.HasPrefix(path, "$") {
+		i = 1
+	}
+
+	for i < len(path) {
+		switch path[i] {
+		case '.':
+			if strings.HasPrefix(path[i:], "..") {
+				return append(segments, pathSegment{segmentType: segmentRecursive, prefix: path}), nil
+			}
+
+			name, end := readName(path, i+1)
+			if name == "" {
+				return nil, fmt.Errorf("invalid path %s: expected a name at position %d", path, i+1)
+			}
+
+			segments = append(segments, nameSegment(name, path[:end]))
+			i = end
+		case '[':
+			end, err := findClosingBracket(path, i)
+			if err != nil {
+				return nil, err
+			}
+
+			segment, err := bracketSegment(strings.TrimSpace(path[i+1:end]), path[:end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid path %s: %v", path, err)
+			}
+
+			segments = append(segments, segment)
+			i = end + 1
+		default:
+			// a path can start with a name without a dot
+			if len(segments) > 0 {
+				return nil, fmt.Errorf("invalid path %s: unexpected character %q at position %d", path, path[i], i)
+			}
+
+			name, end := readName(path, i)
+			segments = append(segments, nameSegment(name, path[:end]))
+			i = end
+		}
+	}
+
+	return segments, nil
+}
+
+func nameSegment(name, prefix string) pathSegment {
+	if name == "*" {
+		return pathSegment{segmentType: segmentWildcard, prefix: prefix}
+	}
+
+	return pathSegment{segmentType: segmentChild, names: []string{name}, prefix: prefix}
+}
+
+func readName(path string, start int) (string, int) {
+	end := start
+	for end < len(path) && path[end] != '.' && path[end] != '[' {
+		end++
+	}
+
+	return path[start:end], end
+}
+
+func findClosingBracket(path string, start int) (int, error) {
+	depth := 0
+	var quote byte
+	for i := start; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+			if depth == 0 {
+				return i, nil
+			}
+		}
+	}
+
+	return 0, fmt.Errorf("invalid path %s: missing ] for [ at position %d", path, start)
+}
+
+func bracketSegment(content, prefix string) (pathSegment, error) {
+	switch {
+	case content == "*":
+		return pathSegment{segmentType: segmentWildcard, prefix: prefix}, nil
+	case strings.HasPrefix(content, "?"):
+		return pathSegment{segmentType: segmentFilter, prefix: prefix}, nil
+	case strings.HasPrefix(content, "'") || strings.HasPrefix(content, `"`):
+		names := []string{}
+		for _, name := range strings.Split(content, ",") {
+			name = strings.TrimSpace(name)
+			if len(name) < 2 || (name[0] != '\'' && name[0] != '"') || name[len(name)-1] != name[0] {
+				return pathSegment{}, fmt.Errorf("invalid property name %s", name)
+			}
+
+			names = append(names, name[1:len(name)-1])
+		}
+
+		return pathSegment{segmentType: segmentChild, names: names, prefix: prefix}, nil
+	case strings.Trim(content, "0123456789-:, ") == "" && content != "":
+		return pathSegment{segmentType: segmentIndex, prefix: prefix}, nil
+	}
+
+	return pathSegment{}, fmt.Errorf("unsupported selector [%s]", content)
+}
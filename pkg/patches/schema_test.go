@@ -0,0 +1,232 @@
+package patches
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/config"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const testSchema = `type: object
+properties:
+  spec:
+    type: object
+    properties:
+      secretName:
+        type: string
+      port:
+        type: integer
+      issuerRef:
+        type: object
+        properties:
+          name:
+            type: string
+          kind:
+            type: string
+      refs:
+        type: array
+        items:
+          type: object
+          properties:
+            name:
+              type: string
+            namespace:
+              type: string
+            weight:
+              type: integer
+      dnsNames:
+        type: array
+        items:
+          type: string
+      config:
+        type: object
+        x-kubernetes-preserve-unknown-fields: true
+  status:
+    type: object
+    properties:
+      ready:
+        type: boolean
+`
+
+type schemaTestCase struct {
+	name  string
+	patch *config.Patch
+
+	expectedFields []string
+	expectedErr    string
+}
+
+func TestValidatePatchSchema(t *testing.T) {
+	True := true
+	crdSchema := &apiextensionsv1.JSONSchemaProps{}
+	err := yaml.Unmarshal([]byte(testSchema), crdSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []*schemaTestCase{
+		{
+			name: "valid paths",
+			patch: &config.Patch{
+				Operation: config.PatchTypeCopyFromObject,
+				FromPath:  "status.ready",
+				Path:      "$.spec.refs[0]",
+				Conditions: []*config.PatchCondition{
+					{Path: "metadata.labels['app']", Equal: "test"},
+					{SubPath: "name", Empty: &True},
+				},
+			},
+		},
+		{
+			name: "unknown fields",
+			patch: &config.Patch{
+				Operation: config.PatchTypeCopyFromObject,
+				FromPath:  "spec.config.anything.below",
+				Path:      "spec..name",
+			},
+		},
+		{
+			name: "missing path",
+			patch: &config.Patch{
+				Operation: config.PatchTypeReplace,
+				Path:      "spec.issuerRef.group",
+			},
+			expectedFields: []string{"path"},
+			expectedErr:    "spec.issuerRef.group does not exist",
+		},
+		{
+			name: "missing from path and condition",
+			patch: &config.Patch{
+				Operation: config.PatchTypeCopyFromObject,
+				FromPath:  "status.conditions[*].type",
+				Path:      "spec.refs[?(@.name=='test')]",
+				Conditions: []*config.PatchCondition{
+					{SubPath: "kind", Empty: &True},
+					{AnyOf: []*config.PatchCondition{
+						{SubPath: "name", Empty: &True},
+						{Not: &config.PatchCondition{Path: "spec.missing", Exists: &True}},
+					}},
+				},
+			},
+			expectedFields: []string{"fromPath", "conditions[0].subPath", "conditions[1].anyOf[1].not.path"},
+		},
+		{
+			name: "rewriteName string",
+			patch: &config.Patch{
+				Operation: config.PatchTypeRewriteName,
+				Path:      "spec['secretName']",
+			},
+		},
+		{
+			name: "rewriteName string array",
+			patch: &config.Patch{
+				Operation: config.PatchTypeRewriteName,
+				Path:      "spec.dnsNames",
+			},
+		},
+		{
+			name: "rewriteName object",
+			patch: &config.Patch{
+				Operation: config.PatchTypeRewriteName,
+				Path:      "spec.issuerRef",
+			},
+			expectedFields: []string{"path"},
+			expectedErr:    "is of type object, but must be a string",
+		},
+		{
+			name: "rewriteName with name path",
+			patch: &config.Patch{
+				Operation:     config.PatchTypeRewriteName,
+				Path:          "spec.refs",
+				NamePath:      "name",
+				NamespacePath: "namespace",
+			},
+		},
+		{
+			name: "rewriteName with invalid name path",
+			patch: &config.Patch{
+				Operation:     config.PatchTypeRewriteName,
+				Path:          "spec.refs",
+				NamePath:      "weight",
+				NamespacePath: "ns",
+			},
+			expectedFields: []string{"namePath", "namespacePath"},
+		},
+		{
+			name: "rewriteName with name path on scalar",
+			patch: &config.Patch{
+				Operation: config.PatchTypeRewriteName,
+				Path:      "spec.port",
+				NamePath:  "name",
+			},
+			expectedFields: []string{"path"},
+			expectedErr:    "must be an object or an array of objects",
+		},
+	}
+
+	for _, testCase := range testCases {
+		errs := ValidatePatchSchema(crdSchema, testCase.patch)
+		if len(errs) != len(testCase.expectedFields) {
+			t.Errorf("TestCase %s: expected %d errors, got %v", testCase.name, len(testCase.expectedFields), errs)
+			continue
+		}
+
+		for i, field := range testCase.expectedFields {
+			if errs[i].Field != field {
+				t.Errorf("TestCase %s: expected error for field %s, got %s", testCase.name, field, errs[i].Field)
+			}
+			if testCase.expectedErr != "" && !strings.Contains(errs[i].Message, testCase.expectedErr) {
+				t.Errorf("TestCase %s: expected error %q to contain %q", testCase.name, errs[i].Message, testCase.expectedErr)
+			}
+		}
+	}
+}
+
+func TestParseSchemaPath(t *testing.T) {
+	testCases := map[string][]segmentType{
+		"spec.name":                               {segmentChild, segmentChild},
+		"$.spec.name":                             {segmentChild, segmentChild},
+		"$spec":                                   {segmentChild},
+		"metadata.annotations['test.io/name']":    {segmentChild, segmentChild, segmentChild},
+		"spec.items[*].name":                      {segmentChild, segmentChild, segmentWildcard, segmentChild},
+		"spec.items[0:2]":                         {segmentChild, segmentChild, segmentIndex},
+		"spec.*.name":                             {segmentChild, segmentWildcard, segmentChild},
+		"spec.items[?(@.name=~/^a\\]b/)].value":   {segmentChild, segmentChild, segmentFilter, segmentChild},
+		"spec..name":                              {segmentChild, segmentRecursive},
+		`spec.items[?(@.name=='a]b')]`:            {segmentChild, segmentChild, segmentFilter},
+		`spec["a", "b"]`:                          {segmentChild, segmentChild},
+		"spec.deployments[?(@.name=~/^backend/)]": {segmentChild, segmentChild, segmentFilter},
+	}
+
+	for input, expected := range testCases {
+		segments, err := parseSchemaPath(input)
+		if err != nil {
+			t.Errorf("TestCase %s: unexpected error %v", input, err)
+			continue
+		}
+
+		actual := []segmentType{}
+		for _, segment := range segments {
+			actual = append(actual, segment.segmentType)
+		}
+		if len(actual) != len(expected) {
+			t.Errorf("TestCase %s\nactual:%v\nexpected:%v", input, actual, expected)
+			continue
+		}
+		for i := range expected {
+			if actual[i] != expected[i] {
+				t.Errorf("TestCase %s\nactual:%v\nexpected:%v", input, actual, expected)
+				break
+			}
+		}
+	}
+
+	for _, input := range []string{"spec.", "spec[abc]", "spec[0"} {
+		_, err := parseSchemaPath(input)
+		if err == nil {
+			t.Errorf("TestCase %s: expected an error", input)
+		}
+	}
+}
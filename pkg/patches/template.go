@@ -0,0 +1,116 @@
+package patches
+
+import (
+	"bytes"
+
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/config"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// TemplateContext holds information about the current sync that can be
+// accessed within template patches via .Context
+type TemplateContext struct {
+	// VirtualNamespace is the namespace of the object within the virtual cluster
+	VirtualNamespace string
+	// HostNamespace is the namespace of the object within the host cluster
+	HostNamespace string
+	// PluginName is the name of this plugin
+	PluginName string
+	// ControllerID is the id of the mapping that syncs the object
+	ControllerID string
+}
+
+// Template renders the template of the patch and writes the result as string into path. The current
+// object is the root of the template, the other object is available as .Other and the
+// sync information as .Context, e.g. {{ .metadata.name }}-{{ .Other.spec.name }}-{{ .Context.HostNamespace }}.
+// The template is only rendered if the conditions of the patch are met.
+func Template(obj1, obj2 *yaml.Node, patch *config.Patch, templateContext *TemplateContext) error {
+	matches, err := FindMatches(obj1, patch.Path)
+	if err != nil {
+		return errors.Wrap(err, "find matches")
+	}
+
+	if len(matches) == 0 {
+		validated, err := ValidateAllConditions(obj1, obj2, nil, patch.Conditions)
+		if err != nil {
+			return errors.Wrap(err, "validate conditions")
+		} else if !validated {
+			return nil
+		}
+
+		value, err := renderTemplate(obj1, obj2, patch, templateContext)
+		if err != nil {
+			return err
+		}
+
+		return createPath(obj1, patch.Path, value)
+	}
+
+	validatedMatches := []*yaml.Node{}
+	for _, m := range matches {
+		validated, err := ValidateAllConditions(obj1, obj2, m, patch.Conditions)
+		if err != nil {
+			return errors.Wrap(err, "validate conditions")
+		} else if validated {
+			validatedMatches = append(validatedMatches, m)
+		}
+	}
+	if len(validatedMatches) == 0 {
+		return nil
+	}
+
+	value, err := renderTemplate(obj1, obj2, patch, templateContext)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range validatedMatches {
+		ReplaceNode(obj1, m, value)
+	}
+
+	return nil
+}
+
+func renderTemplate(obj1, obj2 *yaml.Node, patch *config.Patch, templateContext *TemplateContext) (*yaml.Node, error) {
+	tpl := patch.ParsedTemplate
+	if tpl == nil {
+		var err error
+		tpl, err = config.ParseTemplate(patch.Template)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse template")
+		}
+	}
+
+	data := map[string]interface{}{}
+	err := obj1.Decode(&data)
+	if err != nil {
+		return nil, errors.Wrap(err, "decode object")
+	}
+	if obj2 != nil {
+		other := map[string]interface{}{}
+		err = obj2.Decode(&other)
+		if err != nil {
+			return nil, errors.Wrap(err, "decode other object")
+		}
+
+		data["Other"] = other
+	}
+	if templateContext == nil {
+		templateContext = &TemplateContext{}
+	}
+	data["Context"] = templateContext
+
+	buf := &bytes.Buffer{}
+	err = tpl.Execute(buf, data)
+	if err != nil {
+		return nil, errors.Wrap(err, "execute template")
+	}
+
+	value, err := NewNode(buf.String())
+	if err != nil {
+		return nil, errors.Wrap(err, "new node from rendered template")
+	}
+
+	return value, nil
+}
@@ -0,0 +1,117 @@
+package patches
+
+import (
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/config"
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/util/diff"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v3"
+	k8syaml "sigs.k8s.io/yaml"
+)
+
+// Trace records every step of ApplyPatchesWithTrace, e.g. to find out which patch changed a field
+type Trace struct {
+	Steps []*TraceStep
+}
+
+// TraceStep is the application of a single patch
+type TraceStep struct {
+	// Operation is the operation of the patch
+	Operation config.PatchType
+
+	// Path is the path of the patch
+	Path string
+
+	// ConditionsMatched is true if the conditions of the patch were met for at least one
+	// of the matched nodes or, if the path didn't match, for the document
+	ConditionsMatched bool
+
+	// Matches is the number of nodes the path matched before the patch was applied
+	Matches int
+
+	// Diff is a unified diff of the document before and after the patch was applied
+	Diff string
+
+	// Error is the error of the patch, if it couldn't be applied
+	Error string
+}
+
+// newTraceStep evaluates the path and conditions of the patch on the document before it is applied
+func newTraceStep(obj1, obj2 *yaml.Node, patch *config.Patch) (*TraceStep, error) {
+	step := &TraceStep{
+		Operation: patch.Operation,
+		Path:      patch.Path,
+	}
+
+	var matches []*yaml.Node
+	if patch.Path != "" {
+		var err error
+		matches, err = FindMatches(obj1, patch.Path)
+		if err != nil {
+			return nil, errors.Wrap(err, "find matches")
+		}
+	}
+	step.Matches = len(matches)
+
+	if len(matches) == 0 {
+		matches = []*yaml.Node{nil}
+	}
+	for _, m := range matches {
+		validated, err := ValidateAllConditions(obj1, obj2, m, patch.Conditions)
+		if err != nil {
+			return nil, errors.Wrap(err, "validate conditions")
+		} else if validated {
+			step.ConditionsMatched = true
+			break
+		}
+	}
+
+	return step, nil
+}
+
+// traceApplyPatch applies the patch and records the step in the trace
+func (t *Trace) traceApplyPatch(obj1, obj2 *yaml.Node, patch *config.Patch, apply func() error) error {
+	step, err := newTraceStep(obj1, obj2, patch)
+	if err != nil {
+		// the patch itself will fail the same way
+		step = &TraceStep{Operation: patch.Operation, Path: patch.Path}
+	}
+	t.Steps = append(t.Steps, step)
+
+	before, err := documentYAML(obj1)
+	if err != nil {
+		return err
+	}
+
+	applyErr := apply()
+	if applyErr != nil {
+		step.Error = applyErr.Error()
+	}
+
+	after, err := documentYAML(obj1)
+	if err != nil {
+		return err
+	}
+	step.Diff, err = diff.Unified(before, after)
+	if err != nil {
+		return errors.Wrap(err, "diff")
+	}
+
+	return applyErr
+}
+
+// documentYAML returns the document as block style yaml, as the documents created from json
+// objects are marshaled in flow style on a single line, which makes the diffs unreadable
+func documentYAML(obj *yaml.Node) (string, error) {
+	var doc interface{}
+	err := obj.Decode(&doc)
+	if err != nil {
+		return "", errors.Wrap(err, "decode document")
+	}
+
+	out, err := k8syaml.Marshal(doc)
+	if err != nil {
+		return "", errors.Wrap(err, "marshal yaml")
+	}
+
+	return string(out), nil
+}
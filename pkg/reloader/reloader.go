@@ -0,0 +1,352 @@
+package reloader
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/config"
+	"github.com/loft-sh/vcluster-sdk/log"
+	"github.com/loft-sh/vcluster-sdk/syncer"
+	synccontext "github.com/loft-sh/vcluster-sdk/syncer/context"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+)
+
+const (
+	// cacheSyncTimeout is the maximum time we wait for the caches of a new configuration to sync
+	cacheSyncTimeout = time.Minute * 2
+)
+
+// SyncersFactory creates all syncers that are needed for the given configuration
+type SyncersFactory func(ctx *synccontext.RegisterContext, configuration *config.Config) ([]syncer.Base, error)
+
+// NewReloader creates a controller that loads the configuration from the given source and
+// (re)creates all syncers with the factory each time the configuration changes. As syncers and
+// their informers cannot be removed from a running manager, each configuration runs in its own
+// physical and virtual manager. The managers of the previous configuration are stopped before
+// the syncers of the next configuration are started, so that both never reconcile the same
+// objects at the same time. State that has to survive a reload, e.g. the events that were
+// forwarded already, is held outside of the syncers and passed in by the factory.
+func NewReloader(source Source, factory SyncersFactory, newClient cluster.NewClientFunc) syncer.Base {
+	r := &reloader{
+		log:       log.New("config-reloader"),
+		source:    source,
+		factory:   factory,
+		newClient: newClient,
+	}
+	r.newGeneration = r.createGeneration
+	r.startGeneration = r.runGeneration
+	return r
+}
+
+type reloader struct {
+	log       log.Logger
+	source    Source
+	factory   SyncersFactory
+	newClient cluster.NewClientFunc
+
+	// newGeneration and startGeneration create and start the syncers of a configuration
+	newGeneration   func(parent *synccontext.RegisterContext, configuration *config.Config) (*generation, error)
+	startGeneration func(g *generation) error
+
+	m         sync.Mutex
+	current   *generation
+	rawConfig string
+}
+
+// generation holds the syncers and managers of a single configuration
+type generation struct {
+	configuration *config.Config
+	loadedConfig  string
+	ctx           *synccontext.RegisterContext
+	syncers       []syncer.Base
+	cancel        context.CancelFunc
+
+	// running is done as soon as the managers of the generation have exited
+	running sync.WaitGroup
+}
+
+// stop stops the managers of the generation and waits until they have exited
+func (g *generation) stop() {
+	g.cancel()
+	g.running.Wait()
+}
+
+var _ syncer.ControllerStarter = &reloader{}
+
+func (r *reloader) Name() string {
+	return "config-reloader"
+}
+
+func (r *reloader) Register(ctx *synccontext.RegisterContext) error {
+	rawConfig, err := r.source.Start(ctx.Context, func(rawConfig string) {
+		err := r.reload(ctx, rawConfig)
+		if err != nil {
+			r.log.Errorf("Rejected new configuration, keeping the last valid configuration: %v", err)
+		}
+	})
+	if err != nil {
+		return errors.Wrap(err, "load configuration")
+	}
+
+	return r.reload(ctx, rawConfig)
+}
+
+func (r *reloader) reload(ctx *synccontext.RegisterContext, rawConfig string) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if r.current != nil && r.rawConfig == rawConfig {
+		return nil
+	}
+
+	configuration, err := config.ParseConfig(rawConfig)
+	if err != nil {
+		return err
+	}
+	loadedConfig, err := yaml.Marshal(configuration)
+	if err != nil {
+		return err
+	}
+
+	// changes that don't change the parsed configuration, e.g. of comments or the
+	// formatting, don't need to restart the syncers
+	if r.current != nil && r.current.loadedConfig == string(loadedConfig) {
+		r.log.Infof("Configuration has changed, but is equivalent to the current configuration")
+		r.rawConfig = rawConfig
+		return nil
+	}
+	r.log.Infof("Loaded configuration:\n%s", string(loadedConfig))
+
+	// create the new syncers before the old ones are stopped, so that a
+	// configuration that cannot be applied does not interrupt syncing
+	next, err := r.newGeneration(ctx, configuration)
+	if err != nil {
+		return err
+	}
+	next.loadedConfig = string(loadedConfig)
+
+	previous := r.current
+	if previous != nil {
+		r.log.Infof("Stopping syncers of the previous configuration")
+		previous.stop()
+		r.current = nil
+	}
+
+	err = r.startGeneration(next)
+	if err != nil {
+		next.stop()
+		if previous != nil {
+			r.restart(ctx, previous)
+		}
+
+		return err
+	}
+
+	r.current = next
+	r.rawConfig = rawConfig
+	return nil
+}
+
+// restart starts the syncers of a stopped generation again with new managers
+func (r *reloader) restart(ctx *synccontext.RegisterContext, previous *generation) {
+	r.log.Infof("Restarting syncers of the previous configuration")
+	restarted, err := r.newGeneration(ctx, previous.configuration)
+	if err == nil {
+		restarted.loadedConfig = previous.loadedConfig
+		err = r.startGeneration(restarted)
+		if err != nil {
+			restarted.stop()
+		}
+	}
+	if err != nil {
+		r.log.Errorf("Error restarting the previous configuration: %v", err)
+		return
+	}
+
+	r.current = restarted
+}
+
+// createGeneration creates new managers for the configuration and initializes its syncers
+func (r *reloader) createGeneration(parent *synccontext.RegisterContext, configuration *config.Config) (*generation, error) {
+	generationCtx, cancel := context.WithCancel(parent.Context)
+	physicalManager, err := ctrl.NewManager(parent.PhysicalManager.GetConfig(), ctrl.Options{
+		Scheme:             parent.PhysicalManager.GetScheme(),
+		MetricsBindAddress: "0",
+		LeaderElection:     false,
+		Namespace:          parent.TargetNamespace,
+		NewClient:          r.newClient,
+	})
+	if err != nil {
+		cancel()
+		return nil, errors.Wrap(err, "create physical manager")
+	}
+	virtualManager, err := ctrl.NewManager(parent.VirtualManager.GetConfig(), ctrl.Options{
+		Scheme:             parent.VirtualManager.GetScheme(),
+		MetricsBindAddress: "0",
+		LeaderElection:     false,
+		NewClient:          r.newClient,
+	})
+	if err != nil {
+		cancel()
+		return nil, errors.Wrap(err, "create virtual manager")
+	}
+
+	registerCtx := *parent
+	registerCtx.Context = generationCtx
+	registerCtx.PhysicalManager = physicalManager
+	registerCtx.VirtualManager = virtualManager
+
+	syncers, err := r.factory(&registerCtx, configuration)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// initialize the syncers the same way the plugin manager does
+	for _, s := range syncers {
+		initializer, ok := s.(syncer.Initializer)
+		if ok {
+			err := initializer.Init(&registerCtx)
+			if err != nil {
+				cancel()
+				return nil, errors.Wrapf(err, "init syncer %s", s.Name())
+			}
+		}
+	}
+	for _, s := range syncers {
+		indexRegisterer, ok := s.(syncer.IndicesRegisterer)
+		if ok {
+			err := indexRegisterer.RegisterIndices(&registerCtx)
+			if err != nil {
+				cancel()
+				return nil, errors.Wrapf(err, "register indices for %s syncer", s.Name())
+			}
+		}
+	}
+
+	return &generation{
+		configuration: configuration,
+		ctx:           &registerCtx,
+		syncers:       syncers,
+		cancel:        cancel,
+	}, nil
+}
+
+// runGeneration starts the managers of the generation and registers its syncers as soon as the
+// caches of the mapped kinds are synced
+func (r *reloader) runGeneration(g *generation) error {
+	// the informers of the controllers are only created after the managers were started,
+	// so the informers of the mapped kinds are created upfront to wait for them
+	err := getInformers(g)
+	if err != nil {
+		return err
+	}
+
+	g.running.Add(2)
+	go func() {
+		defer g.running.Done()
+		err := g.ctx.PhysicalManager.Start(g.ctx.Context)
+		if err != nil {
+			r.log.Errorf("Error starting physical manager: %v", err)
+			g.cancel()
+		}
+	}()
+	go func() {
+		defer g.running.Done()
+		err := g.ctx.VirtualManager.Start(g.ctx.Context)
+		if err != nil {
+			r.log.Errorf("Error starting virtual manager: %v", err)
+			g.cancel()
+		}
+	}()
+
+	// wait for caches to be synced
+	waitCtx, cancel := context.WithTimeout(g.ctx.Context, cacheSyncTimeout)
+	defer cancel()
+	if !g.ctx.PhysicalManager.GetCache().WaitForCacheSync(waitCtx) {
+		return fmt.Errorf("timed out waiting for physical cache to sync")
+	} else if !g.ctx.VirtualManager.GetCache().WaitForCacheSync(waitCtx) {
+		return fmt.Errorf("timed out waiting for virtual cache to sync")
+	}
+
+	for _, v := range g.syncers {
+		fakeSyncer, ok := v.(syncer.FakeSyncer)
+		if ok {
+			r.log.Infof("Start fake syncer %s", fakeSyncer.Name())
+			err := syncer.RegisterFakeSyncer(g.ctx, fakeSyncer)
+			if err != nil {
+				return errors.Wrapf(err, "start %s syncer", v.Name())
+			}
+		}
+
+		realSyncer, ok := v.(syncer.Syncer)
+		if ok {
+			r.log.Infof("Start syncer %s", realSyncer.Name())
+			err := syncer.RegisterSyncer(g.ctx, realSyncer)
+			if err != nil {
+				return errors.Wrapf(err, "start %s syncer", v.Name())
+			}
+		}
+
+		controllerStarter, ok := v.(syncer.ControllerStarter)
+		if ok {
+			r.log.Infof("Start controller %s", v.Name())
+			err := controllerStarter.Register(g.ctx)
+			if err != nil {
+				return errors.Wrapf(err, "start %s controller", v.Name())
+			}
+		}
+	}
+
+	return nil
+}
+
+// getInformers creates the informers of the host and virtual objects of all mappings
+func getInformers(g *generation) error {
+	getInformer := func(c cache.Cache, gvk schema.GroupVersionKind) error {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(gvk)
+		_, err := c.GetInformer(g.ctx.Context, obj)
+		if err != nil {
+			return errors.Wrapf(err, "get informer for %s(%s)", gvk.Kind, gvk.GroupVersion().String())
+		}
+
+		return nil
+	}
+
+	for _, m := range g.configuration.Mappings {
+		gvks := []schema.GroupVersionKind{}
+		if m.FromVirtualCluster != nil {
+			gvks = append(gvks, m.FromVirtualCluster.GVK())
+			for _, syncBack := range m.FromVirtualCluster.SyncBack {
+				err := getInformer(g.ctx.PhysicalManager.GetCache(), syncBack.GVK())
+				if err != nil {
+					return err
+				}
+			}
+		} else if m.FromHostCluster != nil {
+			gvks = append(gvks, m.FromHostCluster.GVK())
+		}
+
+		for _, gvk := range gvks {
+			err := getInformer(g.ctx.PhysicalManager.GetCache(), gvk)
+			if err != nil {
+				return err
+			}
+			err = getInformer(g.ctx.VirtualManager.GetCache(), gvk)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package reloader
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/config"
+	"github.com/loft-sh/vcluster-sdk/log"
+	synccontext "github.com/loft-sh/vcluster-sdk/syncer/context"
+	"gotest.tools/assert"
+)
+
+const (
+	validConfig      = "version: v1beta1\n"
+	otherValidConfig = "version: v1beta1\nmappings:\n- fromHostCluster:\n    apiVersion: v1\n    kind: ConfigMap\n    nameMapping:\n      namespace: default\n"
+	failingConfig    = "version: v1beta1\ndryRun: true\n"
+)
+
+// newTestReloader returns a reloader whose generations are not backed by managers. Each generation
+// runs until it is stopped and fails to start while another one is still running. Generations of
+// configurations in dry run mode fail to start.
+func newTestReloader() *reloader {
+	running := int32(0)
+	r := &reloader{log: log.New("test")}
+	r.newGeneration = func(parent *synccontext.RegisterContext, configuration *config.Config) (*generation, error) {
+		ctx, cancel := context.WithCancel(parent.Context)
+		return &generation{configuration: configuration, ctx: &synccontext.RegisterContext{Context: ctx}, cancel: cancel}, nil
+	}
+	r.startGeneration = func(g *generation) error {
+		if atomic.LoadInt32(&running) != 0 {
+			return fmt.Errorf("previous generation is still running")
+		}
+
+		atomic.AddInt32(&running, 1)
+		g.running.Add(1)
+		go func() {
+			defer g.running.Done()
+			<-g.ctx.Context.Done()
+			time.Sleep(time.Millisecond * 10)
+			atomic.AddInt32(&running, -1)
+		}()
+
+		if g.configuration.DryRun {
+			return fmt.Errorf("start failed")
+		}
+
+		return nil
+	}
+	return r
+}
+
+func TestReload(t *testing.T) {
+	r := newTestReloader()
+	ctx := &synccontext.RegisterContext{Context: context.Background()}
+
+	// the first configuration must start
+	err := r.reload(ctx, failingConfig)
+	assert.ErrorContains(t, err, "start failed")
+	assert.Assert(t, r.current == nil)
+
+	err = r.reload(ctx, validConfig)
+	assert.NilError(t, err)
+	first := r.current
+	assert.Assert(t, first != nil)
+
+	// invalid configurations are rejected and the current configuration keeps running
+	err = r.reload(ctx, "version: v1alpha1\n")
+	assert.ErrorContains(t, err, "unsupported configuration version")
+	assert.Equal(t, r.current, first)
+	assert.NilError(t, first.ctx.Context.Err())
+
+	// configurations that fail to start are rejected and the current configuration is restarted
+	err = r.reload(ctx, failingConfig)
+	assert.ErrorContains(t, err, "start failed")
+	assert.ErrorContains(t, first.ctx.Context.Err(), "canceled")
+	assert.Assert(t, r.current != nil)
+	assert.Assert(t, r.current != first)
+	assert.Equal(t, r.current.configuration, first.configuration)
+	assert.Equal(t, r.current.loadedConfig, first.loadedConfig)
+	assert.Equal(t, r.rawConfig, validConfig)
+	assert.NilError(t, r.current.ctx.Context.Err())
+	first = r.current
+
+	// configurations that only differ in formatting or comments don't restart the syncers
+	err = r.reload(ctx, "# comment\n"+validConfig)
+	assert.NilError(t, err)
+	assert.Equal(t, r.current, first)
+	assert.NilError(t, first.ctx.Context.Err())
+
+	// the previous configuration is stopped before the next one is started
+	err = r.reload(ctx, otherValidConfig)
+	assert.NilError(t, err)
+	assert.Assert(t, r.current != first)
+	assert.Equal(t, r.rawConfig, otherValidConfig)
+	assert.ErrorContains(t, first.ctx.Context.Err(), "canceled")
+	assert.NilError(t, r.current.ctx.Context.Err())
+}
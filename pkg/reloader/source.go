@@ -0,0 +1,217 @@
+package reloader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/loft-sh/vcluster-sdk/log"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	toolscache "k8s.io/client-go/tools/cache"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+)
+
+// Source provides the raw plugin configuration
+type Source interface {
+	// Start loads and returns the current configuration. Afterwards onChange is called
+	// with the new configuration every time it changes, until ctx is done.
+	Start(ctx context.Context, onChange func(rawConfig string)) (string, error)
+}
+
+// NewStaticSource returns a source for a configuration that never changes,
+// e.g. a configuration that was passed via an environment variable
+func NewStaticSource(rawConfig string) Source {
+	return &staticSource{rawConfig: rawConfig}
+}
+
+type staticSource struct {
+	rawConfig string
+}
+
+func (s *staticSource) Start(ctx context.Context, onChange func(rawConfig string)) (string, error) {
+	return s.rawConfig, nil
+}
+
+// NewFileSource returns a source that reads the configuration from the given file
+// and watches it for changes. This also works for files of mounted ConfigMaps,
+// which are updated by replacing a symlink in the parent directory.
+func NewFileSource(path string) Source {
+	return &fileSource{
+		log:  log.New("config-file"),
+		path: path,
+	}
+}
+
+type fileSource struct {
+	log  log.Logger
+	path string
+
+	m    sync.Mutex
+	last string
+}
+
+func (s *fileSource) Start(ctx context.Context, onChange func(rawConfig string)) (string, error) {
+	rawConfig, err := os.ReadFile(s.path)
+	if err != nil {
+		return "", fmt.Errorf("read config file %s: %v", s.path, err)
+	}
+	s.last = string(rawConfig)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return "", fmt.Errorf("create file watcher: %v", err)
+	}
+
+	// we watch the directory instead of the file, as the file might be replaced
+	err = watcher.Add(filepath.Dir(s.path))
+	if err != nil {
+		_ = watcher.Close()
+		return "", fmt.Errorf("watch config file %s: %v", s.path, err)
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+
+				s.log.Errorf("Error watching config file %s: %v", s.path, err)
+			case _, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				s.reload(onChange)
+			}
+		}
+	}()
+
+	return s.last, nil
+}
+
+func (s *fileSource) reload(onChange func(rawConfig string)) {
+	rawConfig, err := os.ReadFile(s.path)
+	if err != nil {
+		// the file might be in the middle of being replaced, we will get another event
+		s.log.Debugf("Error reading config file %s: %v", s.path, err)
+		return
+	}
+
+	s.m.Lock()
+	changed := s.last != string(rawConfig)
+	s.last = string(rawConfig)
+	s.m.Unlock()
+	if changed {
+		onChange(string(rawConfig))
+	}
+}
+
+// NewConfigMapSource returns a source that reads the configuration from the given key
+// of a ConfigMap in the host cluster and watches the ConfigMap for changes
+func NewConfigMapSource(manager ctrl.Manager, configMap types.NamespacedName, key string) Source {
+	return &configMapSource{
+		log:       log.New("config-configmap"),
+		manager:   manager,
+		configMap: configMap,
+		key:       key,
+	}
+}
+
+type configMapSource struct {
+	log       log.Logger
+	manager   ctrl.Manager
+	configMap types.NamespacedName
+	key       string
+
+	m    sync.Mutex
+	last string
+}
+
+func (s *configMapSource) Start(ctx context.Context, onChange func(rawConfig string)) (string, error) {
+	// we use a separate cache that only contains the config map, as the
+	// config map might not be in the target namespace
+	configMapCache, err := cache.New(s.manager.GetConfig(), cache.Options{
+		Scheme:    s.manager.GetScheme(),
+		Mapper:    s.manager.GetRESTMapper(),
+		Namespace: s.configMap.Namespace,
+		SelectorsByObject: cache.SelectorsByObject{
+			&corev1.ConfigMap{}: {
+				Field: fields.OneTermEqualSelector("metadata.name", s.configMap.Name),
+			},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("create config map cache: %v", err)
+	}
+
+	informer, err := configMapCache.GetInformer(ctx, &corev1.ConfigMap{})
+	if err != nil {
+		return "", fmt.Errorf("get config map informer: %v", err)
+	}
+
+	go func() {
+		err := configMapCache.Start(ctx)
+		if err != nil {
+			s.log.Errorf("Error watching config map %s: %v", s.configMap.String(), err)
+		}
+	}()
+	if !configMapCache.WaitForCacheSync(ctx) {
+		return "", fmt.Errorf("wait for config map cache to sync")
+	}
+
+	configMap := &corev1.ConfigMap{}
+	err = configMapCache.Get(ctx, s.configMap, configMap)
+	if err != nil {
+		return "", fmt.Errorf("get config map %s: %v", s.configMap.String(), err)
+	} else if _, ok := configMap.Data[s.key]; !ok {
+		return "", fmt.Errorf("config map %s has no key %s", s.configMap.String(), s.key)
+	}
+	s.last = configMap.Data[s.key]
+
+	informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			s.reload(obj, onChange)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			s.reload(newObj, onChange)
+		},
+		DeleteFunc: func(obj interface{}) {
+			s.log.Errorf("Config map %s was deleted, keeping the last configuration", s.configMap.String())
+		},
+	})
+
+	return s.last, nil
+}
+
+func (s *configMapSource) reload(obj interface{}, onChange func(rawConfig string)) {
+	configMap, ok := obj.(*corev1.ConfigMap)
+	if !ok {
+		return
+	}
+
+	rawConfig, ok := configMap.Data[s.key]
+	if !ok {
+		s.log.Errorf("Config map %s has no key %s, keeping the last configuration", s.configMap.String(), s.key)
+		return
+	}
+
+	s.m.Lock()
+	changed := s.last != rawConfig
+	s.last = rawConfig
+	s.m.Unlock()
+	if changed {
+		onChange(rawConfig)
+	}
+}
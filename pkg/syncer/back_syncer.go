@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/plugin"
 	"github.com/loft-sh/vcluster-sdk/log"
@@ -16,11 +18,15 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/event"
 
 	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/config"
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/metrics"
 	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/namecache"
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/patches"
+	patchesregex "github.com/loft-sh/vcluster-generic-crd-plugin/pkg/patches/regex"
 	"github.com/loft-sh/vcluster-sdk/syncer"
 	synccontext "github.com/loft-sh/vcluster-sdk/syncer/context"
 	"github.com/loft-sh/vcluster-sdk/syncer/translator"
 	"github.com/loft-sh/vcluster-sdk/translate"
+	"k8s.io/apimachinery/pkg/api/equality"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
@@ -36,11 +42,12 @@ import (
 
 const (
 	IndexByVirtualName = "indexbyvirtualname"
+	IndexByOwner       = "indexbyowner"
 
 	MappingsAnnotation = "vcluster.loft.sh/mappings"
 )
 
-func CreateBackSyncer(ctx *synccontext.RegisterContext, config *config.SyncBack, parentConfig *config.FromVirtualCluster, parentNC namecache.NameCache) (syncer.Base, error) {
+func CreateBackSyncer(ctx *synccontext.RegisterContext, config *config.SyncBack, parentConfig *config.FromVirtualCluster, parentNC namecache.NameCache, dryRun bool) (syncer.Base, error) {
 	if len(config.Selectors) == 0 {
 		return nil, fmt.Errorf("the syncBack config for %s (%s) is missing Selectors", config.Kind, parentConfig.Kind)
 	}
@@ -49,15 +56,24 @@ func CreateBackSyncer(ctx *synccontext.RegisterContext, config *config.SyncBack,
 	obj.SetKind(config.Kind)
 	obj.SetAPIVersion(config.APIVersion)
 
-	// TODO: [low priority] check if config.Kind + config.APIVersion has status subresource
-	statusIsSubresource := true
-	return &backSyncController{
+	err := preparePatchesRegex(append(config.Patches, config.ReversePatches...))
+	if err != nil {
+		return nil, err
+	}
+
+	statusIsSubresource, err := hasStatusSubresource(ctx, schema.FromAPIVersionAndKind(config.APIVersion, config.Kind), config.StatusSubresource)
+	if err != nil {
+		return nil, fmt.Errorf("check status subresource of %s(%s): %v", config.Kind, config.APIVersion, err)
+	}
+
+	b := &backSyncController{
 		log: log.New(config.Kind + "-back-syncer"),
 		patcher: &patcher{
 			fromClient:          ctx.PhysicalManager.GetClient(),
 			toClient:            ctx.VirtualManager.GetClient(),
 			statusIsSubresource: statusIsSubresource,
 			log:                 log.New(config.Kind + "-back-syncer"),
+			dryRun:              dryRun,
 		},
 
 		parentGVK:       schema.FromAPIVersionAndKind(parentConfig.APIVersion, parentConfig.Kind),
@@ -72,7 +88,10 @@ func CreateBackSyncer(ctx *synccontext.RegisterContext, config *config.SyncBack,
 		currentNamespaceClient: ctx.CurrentNamespaceClient,
 
 		virtualClient: ctx.VirtualManager.GetClient(),
-	}, nil
+	}
+	b.metrics = metrics.NewController(metrics.ControllerBackSync, config.GVK(), b.getControllerID())
+	b.patcher.metrics = b.metrics
+	return b, nil
 }
 
 type backSyncController struct {
@@ -94,6 +113,8 @@ type backSyncController struct {
 	currentNamespaceClient client.Client
 
 	virtualClient client.Client
+
+	metrics *metrics.Controller
 }
 
 var _ syncer.ControllerStarter = &backSyncController{}
@@ -114,13 +135,29 @@ func (b *backSyncController) RegisterIndices(ctx *synccontext.RegisterContext) e
 		return []string{}
 	})
 
-	if err != nil && strings.Contains(err.Error(), "indexer conflict") {
-		// Field IndexByVirtualName already exists in the index,
-		// added by previous backsyncer, hence skip adding again
-		return nil
+	// an indexer conflict means the field IndexByVirtualName already exists
+	// in the index, added by previous backsyncer, hence skip adding again
+	if err != nil && !strings.Contains(err.Error(), "indexer conflict") {
+		return err
 	}
 
-	return err
+	err = ctx.PhysicalManager.GetCache().IndexField(ctx.Context, b.resource(), IndexByOwner, func(object client.Object) []string {
+		owners := []string{}
+		for _, ownerReference := range object.GetOwnerReferences() {
+			gv, err := schema.ParseGroupVersion(ownerReference.APIVersion)
+			if err != nil {
+				continue
+			}
+
+			owners = append(owners, ownerIndexKey(gv.Group, ownerReference.Kind, ownerReference.Name))
+		}
+		return owners
+	})
+	if err != nil && !strings.Contains(err.Error(), "indexer conflict") {
+		return err
+	}
+
+	return nil
 }
 
 func (b *backSyncController) resource() client.Object {
@@ -152,7 +189,7 @@ func (b *backSyncController) Register(ctx *synccontext.RegisterContext) error {
 			DeleteFunc: func(event event.DeleteEvent, limitingInterface workqueue.RateLimitingInterface) {
 				// delete virtual resource. Would be nicer to have this part of the controller, but
 				// it works for now.
-				virtualName := b.PhysicalToVirtual(types.NamespacedName{
+				virtualName := b.PhysicalToVirtual(ctx.Context, types.NamespacedName{
 					Namespace: event.Object.GetNamespace(),
 					Name:      event.Object.GetName(),
 				}, event.Object)
@@ -170,10 +207,29 @@ func (b *backSyncController) Register(ctx *synccontext.RegisterContext) error {
 		}).
 		Watches(b, nil).
 		For(b.resource())
+
+	// objects that are selected through the labels of the parent host object need to be
+	// enqueued again if these labels change
+	if b.hasFromParentLabels() {
+		parent := &unstructured.Unstructured{}
+		parent.SetGroupVersionKind(b.parentGVK)
+		controller = controller.Watches(&source.Kind{Type: parent}, &handler.Funcs{
+			UpdateFunc: func(event event.UpdateEvent, limitingInterface workqueue.RateLimitingInterface) {
+				b.enqueueParentLabelChanges(event.ObjectOld, event.ObjectNew, limitingInterface)
+			},
+		})
+	}
 	return controller.Complete(b)
 }
 
 func (b *backSyncController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	start := time.Now()
+	result, err := b.reconcile(ctx, req)
+	b.metrics.ObserveReconcile(start, result, err)
+	return result, err
+}
+
+func (b *backSyncController) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := log.NewFromExisting(b.log.Base(), req.Name)
 	syncContext := &synccontext.SyncContext{
 		Context:                ctx,
@@ -197,7 +253,7 @@ func (b *backSyncController) Reconcile(ctx context.Context, req ctrl.Request) (c
 	}
 
 	// get virtual resource
-	vNN := b.PhysicalToVirtual(req.NamespacedName, pObj)
+	vNN := b.PhysicalToVirtual(ctx, req.NamespacedName, pObj)
 	if vNN.Name == "" {
 		// we skip early here, we cannot resolve the physical to virtual,
 		// which means it either doesn't matches or shouldn't get synced anymore
@@ -217,7 +273,11 @@ func (b *backSyncController) Reconcile(ctx context.Context, req ctrl.Request) (c
 	if vObj != nil && pObj == nil {
 		return b.syncDown(syncContext, vObj)
 	} else if vObj != nil && pObj != nil {
-		return b.sync(syncContext, pObj, vObj)
+		result, err := b.sync(syncContext, pObj, vObj)
+		if !b.isExcluded(vObj) {
+			b.patcher.updateSyncStatus(syncContext, vObj, req.NamespacedName, err)
+		}
+		return result, err
 	} else if vObj == nil && pObj != nil {
 		return b.syncUp(syncContext, pObj)
 	}
@@ -225,6 +285,15 @@ func (b *backSyncController) Reconcile(ctx context.Context, req ctrl.Request) (c
 	return ctrl.Result{}, nil
 }
 
+func (b *backSyncController) templateContext(virtualNamespace, hostNamespace string) *patches.TemplateContext {
+	return &patches.TemplateContext{
+		VirtualNamespace: virtualNamespace,
+		HostNamespace:    hostNamespace,
+		PluginName:       plugin.GetPluginName(),
+		ControllerID:     b.getControllerID(),
+	}
+}
+
 func (b *backSyncController) getControllerID() string {
 	if b.config.ID != "" {
 		return b.config.ID
@@ -247,7 +316,7 @@ func (b *backSyncController) deleteVirtualObject(ctx context.Context, vObj clien
 	}
 
 	log.Infof("delete virtual %s/%s, because physical is missing, but virtual object exists", vObj.GetNamespace(), vObj.GetName())
-	err := b.virtualClient.Delete(ctx, vObj)
+	err := b.patcher.Delete(ctx, b.virtualClient, vObj)
 	if err != nil && !kerrors.IsNotFound(err) {
 		return err
 	}
@@ -260,12 +329,13 @@ func (b *backSyncController) sync(ctx *synccontext.SyncContext, pObj client.Obje
 	}
 
 	// execute reverse patches
-	result, err := b.patcher.ApplyReversePatches(ctx.Context, pObj, vObj, b.config.ReversePatches, &virtualToHostNameResolver{namespace: vObj.GetNamespace()})
+	result, err := b.patcher.ApplyReversePatches(ctx.Context, pObj, vObj, b.config.ReversePatches, &virtualToHostNameResolver{namespace: vObj.GetNamespace()}, b.templateContext(vObj.GetNamespace(), pObj.GetNamespace()))
 	if err != nil {
 		if kerrors.IsInvalid(err) {
 			ctx.Log.Infof("Warning: this message could indicate a timing issue with no significant impact, or a bug. Please report this if your resource never reaches the expected state. Error message: failed to patch virtual %s %s/%s: %v", b.config.Kind, vObj.GetNamespace(), vObj.GetName(), err)
 			// this happens when some field is being removed shortly after being added, which suggest it's a timing issue
 			// it doesn't seem to have any negative consequence besides the logged error message
+			b.metrics.InvalidRequeue()
 			return ctrl.Result{Requeue: true}, nil
 		}
 
@@ -282,15 +352,16 @@ func (b *backSyncController) sync(ctx *synccontext.SyncContext, pObj client.Obje
 	if pAnnotations != nil && pAnnotations[MappingsAnnotation] != "" {
 		_ = json.Unmarshal([]byte(pAnnotations[MappingsAnnotation]), &mappings)
 	}
-	nameResolver := &memorizingHostToVirtualNameResolver{nameCache: b.parentNameCache, gvk: b.parentGVK, mappings: mappings}
+	nameResolver := &memorizingHostToVirtualNameResolver{HostToVirtualTranslator: NewHostToVirtualTranslator(vObj.GetNamespace(), b.targetNamespace, vObj), nameCache: b.parentNameCache, gvk: b.parentGVK, mappings: mappings}
 	_, err = b.patcher.ApplyPatches(ctx.Context, pObj, vObj, b.config.Patches, b.config.ReversePatches, func(obj client.Object) (client.Object, error) {
-		return b.translateMetadata(obj)
-	}, nameResolver)
+		return b.translateMetadata(ctx.Context, obj)
+	}, nameResolver, b.templateContext(vObj.GetNamespace(), pObj.GetNamespace()))
 	if err != nil {
 		if kerrors.IsInvalid(err) {
 			ctx.Log.Infof("Warning: this message could indicate a timing issue with no significant impact, or a bug. Please report this if your resource never reaches the expected state. Error message: failed to patch physical %s %s/%s: %v", b.config.Kind, vObj.GetNamespace(), vObj.GetName(), err)
 			// this happens when some field is being removed shortly after being added, which suggest it's a timing issue
 			// it doesn't seem to have any negative consequence besides the logged error message
+			b.metrics.InvalidRequeue()
 			return ctrl.Result{Requeue: true}, nil
 		}
 
@@ -314,7 +385,7 @@ func (b *backSyncController) syncUp(ctx *synccontext.SyncContext, pObj client.Ob
 	// inside the virtual cluster. So we will also delete it inside the host cluster as well.
 	if b.containsBackSyncNameAnnotations(pObj) {
 		ctx.Log.Infof("Delete physical %s %s/%s, since it was deleted in virtual cluster or is missing there", b.config.Kind, pObj.GetNamespace(), pObj.GetName())
-		err := ctx.PhysicalClient.Delete(ctx.Context, pObj)
+		err := b.patcher.Delete(ctx.Context, ctx.PhysicalClient, pObj)
 		if err != nil {
 			return ctrl.Result{}, fmt.Errorf("error deleting object: %v", err)
 		}
@@ -323,7 +394,7 @@ func (b *backSyncController) syncUp(ctx *synccontext.SyncContext, pObj client.Ob
 	}
 
 	// add annotation with virtual name and namespace on the physical object
-	vNN := b.PhysicalToVirtual(types.NamespacedName{
+	vNN := b.PhysicalToVirtual(ctx.Context, types.NamespacedName{
 		Namespace: pObj.GetNamespace(),
 		Name:      pObj.GetName(),
 	}, pObj)
@@ -337,8 +408,10 @@ func (b *backSyncController) syncUp(ctx *synccontext.SyncContext, pObj client.Ob
 
 	// apply object to virtual cluster
 	ctx.Log.Infof("Create virtual %s %s/%s, since it is missing, but physical object exists", b.config.Kind, vNN.Namespace, vNN.Name)
-	nameResolver := &memorizingHostToVirtualNameResolver{nameCache: b.parentNameCache, gvk: b.parentGVK}
-	_, err = b.patcher.ApplyPatches(ctx.Context, pObj, nil, b.config.Patches, b.config.ReversePatches, b.translateMetadata, nameResolver)
+	nameResolver := &memorizingHostToVirtualNameResolver{HostToVirtualTranslator: NewHostToVirtualTranslator(vNN.Namespace, b.targetNamespace), nameCache: b.parentNameCache, gvk: b.parentGVK}
+	_, err = b.patcher.ApplyPatches(ctx.Context, pObj, nil, b.config.Patches, b.config.ReversePatches, func(obj client.Object) (client.Object, error) {
+		return b.translateMetadata(ctx.Context, obj)
+	}, nameResolver, b.templateContext(vNN.Namespace, pObj.GetNamespace()))
 	if err != nil {
 		_ = b.removeAnnotationsFromPhysicalObject(ctx, pObj)
 		return ctrl.Result{}, fmt.Errorf("error applying patches: %v", err)
@@ -354,8 +427,8 @@ func (b *backSyncController) syncUp(ctx *synccontext.SyncContext, pObj client.Ob
 }
 
 // translateMetadata converts the physical object into a virtual object
-func (b *backSyncController) translateMetadata(pObj client.Object) (client.Object, error) {
-	vNN := b.PhysicalToVirtual(types.NamespacedName{
+func (b *backSyncController) translateMetadata(ctx context.Context, pObj client.Object) (client.Object, error) {
+	vNN := b.PhysicalToVirtual(ctx, types.NamespacedName{
 		Namespace: pObj.GetNamespace(),
 		Name:      pObj.GetName(),
 	}, pObj)
@@ -368,6 +441,13 @@ func (b *backSyncController) translateMetadata(pObj client.Object) (client.Objec
 	newObj.SetNamespace(vNN.Namespace)
 	newObj.SetName(vNN.Name)
 
+	// recreate the owner reference to the virtual parent
+	ownerReferences, err := b.translateOwnerReferences(ctx, pObj, vNN.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	newObj.SetOwnerReferences(ownerReferences)
+
 	// set annotations
 	annotations := newObj.GetAnnotations()
 	delete(annotations, translate.MarkerLabel)
@@ -387,7 +467,7 @@ func (b *backSyncController) translateMetadata(pObj client.Object) (client.Objec
 	return newObj, nil
 }
 
-func (b *backSyncController) PhysicalToVirtual(req types.NamespacedName, pObj client.Object) types.NamespacedName {
+func (b *backSyncController) PhysicalToVirtual(ctx context.Context, req types.NamespacedName, pObj client.Object) types.NamespacedName {
 	if pObj != nil && b.containsBackSyncNameAnnotations(pObj) {
 		pAnnotations := pObj.GetAnnotations()
 		return types.NamespacedName{
@@ -407,9 +487,27 @@ func (b *backSyncController) PhysicalToVirtual(req types.NamespacedName, pObj cl
 			if nn.Name == "" {
 				continue
 			}
+		} else if s.LabelSelector != nil {
+			if pObj == nil {
+				continue
+			}
+
+			nn = b.resolveLabelSelector(s.LabelSelector, pObj)
+			if nn.Name == "" {
+				continue
+			}
+		} else if s.OwnerReference != nil {
+			if pObj == nil {
+				continue
+			}
+
+			_, vParent := b.resolveOwnerReference(ctx, s.OwnerReference, pObj)
+			if vParent.Name == "" {
+				continue
+			}
+			nn = types.NamespacedName{Namespace: vParent.Namespace, Name: pObj.GetName()}
 		}
 
-		// TODO: implement other selector types here
 		// if part of a selector does not match then we call `continue` to try different selector
 		if nn.Name != "" {
 			// if this selector matches then we don't evaluate other and return
@@ -444,13 +542,295 @@ func (b *backSyncController) Start(ctx context.Context, h handler.EventHandler,
 					}
 				}
 			})
+		} else if s.LabelSelector != nil {
+			labelSelector := s.LabelSelector
+			b.parentNameCache.AddChangeHook(b.parentGVK, namecache.IndexPhysicalToVirtualNamePath, func(name, key, value string) {
+				// key is format PHYSICAL_NAME/PATH, only changes of the parent name itself are relevant
+				if name != "" && strings.HasSuffix(key, "/"+namecache.MetadataFieldPath) {
+					b.enqueueLabelSelectorMatches(labelSelector, strings.TrimSuffix(key, "/"+namecache.MetadataFieldPath), q)
+				}
+			})
+		} else if s.OwnerReference != nil {
+			b.parentNameCache.AddChangeHook(b.parentGVK, namecache.IndexPhysicalToVirtualNamePath, func(name, key, value string) {
+				// key is format PHYSICAL_NAME/PATH, only changes of the parent name itself are relevant
+				if name != "" && strings.HasSuffix(key, "/"+namecache.MetadataFieldPath) {
+					b.enqueueOwnedObjects(strings.TrimSuffix(key, "/"+namecache.MetadataFieldPath), q)
+				}
+			})
 		}
-
-		// TODO: implement other selector types here
 	}
 	return nil
 }
 
+// resolveOwnerReference returns the owner reference of the physical object that points to a parent host object
+// together with the virtual name of that parent
+func (b *backSyncController) resolveOwnerReference(ctx context.Context, selector *config.OwnerReferenceSyncBackSelector, pObj client.Object) (*metav1.OwnerReference, types.NamespacedName) {
+	for _, ownerReference := range pObj.GetOwnerReferences() {
+		if ownerReference.Kind != b.parentGVK.Kind {
+			continue
+		}
+		gv, err := schema.ParseGroupVersion(ownerReference.APIVersion)
+		if err != nil || gv.Group != b.parentGVK.Group {
+			continue
+		}
+		if selector.Controller && (ownerReference.Controller == nil || !*ownerReference.Controller) {
+			continue
+		}
+
+		vParent := b.parentNameCache.ResolveName(b.parentGVK, ownerReference.Name)
+		if vParent.Name == "" {
+			continue
+		}
+
+		// the owner reference might point to a former parent with the same name
+		pParent := &unstructured.Unstructured{}
+		pParent.SetGroupVersionKind(b.parentGVK)
+		err = b.physicalClient.Get(ctx, types.NamespacedName{Namespace: pObj.GetNamespace(), Name: ownerReference.Name}, pParent)
+		if err != nil {
+			if !kerrors.IsNotFound(err) {
+				b.log.Errorf("error retrieving parent %s %s/%s: %v", b.parentGVK.Kind, pObj.GetNamespace(), ownerReference.Name, err)
+			}
+			continue
+		} else if pParent.GetUID() != ownerReference.UID {
+			continue
+		}
+
+		return ownerReference.DeepCopy(), vParent
+	}
+
+	return nil, types.NamespacedName{}
+}
+
+// translateOwnerReferences returns the owner references for the virtual object, which point to the
+// virtual parent if the physical object is owned by the parent host object
+func (b *backSyncController) translateOwnerReferences(ctx context.Context, pObj client.Object, virtualNamespace string) ([]metav1.OwnerReference, error) {
+	for _, s := range b.config.Selectors {
+		if s.OwnerReference == nil {
+			continue
+		}
+
+		ownerReference, vParentName := b.resolveOwnerReference(ctx, s.OwnerReference, pObj)
+		if ownerReference == nil || vParentName.Namespace != virtualNamespace {
+			continue
+		}
+
+		vParent := &unstructured.Unstructured{}
+		vParent.SetGroupVersionKind(b.parentGVK)
+		err := b.virtualClient.Get(ctx, vParentName, vParent)
+		if err != nil {
+			return nil, fmt.Errorf("retrieve virtual owner %s %s: %v", b.parentGVK.Kind, vParentName.String(), err)
+		}
+
+		return []metav1.OwnerReference{{
+			APIVersion:         vParent.GetAPIVersion(),
+			Kind:               vParent.GetKind(),
+			Name:               vParent.GetName(),
+			UID:                vParent.GetUID(),
+			Controller:         ownerReference.Controller,
+			BlockOwnerDeletion: ownerReference.BlockOwnerDeletion,
+		}}, nil
+	}
+
+	return nil, nil
+}
+
+// enqueueOwnedObjects enqueues all physical objects that are owned by the parent host object with the given name
+func (b *backSyncController) enqueueOwnedObjects(parentName string, q workqueue.RateLimitingInterface) {
+	list := &unstructured.UnstructuredList{}
+	list.SetKind(b.config.Kind + "List")
+	list.SetAPIVersion(b.config.APIVersion)
+	err := b.physicalClient.List(context.Background(), list, client.InNamespace(b.targetNamespace), client.MatchingFields{IndexByOwner: ownerIndexKey(b.parentGVK.Group, b.parentGVK.Kind, parentName)})
+	if err != nil {
+		b.log.Errorf("error listing %s owned by %s %s: %v", b.config.Kind, b.parentGVK.Kind, parentName, err)
+		return
+	}
+
+	for _, item := range list.Items {
+		q.Add(reconcile.Request{NamespacedName: types.NamespacedName{
+			Namespace: item.GetNamespace(),
+			Name:      item.GetName(),
+		}})
+	}
+}
+
+// ownerIndexKey returns the key of an owner in the IndexByOwner index
+func ownerIndexKey(group, kind, name string) string {
+	return group + "/" + kind + "/" + name
+}
+
+// resolveLabelSelector finds the parent host object of the given physical object through its labels and
+// returns the name of the physical object within the virtual namespace of the parent
+func (b *backSyncController) resolveLabelSelector(selector *config.LabelSyncBackSelector, pObj client.Object) types.NamespacedName {
+	labels := pObj.GetLabels()
+	for key, value := range selector.MatchLabels {
+		if labels[key] != value {
+			return types.NamespacedName{}
+		}
+	}
+
+	parentLabels := map[string]string{}
+	for key, parentKey := range selector.FromParentLabels {
+		value, ok := labels[key]
+		if !ok {
+			return types.NamespacedName{}
+		}
+
+		parentLabels[parentKey] = value
+	}
+
+	parentName := ""
+	if selector.ParentNameLabel != "" {
+		parentName = labels[selector.ParentNameLabel]
+		if parentName == "" {
+			return types.NamespacedName{}
+		}
+	}
+
+	parents, err := b.listParents(pObj.GetNamespace(), parentName, parentLabels)
+	if err != nil {
+		b.log.Errorf("error listing parent %s for %s/%s: %v", b.parentGVK.Kind, pObj.GetNamespace(), pObj.GetName(), err)
+		return types.NamespacedName{}
+	}
+
+	for _, parent := range parents {
+		vParent := b.parentNameCache.ResolveName(b.parentGVK, parent.GetName())
+		if vParent.Name == "" {
+			continue
+		}
+
+		return types.NamespacedName{
+			Namespace: vParent.Namespace,
+			Name:      pObj.GetName(),
+		}
+	}
+
+	return types.NamespacedName{}
+}
+
+// listParents returns the parent host objects sorted by name that have the given name and labels
+func (b *backSyncController) listParents(namespace, name string, labels map[string]string) ([]client.Object, error) {
+	if name != "" {
+		parent := &unstructured.Unstructured{}
+		parent.SetGroupVersionKind(b.parentGVK)
+		err := b.physicalClient.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: name}, parent)
+		if err != nil {
+			if kerrors.IsNotFound(err) {
+				return nil, nil
+			}
+
+			return nil, err
+		}
+
+		for key, value := range labels {
+			if parent.GetLabels()[key] != value {
+				return nil, nil
+			}
+		}
+		return []client.Object{parent}, nil
+	}
+
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(b.parentGVK.GroupVersion().WithKind(b.parentGVK.Kind + "List"))
+	err := b.physicalClient.List(context.Background(), list, client.InNamespace(namespace), client.MatchingLabels(labels))
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(list.Items, func(i, j int) bool {
+		return list.Items[i].GetName() < list.Items[j].GetName()
+	})
+	parents := []client.Object{}
+	for i := range list.Items {
+		parents = append(parents, &list.Items[i])
+	}
+	return parents, nil
+}
+
+// enqueueLabelSelectorMatches enqueues all physical objects that are selected by the label selector
+// for the parent host object with the given name
+func (b *backSyncController) enqueueLabelSelectorMatches(selector *config.LabelSyncBackSelector, parentName string, q workqueue.RateLimitingInterface) {
+	var parentLabels map[string]string
+	if len(selector.FromParentLabels) > 0 {
+		parent := &unstructured.Unstructured{}
+		parent.SetGroupVersionKind(b.parentGVK)
+		err := b.physicalClient.Get(context.Background(), types.NamespacedName{Namespace: b.targetNamespace, Name: parentName}, parent)
+		if err != nil {
+			if !kerrors.IsNotFound(err) {
+				b.log.Errorf("error retrieving parent %s %s/%s: %v", b.parentGVK.Kind, b.targetNamespace, parentName, err)
+			}
+			return
+		}
+
+		parentLabels = parent.GetLabels()
+	}
+
+	b.enqueueLabelSelectorMatchesWithLabels(selector, parentName, parentLabels, q)
+}
+
+// enqueueLabelSelectorMatchesWithLabels enqueues all physical objects that are selected by the label
+// selector for the parent host object with the given name and labels
+func (b *backSyncController) enqueueLabelSelectorMatchesWithLabels(selector *config.LabelSyncBackSelector, parentName string, parentLabels map[string]string, q workqueue.RateLimitingInterface) {
+	matchLabels := map[string]string{}
+	for key, value := range selector.MatchLabels {
+		matchLabels[key] = value
+	}
+	if selector.ParentNameLabel != "" {
+		matchLabels[selector.ParentNameLabel] = parentName
+	}
+	for key, parentKey := range selector.FromParentLabels {
+		value, ok := parentLabels[parentKey]
+		if !ok {
+			return
+		}
+
+		matchLabels[key] = value
+	}
+
+	list := &unstructured.UnstructuredList{}
+	list.SetKind(b.config.Kind + "List")
+	list.SetAPIVersion(b.config.APIVersion)
+	err := b.physicalClient.List(context.Background(), list, client.InNamespace(b.targetNamespace), client.MatchingLabels(matchLabels))
+	if err != nil {
+		b.log.Errorf("error listing %s for parent %s %s: %v", b.config.Kind, b.parentGVK.Kind, parentName, err)
+		return
+	}
+
+	for _, item := range list.Items {
+		q.Add(reconcile.Request{NamespacedName: types.NamespacedName{
+			Namespace: item.GetNamespace(),
+			Name:      item.GetName(),
+		}})
+	}
+}
+
+// hasFromParentLabels returns true if objects are selected through the labels of the parent host object
+func (b *backSyncController) hasFromParentLabels() bool {
+	for _, s := range b.config.Selectors {
+		if s.LabelSelector != nil && len(s.LabelSelector.FromParentLabels) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// enqueueParentLabelChanges enqueues the physical objects that were selected through the old labels
+// of the parent host object as well as the ones that are selected through the new labels
+func (b *backSyncController) enqueueParentLabelChanges(oldParent, newParent client.Object, q workqueue.RateLimitingInterface) {
+	if oldParent == nil || newParent == nil || newParent.GetNamespace() != b.targetNamespace || equality.Semantic.DeepEqual(oldParent.GetLabels(), newParent.GetLabels()) {
+		return
+	}
+
+	for _, s := range b.config.Selectors {
+		if s.LabelSelector == nil || len(s.LabelSelector.FromParentLabels) == 0 {
+			continue
+		}
+
+		b.enqueueLabelSelectorMatchesWithLabels(s.LabelSelector, oldParent.GetName(), oldParent.GetLabels(), q)
+		b.enqueueLabelSelectorMatchesWithLabels(s.LabelSelector, newParent.GetName(), newParent.GetLabels(), q)
+	}
+}
+
 func (b *backSyncController) containsBackSyncNameAnnotations(obj client.Object) bool {
 	annotations := obj.GetAnnotations()
 	return annotations != nil && annotations[translate.MarkerLabel] == b.options.Name && annotations[translator.NameAnnotation] != "" && annotations[translator.NamespaceAnnotation] != ""
@@ -474,7 +854,7 @@ func (b *backSyncController) removeAnnotationsFromPhysicalObject(ctx *synccontex
 	}
 
 	ctx.Log.Infof("Delete marker annotations on object")
-	return ctx.PhysicalClient.Patch(ctx.Context, pObj, patch)
+	return b.patcher.Patch(ctx.Context, ctx.PhysicalClient, originalObject, pObj, patch)
 }
 
 func (b *backSyncController) addAnnotationsToPhysicalObject(ctx *synccontext.SyncContext, pObj client.Object, vObj types.NamespacedName, mappings map[string]string) error {
@@ -503,7 +883,7 @@ func (b *backSyncController) addAnnotationsToPhysicalObject(ctx *synccontext.Syn
 	}
 
 	ctx.Log.Infof("Patch marker annotations on object")
-	return ctx.PhysicalClient.Patch(ctx.Context, pObj, patch)
+	return b.patcher.Patch(ctx.Context, ctx.PhysicalClient, originalObject, pObj, patch)
 }
 
 func (b *backSyncController) enqueueVirtual(obj client.Object, q workqueue.RateLimitingInterface, isDelete bool) {
@@ -529,7 +909,7 @@ func (b *backSyncController) enqueueVirtual(obj client.Object, q workqueue.RateL
 	} else if len(objs) == 0 {
 		if !isDelete {
 			b.log.Infof("delete virtual %s/%s, because physical is missing, but virtual object exists", obj.GetNamespace(), obj.GetName())
-			err := b.virtualClient.Delete(context.Background(), obj)
+			err := b.patcher.Delete(context.Background(), b.virtualClient, obj)
 			if err != nil && !kerrors.IsNotFound(err) {
 				b.log.Errorf("error deleting virtual %s/%s: %v", obj.GetNamespace(), obj.GetName(), err)
 				return
@@ -546,18 +926,56 @@ func (b *backSyncController) enqueueVirtual(obj client.Object, q workqueue.RateL
 }
 
 type memorizingHostToVirtualNameResolver struct {
+	HostToVirtualTranslator
+
 	gvk       schema.GroupVersionKind
 	nameCache namecache.NameCache
 
+	// byName resolves the names of the objects themselves instead of the references at
+	// the patch path, see hostToVirtualNameResolver
+	byName bool
+
 	mappings map[string]string
 }
 
-func (r *memorizingHostToVirtualNameResolver) TranslateName(name string, _ *regexp.Regexp, path string) (string, error) {
-	// TODO: add regex support
+func (r *memorizingHostToVirtualNameResolver) TranslateName(name string, regex *regexp.Regexp, path string) (string, error) {
 	if r.mappings == nil {
 		r.mappings = map[string]string{}
 	}
 
+	if regex != nil {
+		var translateErr error
+		translated := patchesregex.ProcessRegex(regex, name, func(name, _ string) types.NamespacedName {
+			n, err := r.resolve(name, path, true)
+			if err != nil && translateErr == nil {
+				translateErr = err
+			}
+
+			return n
+		})
+		if translateErr != nil {
+			return "", translateErr
+		}
+
+		return translated, nil
+	}
+
+	n, err := r.resolve(name, path, false)
+	if err != nil {
+		return "", err
+	}
+
+	return n.Name, nil
+}
+
+// resolve translates the host name via the name cache and memorizes the result in the
+// mappings, so that the name can still be translated after the parent is gone. For regex
+// replacements the namespace is memorized as well, as it might be part of the replaced value.
+func (r *memorizingHostToVirtualNameResolver) resolve(name, path string, withNamespace bool) (types.NamespacedName, error) {
+	if r.byName {
+		path = namecache.MetadataFieldPath
+	}
+
 	key := name + "/" + path
 	var n types.NamespacedName
 	if path == "" {
@@ -566,28 +984,65 @@ func (r *memorizingHostToVirtualNameResolver) TranslateName(name string, _ *rege
 		n = r.nameCache.ResolveNamePath(r.gvk, name, path)
 	}
 	if n.Name == "" {
-		if r.mappings[key] != "" {
-			return r.mappings[key], nil
+		memorized := r.mappings[key]
+		if memorized == "" {
+			return types.NamespacedName{}, fmt.Errorf("could not translate %s host resource name to vcluster resource name", name)
+		} else if strings.Contains(memorized, "/") {
+			return namecache.StringToNamespacedName(memorized), nil
 		}
 
-		return "", fmt.Errorf("could not translate %s host resource name to vcluster resource name", name)
+		return types.NamespacedName{Name: memorized}, nil
 	}
-	r.mappings[key] = n.Name
-	return n.Name, nil
-}
 
-func (r *memorizingHostToVirtualNameResolver) TranslateNameWithNamespace(name string, namespace string, _ *regexp.Regexp, path string) (string, error) {
-	return "", fmt.Errorf("translation not supported from host to virtual object")
-}
-func (r *memorizingHostToVirtualNameResolver) TranslateLabelKey(key string) (string, error) {
-	return "", fmt.Errorf("translation not supported from host to virtual object")
+	if withNamespace {
+		r.mappings[key] = n.String()
+	} else {
+		r.mappings[key] = n.Name
+	}
+	return n, nil
 }
-func (r *memorizingHostToVirtualNameResolver) TranslateLabelExpressionsSelector(selector *metav1.LabelSelector) (*metav1.LabelSelector, error) {
-	return nil, fmt.Errorf("translation not supported from host to virtual object")
+
+func (r *memorizingHostToVirtualNameResolver) TranslateNameWithNamespace(name string, namespace string, regex *regexp.Regexp, path string) (string, error) {
+	if regex != nil {
+		return r.TranslateName(name, regex, path)
+	}
+
+	if r.mappings == nil {
+		r.mappings = map[string]string{}
+	}
+	n, err := r.resolve(name, path, true)
+	if err != nil {
+		return "", err
+	}
+
+	err = r.ValidateResolvedNamespace(name, namespace, n.Namespace)
+	if err != nil {
+		return "", err
+	}
+
+	return n.Name, nil
 }
-func (r *memorizingHostToVirtualNameResolver) TranslateLabelSelector(selector map[string]string) (map[string]string, error) {
-	return nil, fmt.Errorf("translation not supported from host to virtual object")
+
+func (r *memorizingHostToVirtualNameResolver) TranslateNamespacedName(name string, namespace string, path string) (types.NamespacedName, error) {
+	err := r.ValidateHostNamespace(name, namespace)
+	if err != nil {
+		return types.NamespacedName{}, err
+	}
+
+	if r.mappings == nil {
+		r.mappings = map[string]string{}
+	}
+	return r.resolve(name, path, true)
 }
-func (r *memorizingHostToVirtualNameResolver) TranslateNamespaceRef(namespace string) (string, error) {
-	return "", fmt.Errorf("translation not supported from host to virtual object")
+
+// ForTarget returns a resolver for the given kind that memorizes into the same mappings
+func (r *memorizingHostToVirtualNameResolver) ForTarget(gvk schema.GroupVersionKind) (patches.NameResolver, error) {
+	if !r.nameCache.Watches(gvk) {
+		return NewPhysicalNameResolver(r.HostToVirtualTranslator), nil
+	}
+
+	if r.mappings == nil {
+		r.mappings = map[string]string{}
+	}
+	return &memorizingHostToVirtualNameResolver{HostToVirtualTranslator: r.HostToVirtualTranslator, gvk: gvk, nameCache: r.nameCache, byName: true, mappings: r.mappings}, nil
 }
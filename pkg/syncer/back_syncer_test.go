@@ -0,0 +1,347 @@
+package syncer
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/config"
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/namecache"
+	patchesregex "github.com/loft-sh/vcluster-generic-crd-plugin/pkg/patches/regex"
+	"github.com/loft-sh/vcluster-sdk/log"
+	"github.com/loft-sh/vcluster-sdk/translate"
+	"gotest.tools/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// the back syncer tests use core kinds, as the fake client only lists kinds of its scheme
+var (
+	parentGVK = schema.GroupVersionKind{Version: "v1", Kind: "Service"}
+	childGVK  = schema.GroupVersionKind{Version: "v1", Kind: "Secret"}
+)
+
+func newTestBackSyncController(selector *config.SyncBackSelector, nc namecache.NameCache, objs ...client.Object) *backSyncController {
+	return &backSyncController{
+		log:       log.New("test"),
+		parentGVK: parentGVK,
+		config: &config.SyncBack{
+			SyncBase: config.SyncBase{
+				TypeInformation: config.TypeInformation{APIVersion: childGVK.GroupVersion().String(), Kind: childGVK.Kind},
+			},
+			Selectors: []*config.SyncBackSelector{selector},
+		},
+		parentNameCache: nc,
+		targetNamespace: "vcluster",
+		physicalClient:  fake.NewClientBuilder().WithObjects(objs...).Build(),
+	}
+}
+
+// newParentNameCache returns a name cache that knows the parent host objects with the given virtual names
+func newParentNameCache(virtualNames ...types.NamespacedName) *fakeNameCache {
+	index := map[string]string{}
+	for _, virtualName := range virtualNames {
+		index[translate.PhysicalName(virtualName.Name, virtualName.Namespace)] = virtualName.String()
+	}
+
+	return &fakeNameCache{indices: map[schema.GroupVersionKind]map[string]map[string]string{
+		parentGVK: {namecache.IndexPhysicalToVirtualName: index},
+	}}
+}
+
+func newHostObject(gvk schema.GroupVersionKind, name string, labels map[string]string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetNamespace("vcluster")
+	obj.SetName(name)
+	obj.SetLabels(labels)
+	return obj
+}
+
+func TestResolveLabelSelector(t *testing.T) {
+	parentName := translate.PhysicalName("parent", "default")
+	parent := newHostObject(parentGVK, parentName, map[string]string{"app": "parent"})
+	unknownParent := newHostObject(parentGVK, "unknown", map[string]string{"app": "unknown"})
+	selector := &config.LabelSyncBackSelector{
+		MatchLabels:      map[string]string{"type": "child"},
+		FromParentLabels: map[string]string{"parent-app": "app"},
+	}
+	b := newTestBackSyncController(&config.SyncBackSelector{LabelSelector: selector}, newParentNameCache(types.NamespacedName{Namespace: "default", Name: "parent"}), parent, unknownParent)
+
+	type testCase struct {
+		name     string
+		labels   map[string]string
+		expected types.NamespacedName
+	}
+	testCases := []testCase{
+		{
+			name:     "matching labels",
+			labels:   map[string]string{"type": "child", "parent-app": "parent"},
+			expected: types.NamespacedName{Namespace: "default", Name: "child"},
+		},
+		{
+			name:   "missing match label",
+			labels: map[string]string{"parent-app": "parent"},
+		},
+		{
+			name:   "missing parent label",
+			labels: map[string]string{"type": "child"},
+		},
+		{
+			name:   "parent doesn't exist",
+			labels: map[string]string{"type": "child", "parent-app": "other"},
+		},
+		{
+			name:   "parent isn't synced",
+			labels: map[string]string{"type": "child", "parent-app": "unknown"},
+		},
+	}
+	for _, testCase := range testCases {
+		actual := b.resolveLabelSelector(selector, newHostObject(childGVK, "child", testCase.labels))
+		assert.Equal(t, actual, testCase.expected, "test case %s", testCase.name)
+	}
+
+	// the parent can also be selected by name
+	selector = &config.LabelSyncBackSelector{ParentNameLabel: "parent-name"}
+	b.config.Selectors = []*config.SyncBackSelector{{LabelSelector: selector}}
+	actual := b.resolveLabelSelector(selector, newHostObject(childGVK, "child", map[string]string{"parent-name": parentName}))
+	assert.Equal(t, actual, types.NamespacedName{Namespace: "default", Name: "child"})
+	actual = b.resolveLabelSelector(selector, newHostObject(childGVK, "child", map[string]string{"parent-name": "unknown"}))
+	assert.Equal(t, actual, types.NamespacedName{})
+}
+
+func TestEnqueueParentLabelChanges(t *testing.T) {
+	selector := &config.SyncBackSelector{LabelSelector: &config.LabelSyncBackSelector{
+		FromParentLabels: map[string]string{"parent-app": "app"},
+	}}
+	oldChild := newHostObject(childGVK, "old", map[string]string{"parent-app": "old"})
+	newChild := newHostObject(childGVK, "new", map[string]string{"parent-app": "new"})
+	otherChild := newHostObject(childGVK, "other", map[string]string{"parent-app": "other"})
+	b := newTestBackSyncController(selector, newParentNameCache(), oldChild, newChild, otherChild)
+	assert.Assert(t, b.hasFromParentLabels())
+
+	q := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer q.ShutDown()
+
+	// unchanged labels don't enqueue anything
+	oldParent := newHostObject(parentGVK, "parent", map[string]string{"app": "old"})
+	b.enqueueParentLabelChanges(oldParent, oldParent.DeepCopy(), q)
+	assert.Equal(t, q.Len(), 0)
+
+	// the objects of the old and the new labels are enqueued
+	newParent := newHostObject(parentGVK, "parent", map[string]string{"app": "new"})
+	b.enqueueParentLabelChanges(oldParent, newParent, q)
+	assert.Equal(t, q.Len(), 2)
+	enqueued := map[interface{}]bool{}
+	for q.Len() > 0 {
+		item, _ := q.Get()
+		enqueued[item] = true
+		q.Done(item)
+	}
+	assert.DeepEqual(t, enqueued, map[interface{}]bool{
+		reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "vcluster", Name: "old"}}: true,
+		reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "vcluster", Name: "new"}}: true,
+	})
+}
+
+func TestResolveOwnerReference(t *testing.T) {
+	parentName := translate.PhysicalName("parent", "default")
+	parent := newHostObject(parentGVK, parentName, nil)
+	parent.SetUID("parent-uid")
+	vParent := &unstructured.Unstructured{}
+	vParent.SetGroupVersionKind(parentGVK)
+	vParent.SetNamespace("default")
+	vParent.SetName("parent")
+	vParent.SetUID("virtual-parent-uid")
+
+	selector := &config.OwnerReferenceSyncBackSelector{Controller: true}
+	b := newTestBackSyncController(&config.SyncBackSelector{OwnerReference: selector}, newParentNameCache(types.NamespacedName{Namespace: "default", Name: "parent"}), parent)
+	b.virtualClient = fake.NewClientBuilder().WithObjects(vParent).Build()
+
+	controller := true
+	ownerReference := metav1.OwnerReference{APIVersion: "v1", Kind: "Service", Name: parentName, UID: "parent-uid", Controller: &controller}
+	type testCase struct {
+		name           string
+		ownerReference metav1.OwnerReference
+		expected       types.NamespacedName
+	}
+	otherGroup := ownerReference
+	otherGroup.APIVersion = "serving.knative.dev/v1"
+	formerParent := ownerReference
+	formerParent.UID = "former-parent-uid"
+	noController := ownerReference
+	noController.Controller = nil
+	unknownParent := ownerReference
+	unknownParent.Name = "unknown"
+	testCases := []testCase{
+		{
+			name:           "owned by parent",
+			ownerReference: ownerReference,
+			expected:       types.NamespacedName{Namespace: "default", Name: "parent"},
+		},
+		{
+			name:           "owned by kind of another group",
+			ownerReference: otherGroup,
+		},
+		{
+			name:           "owned by former parent with the same name",
+			ownerReference: formerParent,
+		},
+		{
+			name:           "parent is not the controller",
+			ownerReference: noController,
+		},
+		{
+			name:           "parent isn't synced",
+			ownerReference: unknownParent,
+		},
+	}
+	ctx := context.Background()
+	for _, testCase := range testCases {
+		child := newHostObject(childGVK, "child", nil)
+		child.SetOwnerReferences([]metav1.OwnerReference{testCase.ownerReference})
+		_, actual := b.resolveOwnerReference(ctx, selector, child)
+		assert.Equal(t, actual, testCase.expected, "test case %s", testCase.name)
+	}
+
+	// the owner reference of the virtual object points to the virtual parent
+	child := newHostObject(childGVK, "child", nil)
+	child.SetOwnerReferences([]metav1.OwnerReference{ownerReference})
+	ownerReferences, err := b.translateOwnerReferences(ctx, child, "default")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, ownerReferences, []metav1.OwnerReference{{
+		APIVersion: "v1",
+		Kind:       "Service",
+		Name:       "parent",
+		UID:        "virtual-parent-uid",
+		Controller: &controller,
+	}})
+	ownerReferences, err = b.translateOwnerReferences(ctx, child, "other")
+	assert.NilError(t, err)
+	assert.Equal(t, len(ownerReferences), 0)
+
+	assert.Equal(t, ownerIndexKey("", "Service", parentName), "/Service/"+parentName)
+	assert.Assert(t, ownerIndexKey("serving.knative.dev", "Service", parentName) != ownerIndexKey("", "Service", parentName))
+}
+
+func TestMemorizingHostToVirtualNameResolver(t *testing.T) {
+	parentName := translate.PhysicalName("parent", "default")
+	referenceName := translate.PhysicalName("reference", "default")
+	nc := newParentNameCache(types.NamespacedName{Namespace: "default", Name: "parent"})
+	nc.indices[parentGVK][namecache.IndexPhysicalToVirtualNamePath] = map[string]string{
+		parentName + "/" + namecache.MetadataFieldPath: "default/parent",
+		referenceName + "/spec.reference":              "default/reference",
+	}
+	namespacedNameRegex, err := patchesregex.PrepareRegex("$NAMESPACE/$NAME")
+	assert.NilError(t, err)
+	nameRegex, err := patchesregex.PrepareRegex("prefix-$NAME")
+	assert.NilError(t, err)
+
+	type testCase struct {
+		name             string
+		input            string
+		regex            *regexp.Regexp
+		path             string
+		byName           bool
+		mappings         map[string]string
+		expected         string
+		expectedErr      string
+		expectedMappings map[string]string
+	}
+	testCases := []testCase{
+		{
+			name:             "name",
+			input:            parentName,
+			expected:         "parent",
+			expectedMappings: map[string]string{parentName + "/": "parent"},
+		},
+		{
+			name:             "reference at path",
+			input:            referenceName,
+			path:             "spec.reference",
+			expected:         "reference",
+			expectedMappings: map[string]string{referenceName + "/spec.reference": "reference"},
+		},
+		{
+			name:             "by name",
+			input:            parentName,
+			path:             "spec.reference",
+			byName:           true,
+			expected:         "parent",
+			expectedMappings: map[string]string{parentName + "/" + namecache.MetadataFieldPath: "parent"},
+		},
+		{
+			name:        "unknown name",
+			input:       "unknown",
+			expectedErr: "could not translate unknown host resource name",
+		},
+		{
+			name:             "memorized name",
+			input:            "former",
+			mappings:         map[string]string{"former/": "former-parent"},
+			expected:         "former-parent",
+			expectedMappings: map[string]string{"former/": "former-parent"},
+		},
+		{
+			name:             "regex with namespace",
+			input:            "vcluster/" + parentName,
+			regex:            namespacedNameRegex,
+			expected:         "default/parent",
+			expectedMappings: map[string]string{parentName + "/": "default/parent"},
+		},
+		{
+			name:             "regex without namespace",
+			input:            "prefix-" + parentName,
+			regex:            nameRegex,
+			expected:         "prefix-parent",
+			expectedMappings: map[string]string{parentName + "/": "default/parent"},
+		},
+		{
+			name:             "memorized regex",
+			input:            "vcluster/former",
+			regex:            namespacedNameRegex,
+			mappings:         map[string]string{"former/": "default/former-parent"},
+			expected:         "default/former-parent",
+			expectedMappings: map[string]string{"former/": "default/former-parent"},
+		},
+		{
+			name:             "regex doesn't match",
+			input:            "Not-A-Name",
+			regex:            namespacedNameRegex,
+			expected:         "Not-A-Name",
+			expectedMappings: map[string]string{},
+		},
+		{
+			name:        "regex with unknown name",
+			input:       "vcluster/unknown",
+			regex:       namespacedNameRegex,
+			expectedErr: "could not translate unknown host resource name",
+		},
+	}
+
+	for _, testCase := range testCases {
+		resolver := &memorizingHostToVirtualNameResolver{
+			HostToVirtualTranslator: NewHostToVirtualTranslator("default", "vcluster"),
+			gvk:                     parentGVK,
+			nameCache:               nc,
+			byName:                  testCase.byName,
+			mappings:                testCase.mappings,
+		}
+
+		actual, err := resolver.TranslateName(testCase.input, testCase.regex, testCase.path)
+		if testCase.expectedErr != "" {
+			assert.ErrorContains(t, err, testCase.expectedErr, "test case %s", testCase.name)
+			continue
+		}
+
+		assert.NilError(t, err, "test case %s", testCase.name)
+		assert.Equal(t, actual, testCase.expected, "test case %s", testCase.name)
+		assert.DeepEqual(t, resolver.mappings, testCase.expectedMappings)
+	}
+}
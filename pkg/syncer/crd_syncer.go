@@ -0,0 +1,429 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/plugin"
+	"github.com/loft-sh/vcluster-sdk/log"
+	"github.com/loft-sh/vcluster-sdk/syncer"
+	synccontext "github.com/loft-sh/vcluster-sdk/syncer/context"
+	"github.com/loft-sh/vcluster-sdk/translate"
+	"github.com/pkg/errors"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// AdoptCRDAnnotation marks an unlabeled crd in the virtual cluster that should be taken over
+	// by the plugin, e.g. because it was copied from the host cluster by an older plugin version
+	// that cannot be recognized through the managed fields
+	AdoptCRDAnnotation = "vcluster.loft.sh/adopt-crd"
+)
+
+// legacyFieldManager is the field manager the api server derives from the default user agent of the
+// plugin binary. Older plugin versions copied the host crds with it and without the controlled-by label.
+var legacyFieldManager = func() string {
+	_, command := filepath.Split(os.Args[0])
+	if command == "" {
+		return "unknown"
+	}
+
+	return command
+}()
+
+// CreateCRDSyncer creates a controller that keeps the CRDs of the given group version kinds
+// in the virtual cluster up to date with the CRDs in the host cluster. Changes to schemas,
+// versions and printer columns are synced and virtual CRDs are deleted with the host CRD.
+// CRDs that are missing in the virtual cluster are created before this function returns, so
+// that informers for the mapped kinds can be started right away.
+func CreateCRDSyncer(ctx *synccontext.RegisterContext, gvks []schema.GroupVersionKind) (syncer.Base, error) {
+	groupKinds := map[schema.GroupKind]bool{}
+	for _, gvk := range gvks {
+		groupKinds[gvk.GroupKind()] = true
+	}
+
+	c := &crdController{
+		log:            log.New("crd-syncer"),
+		groupKinds:     groupKinds,
+		physicalClient: ctx.PhysicalManager.GetClient(),
+		virtualClient:  ctx.VirtualManager.GetClient(),
+		virtualReader:  ctx.VirtualManager.GetAPIReader(),
+	}
+	for _, gvk := range gvks {
+		err := c.ensureCRD(ctx, gvk)
+		if err != nil {
+			return nil, fmt.Errorf("error syncronizing CRD %s(%s) from the host cluster into vcluster: %v", gvk.Kind, gvk.GroupVersion().String(), err)
+		}
+	}
+
+	return c, nil
+}
+
+type crdController struct {
+	log        log.Logger
+	groupKinds map[schema.GroupKind]bool
+
+	physicalClient client.Client
+	virtualClient  client.Client
+	virtualReader  client.Reader
+}
+
+var _ syncer.ControllerStarter = &crdController{}
+
+func (c *crdController) Name() string {
+	return "crd-syncer"
+}
+
+func (c *crdController) Register(ctx *synccontext.RegisterContext) error {
+	isMapped := predicate.NewPredicateFuncs(func(object client.Object) bool {
+		crd, ok := object.(*apiextensionsv1.CustomResourceDefinition)
+		return ok && c.groupKinds[schema.GroupKind{Group: crd.Spec.Group, Kind: crd.Spec.Names.Kind}]
+	})
+
+	controller := ctrl.NewControllerManagedBy(ctx.PhysicalManager).
+		Named(c.Name()).
+		Watches(source.NewKindWithCache(&apiextensionsv1.CustomResourceDefinition{}, ctx.VirtualManager.GetCache()), &handler.EnqueueRequestForObject{}, builder.WithPredicates(isMapped)).
+		For(&apiextensionsv1.CustomResourceDefinition{}, builder.WithPredicates(isMapped))
+	return controller.Complete(c)
+}
+
+func (c *crdController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.NewFromExisting(c.log.Base(), req.Name)
+
+	pCRD := &apiextensionsv1.CustomResourceDefinition{}
+	err := c.physicalClient.Get(ctx, types.NamespacedName{Name: req.Name}, pCRD)
+	if err != nil {
+		if !kerrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+
+		pCRD = nil
+	}
+
+	vCRD := &apiextensionsv1.CustomResourceDefinition{}
+	err = c.virtualClient.Get(ctx, types.NamespacedName{Name: req.Name}, vCRD)
+	if err != nil {
+		if !kerrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+
+		vCRD = nil
+	}
+
+	// delete the virtual crd if the host crd is gone, but only if we have created it
+	if pCRD == nil || pCRD.DeletionTimestamp != nil {
+		if vCRD != nil && vCRD.DeletionTimestamp == nil && c.isOwned(vCRD) {
+			log.Infof("Delete virtual crd %s, because host crd was deleted", vCRD.Name)
+			err = c.virtualClient.Delete(ctx, vCRD)
+			if err != nil && !kerrors.IsNotFound(err) {
+				return ctrl.Result{}, errors.Wrap(err, "delete crd in virtual cluster")
+			}
+		}
+
+		return ctrl.Result{}, nil
+	}
+
+	// create the virtual crd if it is missing
+	if vCRD == nil {
+		log.Infof("Create crd %s in virtual cluster", pCRD.Name)
+		c.warnConversion(log, pCRD)
+		err = c.virtualClient.Create(ctx, c.translateCRD(pCRD))
+		if err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "create crd in virtual cluster")
+		}
+
+		return ctrl.Result{}, nil
+	} else if vCRD.DeletionTimestamp != nil {
+		// wait until the virtual crd is gone and recreate it then
+		return ctrl.Result{Requeue: true}, nil
+	} else if !c.isOwned(vCRD) && !isAdoptable(vCRD, pCRD) {
+		// never take over a crd that was installed in the virtual cluster or is managed by someone else
+		log.Infof("Warning: skip crd %s in virtual cluster, because it conflicts with the host crd and was not created by the plugin. Add the %s=true annotation to let the plugin take it over", vCRD.Name, AdoptCRDAnnotation)
+		return ctrl.Result{}, nil
+	}
+
+	// versions that were removed in the host crd are kept unserved until all objects were
+	// migrated to the storage version, otherwise objects stored at these versions are lost
+	newCRD := c.translateCRD(pCRD)
+	storedVersions := filterStoredVersions(vCRD.Status.StoredVersions, newCRD.Spec)
+	removedVersions := keepRemovedVersions(&newCRD.Spec, vCRD)
+
+	// update the virtual crd if the spec has changed
+	if !equality.Semantic.DeepEqual(vCRD.Spec, newCRD.Spec) || !c.isOwned(vCRD) {
+		if !c.isOwned(vCRD) {
+			log.Infof("Adopt crd %s in virtual cluster", vCRD.Name)
+		}
+
+		log.Infof("Update crd %s in virtual cluster, because host crd has changed", pCRD.Name)
+		c.warnConversion(log, pCRD)
+		vCRD.Spec = newCRD.Spec
+		vCRD.Labels = mergeLabels(vCRD.Labels, newCRD.Labels)
+		if vCRD.Annotations != nil {
+			delete(vCRD.Annotations, AdoptCRDAnnotation)
+		}
+		err = c.virtualClient.Update(ctx, vCRD)
+		if err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "update crd in virtual cluster")
+		}
+
+		// migrate the objects after the new storage version is active
+		return ctrl.Result{Requeue: len(removedVersions) > 0}, nil
+	} else if len(removedVersions) == 0 {
+		return ctrl.Result{}, nil
+	}
+
+	// rewrite all objects at the storage version before the removed versions are dropped
+	log.Infof("Migrate objects of crd %s in virtual cluster from removed versions %v", vCRD.Name, removedVersions)
+	err = c.migrateObjects(ctx, vCRD)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "migrate objects to storage version")
+	}
+
+	// the unserved versions are removed from the spec in the next reconcile, as the
+	// api server only allows to remove versions that are not stored anymore
+	log.Infof("Update stored versions of crd %s in virtual cluster to %v", vCRD.Name, storedVersions)
+	vCRD.Status.StoredVersions = storedVersions
+	err = c.virtualClient.Status().Update(ctx, vCRD)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "update crd status in virtual cluster")
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// migrateObjects rewrites all objects of the virtual crd, which makes the api server
+// store them at the current storage version
+func (c *crdController) migrateObjects(ctx context.Context, vCRD *apiextensionsv1.CustomResourceDefinition) error {
+	storageVersion := ""
+	for _, version := range vCRD.Spec.Versions {
+		if version.Storage {
+			storageVersion = version.Name
+		}
+	}
+
+	listKind := vCRD.Spec.Names.ListKind
+	if listKind == "" {
+		listKind = vCRD.Spec.Names.Kind + "List"
+	}
+
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{Group: vCRD.Spec.Group, Version: storageVersion, Kind: listKind})
+	for {
+		err := c.virtualReader.List(ctx, list, client.Limit(500), client.Continue(list.GetContinue()))
+		if err != nil {
+			return errors.Wrap(err, "list objects")
+		}
+
+		for i := range list.Items {
+			// objects that were changed or deleted in the meantime don't need to be rewritten
+			err = c.virtualClient.Update(ctx, &list.Items[i])
+			if err != nil && !kerrors.IsConflict(err) && !kerrors.IsNotFound(err) {
+				return errors.Wrapf(err, "update %s/%s", list.Items[i].GetNamespace(), list.Items[i].GetName())
+			}
+		}
+
+		if list.GetContinue() == "" {
+			return nil
+		}
+	}
+}
+
+// translateCRD converts the host crd into the crd that should exist in the virtual cluster
+func (c *crdController) translateCRD(pCRD *apiextensionsv1.CustomResourceDefinition) *apiextensionsv1.CustomResourceDefinition {
+	vCRD := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: pCRD.Name,
+			Labels: map[string]string{
+				controlledByLabel: plugin.GetPluginName(),
+			},
+		},
+		Spec: *pCRD.Spec.DeepCopy(),
+	}
+	vCRD.Spec.PreserveUnknownFields = false
+
+	// the conversion webhook service of the host cluster is not reachable from the
+	// virtual cluster, so objects are converted without a webhook instead
+	if usesConversionWebhook(pCRD) {
+		vCRD.Spec.Conversion = &apiextensionsv1.CustomResourceConversion{
+			Strategy: apiextensionsv1.NoneConverter,
+		}
+	}
+	return vCRD
+}
+
+// ensureCRD creates the crd of the given kind in the virtual cluster if it does not exist yet
+// and waits until it is established.
+func (c *crdController) ensureCRD(ctx *synccontext.RegisterContext, gvk schema.GroupVersionKind) error {
+	exists, err := translate.KindExists(ctx.VirtualManager.GetConfig(), gvk)
+	if err != nil {
+		return errors.Wrap(err, "check virtual cluster kind")
+	} else if exists {
+		return nil
+	}
+
+	gvr, err := translate.ConvertKindToResource(ctx.PhysicalManager.GetConfig(), gvk)
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return fmt.Errorf("seems like resource %s is not available in the physical cluster or vcluster has no access to it", gvk.String())
+		}
+
+		return err
+	}
+
+	// the caches are not started yet, so the api servers are queried directly
+	name := gvr.GroupResource().String()
+	pCRD := &apiextensionsv1.CustomResourceDefinition{}
+	err = ctx.PhysicalManager.GetAPIReader().Get(ctx.Context, types.NamespacedName{Name: name}, pCRD)
+	if err != nil {
+		return errors.Wrap(err, "retrieve crd in host cluster")
+	}
+
+	c.log.Infof("Create crd %s in virtual cluster", name)
+	c.warnConversion(c.log, pCRD)
+	err = c.virtualClient.Create(ctx.Context, c.translateCRD(pCRD))
+	if err != nil && !kerrors.IsAlreadyExists(err) {
+		return errors.Wrap(err, "create crd in virtual cluster")
+	}
+
+	c.log.Infof("Wait for crd %s to become ready in virtual cluster", name)
+	return wait.ExponentialBackoffWithContext(ctx.Context, wait.Backoff{Duration: time.Second, Factor: 1.5, Cap: time.Minute, Steps: math.MaxInt32}, func() (bool, error) {
+		vCRD := &apiextensionsv1.CustomResourceDefinition{}
+		err := ctx.VirtualManager.GetAPIReader().Get(ctx.Context, types.NamespacedName{Name: name}, vCRD)
+		if err != nil {
+			return false, errors.Wrap(err, "retrieve crd in virtual cluster")
+		}
+
+		for _, cond := range vCRD.Status.Conditions {
+			if cond.Type == apiextensionsv1.Established && cond.Status == apiextensionsv1.ConditionTrue {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+}
+
+// isOwned returns true if the virtual crd was created or adopted by this plugin
+func (c *crdController) isOwned(vCRD *apiextensionsv1.CustomResourceDefinition) bool {
+	return vCRD.Labels[controlledByLabel] == plugin.GetPluginName()
+}
+
+// isAdoptable returns true if the unlabeled virtual crd was copied from the host crd by an older
+// version of the plugin or is marked to be taken over by the plugin
+func isAdoptable(vCRD, pCRD *apiextensionsv1.CustomResourceDefinition) bool {
+	if isControlled(vCRD) {
+		return false
+	} else if vCRD.Annotations[AdoptCRDAnnotation] == "true" {
+		return true
+	} else if vCRD.Spec.Group != pCRD.Spec.Group || vCRD.Spec.Names.Kind != pCRD.Spec.Names.Kind {
+		return false
+	}
+
+	for _, managedFields := range vCRD.ManagedFields {
+		if managedFields.Manager == legacyFieldManager {
+			return true
+		}
+	}
+
+	return false
+}
+
+// warnConversion logs a warning if the conversion webhook of the host crd is dropped
+func (c *crdController) warnConversion(log log.Logger, pCRD *apiextensionsv1.CustomResourceDefinition) {
+	if usesConversionWebhook(pCRD) {
+		log.Infof("Warning: crd %s uses a conversion webhook that is not reachable from the virtual cluster, objects will be converted without a webhook", pCRD.Name)
+	}
+}
+
+func usesConversionWebhook(crd *apiextensionsv1.CustomResourceDefinition) bool {
+	return crd.Spec.Conversion != nil && crd.Spec.Conversion.Strategy == apiextensionsv1.WebhookConverter
+}
+
+// keepRemovedVersions adds the versions of the virtual crd that are still stored, but were removed
+// from the given spec, as unserved versions to the spec and returns their names
+func keepRemovedVersions(spec *apiextensionsv1.CustomResourceDefinitionSpec, vCRD *apiextensionsv1.CustomResourceDefinition) []string {
+	versions := map[string]bool{}
+	for _, version := range spec.Versions {
+		versions[version.Name] = true
+	}
+	stored := map[string]bool{}
+	for _, version := range vCRD.Status.StoredVersions {
+		stored[version] = true
+	}
+
+	removed := []string{}
+	for _, version := range vCRD.Spec.Versions {
+		if versions[version.Name] || !stored[version.Name] {
+			continue
+		}
+
+		version = *version.DeepCopy()
+		version.Served = false
+		version.Storage = false
+		spec.Versions = append(spec.Versions, version)
+		removed = append(removed, version.Name)
+	}
+
+	return removed
+}
+
+// filterStoredVersions returns the stored versions that still exist in the given spec. The
+// storage version of the spec is always part of the stored versions.
+func filterStoredVersions(storedVersions []string, spec apiextensionsv1.CustomResourceDefinitionSpec) []string {
+	versions := map[string]bool{}
+	storageVersion := ""
+	for _, version := range spec.Versions {
+		versions[version.Name] = true
+		if version.Storage {
+			storageVersion = version.Name
+		}
+	}
+
+	filtered := []string{}
+	for _, version := range storedVersions {
+		if versions[version] {
+			filtered = append(filtered, version)
+		}
+	}
+	if storageVersion != "" && !versionsContain(filtered, storageVersion) {
+		filtered = append(filtered, storageVersion)
+	}
+
+	return filtered
+}
+
+func versionsContain(versions []string, version string) bool {
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+func mergeLabels(labels map[string]string, other map[string]string) map[string]string {
+	merged := map[string]string{}
+	for k, v := range labels {
+		merged[k] = v
+	}
+	for k, v := range other {
+		merged[k] = v
+	}
+	return merged
+}
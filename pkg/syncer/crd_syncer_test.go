@@ -0,0 +1,234 @@
+package syncer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/loft-sh/vcluster-sdk/log"
+	"github.com/loft-sh/vcluster-sdk/plugin"
+	"gotest.tools/assert"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestTranslateCRD(t *testing.T) {
+	t.Setenv(plugin.PLUGIN_NAME, "generic-crd-plugin")
+
+	pCRD := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "certificates.cert-manager.io"},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group:                 "cert-manager.io",
+			PreserveUnknownFields: true,
+			Conversion: &apiextensionsv1.CustomResourceConversion{
+				Strategy: apiextensionsv1.WebhookConverter,
+				Webhook: &apiextensionsv1.WebhookConversion{
+					ClientConfig: &apiextensionsv1.WebhookClientConfig{
+						Service: &apiextensionsv1.ServiceReference{Namespace: "cert-manager", Name: "cert-manager-webhook"},
+					},
+					ConversionReviewVersions: []string{"v1"},
+				},
+			},
+		},
+	}
+
+	vCRD := (&crdController{}).translateCRD(pCRD)
+	assert.Assert(t, isControlled(vCRD))
+	assert.Equal(t, vCRD.Spec.PreserveUnknownFields, false)
+	assert.DeepEqual(t, vCRD.Spec.Conversion, &apiextensionsv1.CustomResourceConversion{Strategy: apiextensionsv1.NoneConverter})
+	assert.Equal(t, pCRD.Spec.Conversion.Strategy, apiextensionsv1.WebhookConverter, "host crd must not be changed")
+
+	pCRD.Spec.Conversion = &apiextensionsv1.CustomResourceConversion{Strategy: apiextensionsv1.NoneConverter}
+	assert.DeepEqual(t, (&crdController{}).translateCRD(pCRD).Spec.Conversion, pCRD.Spec.Conversion)
+	pCRD.Spec.Conversion = nil
+	assert.Assert(t, (&crdController{}).translateCRD(pCRD).Spec.Conversion == nil)
+}
+
+func newTestCRD(name string, labels map[string]string, versions ...string) *apiextensionsv1.CustomResourceDefinition {
+	crd := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: "cert-manager.io",
+			Names: apiextensionsv1.CustomResourceDefinitionNames{Kind: "Certificate", Plural: "certificates"},
+			Scope: apiextensionsv1.NamespaceScoped,
+		},
+	}
+	for i, version := range versions {
+		crd.Spec.Versions = append(crd.Spec.Versions, apiextensionsv1.CustomResourceDefinitionVersion{
+			Name:    version,
+			Served:  true,
+			Storage: i == len(versions)-1,
+		})
+	}
+	crd.Status.StoredVersions = []string{versions[len(versions)-1]}
+	return crd
+}
+
+func TestCRDReconcile(t *testing.T) {
+	t.Setenv(plugin.PLUGIN_NAME, "generic-crd-plugin")
+	name := "certificates.cert-manager.io"
+
+	tests := []struct {
+		name         string
+		vCRD         *apiextensionsv1.CustomResourceDefinition
+		expectedSpec []string
+		expectedOwn  bool
+	}{
+		{
+			name:         "skip unlabeled crd",
+			vCRD:         newTestCRD(name, map[string]string{"app": "cert-manager"}, "v1"),
+			expectedSpec: []string{"v1"},
+		},
+		{
+			name: "adopt annotated crd",
+			vCRD: func() *apiextensionsv1.CustomResourceDefinition {
+				crd := newTestCRD(name, map[string]string{"app": "cert-manager"}, "v1")
+				crd.Annotations = map[string]string{AdoptCRDAnnotation: "true"}
+				return crd
+			}(),
+			expectedSpec: []string{"v1", "v2"},
+			expectedOwn:  true,
+		},
+		{
+			name: "adopt crd copied by an older plugin version",
+			vCRD: func() *apiextensionsv1.CustomResourceDefinition {
+				crd := newTestCRD(name, map[string]string{"app": "cert-manager"}, "v1")
+				crd.ManagedFields = []metav1.ManagedFieldsEntry{{Manager: legacyFieldManager, Operation: metav1.ManagedFieldsOperationUpdate}}
+				return crd
+			}(),
+			expectedSpec: []string{"v1", "v2"},
+			expectedOwn:  true,
+		},
+		{
+			name: "skip crd installed by someone else",
+			vCRD: func() *apiextensionsv1.CustomResourceDefinition {
+				crd := newTestCRD(name, map[string]string{"app": "cert-manager"}, "v1")
+				crd.ManagedFields = []metav1.ManagedFieldsEntry{{Manager: "helm", Operation: metav1.ManagedFieldsOperationUpdate}}
+				return crd
+			}(),
+			expectedSpec: []string{"v1"},
+		},
+		{
+			name:         "update owned crd",
+			vCRD:         newTestCRD(name, map[string]string{controlledByLabel: "generic-crd-plugin"}, "v1"),
+			expectedSpec: []string{"v1", "v2"},
+			expectedOwn:  true,
+		},
+		{
+			name:         "skip crd controlled by someone else",
+			vCRD:         newTestCRD(name, map[string]string{controlledByLabel: "other-plugin"}, "v1"),
+			expectedSpec: []string{"v1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := &crdController{
+				log:            log.New("crd-syncer"),
+				physicalClient: fake.NewClientBuilder().WithScheme(plugin.Scheme).WithObjects(newTestCRD(name, nil, "v1", "v2")).Build(),
+				virtualClient:  fake.NewClientBuilder().WithScheme(plugin.Scheme).WithObjects(test.vCRD).Build(),
+			}
+
+			_, err := c.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Name: name}})
+			assert.NilError(t, err)
+
+			vCRD := &apiextensionsv1.CustomResourceDefinition{}
+			err = c.virtualClient.Get(context.Background(), types.NamespacedName{Name: name}, vCRD)
+			assert.NilError(t, err)
+
+			versions := []string{}
+			for _, version := range vCRD.Spec.Versions {
+				versions = append(versions, version.Name)
+			}
+			assert.DeepEqual(t, versions, test.expectedSpec)
+			assert.Equal(t, c.isOwned(vCRD), test.expectedOwn)
+		})
+	}
+}
+
+func TestCRDReconcileRemovedVersion(t *testing.T) {
+	t.Setenv(plugin.PLUGIN_NAME, "generic-crd-plugin")
+	name := "certificates.cert-manager.io"
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Certificate"})
+	obj.SetNamespace("test")
+	obj.SetName("test")
+
+	virtualClient := fake.NewClientBuilder().WithScheme(plugin.Scheme).WithObjects(newTestCRD(name, map[string]string{controlledByLabel: "generic-crd-plugin"}, "v1"), obj).Build()
+	c := &crdController{
+		log:            log.New("crd-syncer"),
+		physicalClient: fake.NewClientBuilder().WithScheme(plugin.Scheme).WithObjects(newTestCRD(name, nil, "v2")).Build(),
+		virtualClient:  virtualClient,
+		virtualReader:  virtualClient,
+	}
+
+	reconcile := func() (*apiextensionsv1.CustomResourceDefinition, ctrl.Result) {
+		result, err := c.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Name: name}})
+		assert.NilError(t, err)
+
+		vCRD := &apiextensionsv1.CustomResourceDefinition{}
+		err = virtualClient.Get(context.Background(), types.NamespacedName{Name: name}, vCRD)
+		assert.NilError(t, err)
+		return vCRD, result
+	}
+
+	// the removed version is kept unserved as long as it is stored
+	vCRD, result := reconcile()
+	assert.Equal(t, result.Requeue, true)
+	assert.DeepEqual(t, vCRD.Spec.Versions, []apiextensionsv1.CustomResourceDefinitionVersion{
+		{Name: "v2", Served: true, Storage: true},
+		{Name: "v1", Served: false, Storage: false},
+	})
+	assert.DeepEqual(t, vCRD.Status.StoredVersions, []string{"v1"})
+
+	// the stored versions are pruned after the objects were migrated
+	vCRD, _ = reconcile()
+	assert.Equal(t, len(vCRD.Spec.Versions), 2)
+	assert.DeepEqual(t, vCRD.Status.StoredVersions, []string{"v2"})
+
+	// the removed version is dropped when it is not stored anymore
+	vCRD, result = reconcile()
+	assert.Equal(t, result.Requeue, false)
+	assert.DeepEqual(t, vCRD.Spec.Versions, []apiextensionsv1.CustomResourceDefinitionVersion{
+		{Name: "v2", Served: true, Storage: true},
+	})
+}
+
+func TestCRDReconcileDelete(t *testing.T) {
+	t.Setenv(plugin.PLUGIN_NAME, "generic-crd-plugin")
+	name := "certificates.cert-manager.io"
+
+	for _, labels := range []map[string]string{{controlledByLabel: "generic-crd-plugin"}, nil} {
+		c := &crdController{
+			log:            log.New("crd-syncer"),
+			physicalClient: fake.NewClientBuilder().WithScheme(plugin.Scheme).Build(),
+			virtualClient:  fake.NewClientBuilder().WithScheme(plugin.Scheme).WithObjects(newTestCRD(name, labels, "v1")).Build(),
+		}
+
+		_, err := c.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Name: name}})
+		assert.NilError(t, err)
+
+		err = c.virtualClient.Get(context.Background(), types.NamespacedName{Name: name}, &apiextensionsv1.CustomResourceDefinition{})
+		assert.Equal(t, kerrors.IsNotFound(err), labels != nil, "only owned crds are deleted with the host crd")
+	}
+}
+
+func TestFilterStoredVersions(t *testing.T) {
+	spec := apiextensionsv1.CustomResourceDefinitionSpec{
+		Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+			{Name: "v1beta1"},
+			{Name: "v1", Storage: true},
+		},
+	}
+
+	assert.DeepEqual(t, filterStoredVersions([]string{"v1beta1", "v1"}, spec), []string{"v1beta1", "v1"})
+	assert.DeepEqual(t, filterStoredVersions([]string{"v1alpha1", "v1"}, spec), []string{"v1"})
+	assert.DeepEqual(t, filterStoredVersions([]string{"v1alpha1"}, spec), []string{"v1"})
+	assert.DeepEqual(t, filterStoredVersions([]string{"v1beta1"}, spec), []string{"v1beta1", "v1"})
+}
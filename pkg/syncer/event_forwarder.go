@@ -0,0 +1,410 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/config"
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/namecache"
+	"github.com/loft-sh/vcluster-sdk/log"
+	"github.com/loft-sh/vcluster-sdk/syncer"
+	synccontext "github.com/loft-sh/vcluster-sdk/syncer/context"
+	"github.com/loft-sh/vcluster-sdk/syncer/translator"
+	"github.com/loft-sh/vcluster-sdk/translate"
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// eventsPerObjectLimit and eventsPerObjectBurst limit the events that are forwarded to a single
+	// virtual object, so that a misbehaving host controller cannot flood the virtual cluster
+	eventsPerObjectLimit = rate.Limit(1.0 / 10)
+	eventsPerObjectBurst = 10
+
+	// eventLimiterExpiry is the time after which the rate limiter of an idle virtual object is removed
+	eventLimiterExpiry = 30 * time.Minute
+)
+
+// hostObjectResolver resolves the virtual object of a host object of a single kind
+type hostObjectResolver func(ctx context.Context, hostObject types.NamespacedName) (types.NamespacedName, error)
+
+// EventForwarderState holds the events that were forwarded already and the rate limits of the
+// virtual objects. It is shared by the event forwarders of all configurations, so that events
+// aren't forwarded again when the configuration is reloaded.
+type EventForwarderState struct {
+	// started is the time the plugin was started. Events that occurred before were forwarded
+	// by a previous plugin instance already.
+	started time.Time
+
+	m sync.Mutex
+	// forwarded holds the host events that were already forwarded
+	forwarded map[types.NamespacedName]forwardedEvent
+	// limiters hold the rate limiters of the virtual objects events are forwarded to
+	limiters map[types.UID]*eventLimiter
+}
+
+// NewEventForwarderState creates the state that is shared by the event forwarders
+func NewEventForwarderState() *EventForwarderState {
+	return &EventForwarderState{
+		started:   time.Now(),
+		forwarded: map[types.NamespacedName]forwardedEvent{},
+		limiters:  map[types.UID]*eventLimiter{},
+	}
+}
+
+// CreateEventForwarder creates a controller that mirrors the events of host objects that are
+// managed by a mapping onto the corresponding virtual objects
+func CreateEventForwarder(ctx *synccontext.RegisterContext, configuration *config.Config, nc namecache.NameCache, state *EventForwarderState) (syncer.Base, error) {
+	e := &eventForwarder{
+		log:            log.New("event-forwarder"),
+		physicalClient: ctx.PhysicalManager.GetClient(),
+		virtualClient:  ctx.VirtualManager.GetClient(),
+		eventRecorder:  ctx.VirtualManager.GetEventRecorderFor("event-forwarder"),
+		vclusterName:   ctx.Options.Name,
+		resolvers:      map[schema.GroupVersionKind]hostObjectResolver{},
+		hostNamespaces: map[string]bool{},
+		state:          state,
+	}
+
+	e.addResolvers(configuration, nc)
+	err := e.addHostNamespaces(ctx, configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// addHostNamespaces adds the host namespaces outside of the target namespace the events of
+// fromHostCluster mappings are reported in. Events of cluster scoped objects are reported
+// in the default namespace.
+func (e *eventForwarder) addHostNamespaces(ctx *synccontext.RegisterContext, configuration *config.Config) error {
+	for _, mapping := range configuration.Mappings {
+		if mapping.FromHostCluster == nil {
+			continue
+		}
+
+		if mapping.FromHostCluster.NameMapping.RewriteName == config.RewriteNameTypeFromHostToVirtualNamespace {
+			for _, namespace := range mapping.FromHostCluster.NameMapping.Namespaces {
+				e.hostNamespaces[namespace.Host] = true
+			}
+
+			continue
+		}
+
+		gvk := mapping.FromHostCluster.GVK()
+		restMapping, err := ctx.PhysicalManager.GetRESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return fmt.Errorf("retrieve rest mapping for %s(%s): %v", gvk.Kind, mapping.FromHostCluster.APIVersion, err)
+		} else if restMapping.Scope.Name() != meta.RESTScopeNameNamespace {
+			e.hostNamespaces[metav1.NamespaceDefault] = true
+		}
+	}
+
+	// the events of the target namespace are watched by the physical manager
+	delete(e.hostNamespaces, ctx.TargetNamespace)
+	return nil
+}
+
+// addResolvers adds the resolvers of the virtual objects for all kinds that are managed by a mapping
+func (e *eventForwarder) addResolvers(configuration *config.Config, nc namecache.NameCache) {
+	for _, mapping := range configuration.Mappings {
+		if mapping.FromVirtualCluster != nil {
+			gvk := mapping.FromVirtualCluster.GVK()
+			e.resolvers[gvk] = func(ctx context.Context, hostObject types.NamespacedName) (types.NamespacedName, error) {
+				return e.resolveTranslatorAnnotations(ctx, gvk, hostObject)
+			}
+
+			for _, syncBack := range mapping.FromVirtualCluster.SyncBack {
+				gvk := syncBack.GVK()
+				e.resolvers[gvk] = func(ctx context.Context, hostObject types.NamespacedName) (types.NamespacedName, error) {
+					return e.resolveBackSyncAnnotations(ctx, gvk, hostObject)
+				}
+			}
+		} else if mapping.FromHostCluster != nil {
+			gvk := mapping.FromHostCluster.GVK()
+			e.resolvers[gvk] = func(_ context.Context, hostObject types.NamespacedName) (types.NamespacedName, error) {
+				return namecache.StringToNamespacedName(nc.GetFirstByIndex(gvk, namecache.IndexHostToVirtualName, hostObject.String())), nil
+			}
+		}
+	}
+}
+
+type eventForwarder struct {
+	log log.Logger
+
+	physicalClient client.Client
+	virtualClient  client.Client
+	eventRecorder  record.EventRecorder
+	vclusterName   string
+
+	// resolvers resolve the virtual objects of host objects by kind
+	resolvers map[schema.GroupVersionKind]hostObjectResolver
+
+	// hostNamespaces are the namespaces besides the target namespace that are watched for events
+	// by hostEventReader, as the cache of the physical manager only holds the target namespace
+	hostNamespaces  map[string]bool
+	hostEventReader client.Reader
+
+	state *EventForwarderState
+}
+
+type forwardedEvent struct {
+	uid   types.UID
+	count int32
+}
+
+type eventLimiter struct {
+	limiter  *rate.Limiter
+	lastUsed time.Time
+}
+
+var _ syncer.ControllerStarter = &eventForwarder{}
+
+func (e *eventForwarder) Name() string {
+	return "event-forwarder"
+}
+
+func (e *eventForwarder) Register(ctx *synccontext.RegisterContext) error {
+	isMapped := predicate.NewPredicateFuncs(func(object client.Object) bool {
+		event, ok := object.(*corev1.Event)
+		if !ok {
+			return false
+		}
+
+		_, ok = e.resolvers[schema.FromAPIVersionAndKind(event.InvolvedObject.APIVersion, event.InvolvedObject.Kind)]
+		return ok
+	})
+
+	controller := ctrl.NewControllerManagedBy(ctx.PhysicalManager).
+		Named(e.Name()).
+		For(&corev1.Event{}, builder.WithPredicates(isMapped))
+
+	if len(e.hostNamespaces) > 0 {
+		namespaces := []string{}
+		for namespace := range e.hostNamespaces {
+			namespaces = append(namespaces, namespace)
+		}
+
+		hostCache, err := cache.MultiNamespacedCacheBuilder(namespaces)(ctx.PhysicalManager.GetConfig(), cache.Options{
+			Scheme: ctx.PhysicalManager.GetScheme(),
+			Mapper: ctx.PhysicalManager.GetRESTMapper(),
+		})
+		if err != nil {
+			return fmt.Errorf("create host event cache: %v", err)
+		}
+
+		err = ctx.PhysicalManager.Add(hostCache)
+		if err != nil {
+			return fmt.Errorf("start host event cache: %v", err)
+		}
+
+		e.hostEventReader = hostCache
+		controller = controller.Watches(source.NewKindWithCache(&corev1.Event{}, hostCache), &handler.EnqueueRequestForObject{}, builder.WithPredicates(isMapped))
+	}
+
+	return controller.Complete(e)
+}
+
+// eventReader returns the reader for the events of the given namespace
+func (e *eventForwarder) eventReader(namespace string) client.Reader {
+	if e.hostNamespaces[namespace] && e.hostEventReader != nil {
+		return e.hostEventReader
+	}
+
+	return e.physicalClient
+}
+
+func (e *eventForwarder) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	event := &corev1.Event{}
+	err := e.eventReader(req.Namespace).Get(ctx, req.NamespacedName, event)
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			e.forget(req.NamespacedName)
+			return ctrl.Result{}, nil
+		}
+
+		return ctrl.Result{}, err
+	}
+
+	count := eventCount(event)
+	if !e.isNew(req.NamespacedName, event.UID, count) || e.isBeforeStart(event) {
+		return ctrl.Result{}, nil
+	}
+
+	// resolve the virtual object
+	involvedObject := event.InvolvedObject
+	gvk := schema.FromAPIVersionAndKind(involvedObject.APIVersion, involvedObject.Kind)
+	resolver, ok := e.resolvers[gvk]
+	if !ok {
+		return ctrl.Result{}, nil
+	}
+
+	vNN, err := resolver(ctx, types.NamespacedName{Namespace: involvedObject.Namespace, Name: involvedObject.Name})
+	if err != nil {
+		return ctrl.Result{}, err
+	} else if vNN.Name == "" {
+		return ctrl.Result{}, nil
+	}
+
+	vObj := &unstructured.Unstructured{}
+	vObj.SetGroupVersionKind(gvk)
+	err = e.virtualClient.Get(ctx, vNN, vObj)
+	if err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	// forward the event
+	if !e.allow(vObj.GetUID()) {
+		e.log.Debugf("drop event %s/%s for %s %s/%s, because too many events were forwarded", event.Namespace, event.Name, gvk.Kind, vNN.Namespace, vNN.Name)
+	} else {
+		e.eventRecorder.Event(vObj, event.Type, event.Reason, forwardedMessage(event))
+	}
+
+	e.state.m.Lock()
+	e.state.forwarded[req.NamespacedName] = forwardedEvent{uid: event.UID, count: count}
+	e.state.m.Unlock()
+	return ctrl.Result{}, nil
+}
+
+// resolveTranslatorAnnotations resolves the virtual object of a host object that was synced from
+// the virtual cluster through the annotations set by the translator of the from virtual syncer
+func (e *eventForwarder) resolveTranslatorAnnotations(ctx context.Context, gvk schema.GroupVersionKind, hostObject types.NamespacedName) (types.NamespacedName, error) {
+	pObj := &unstructured.Unstructured{}
+	pObj.SetGroupVersionKind(gvk)
+	err := e.physicalClient.Get(ctx, hostObject, pObj)
+	if err != nil {
+		return types.NamespacedName{}, client.IgnoreNotFound(err)
+	} else if !translate.IsManaged(pObj) {
+		return types.NamespacedName{}, nil
+	}
+
+	annotations := pObj.GetAnnotations()
+	if annotations == nil {
+		return types.NamespacedName{}, nil
+	}
+
+	return types.NamespacedName{
+		Namespace: annotations[translator.NamespaceAnnotation],
+		Name:      annotations[translator.NameAnnotation],
+	}, nil
+}
+
+// resolveBackSyncAnnotations resolves the virtual object of a host object that is synced
+// back into the virtual cluster through the annotations set by the back syncer
+func (e *eventForwarder) resolveBackSyncAnnotations(ctx context.Context, gvk schema.GroupVersionKind, hostObject types.NamespacedName) (types.NamespacedName, error) {
+	pObj := &unstructured.Unstructured{}
+	pObj.SetGroupVersionKind(gvk)
+	err := e.physicalClient.Get(ctx, hostObject, pObj)
+	if err != nil {
+		return types.NamespacedName{}, client.IgnoreNotFound(err)
+	}
+
+	annotations := pObj.GetAnnotations()
+	if annotations == nil || annotations[translate.MarkerLabel] != e.vclusterName {
+		return types.NamespacedName{}, nil
+	}
+
+	return types.NamespacedName{
+		Namespace: annotations[translator.NamespaceAnnotation],
+		Name:      annotations[translator.NameAnnotation],
+	}, nil
+}
+
+// isNew returns true if the event wasn't forwarded yet or occurred again since
+func (e *eventForwarder) isNew(req types.NamespacedName, uid types.UID, count int32) bool {
+	e.state.m.Lock()
+	defer e.state.m.Unlock()
+
+	forwarded, ok := e.state.forwarded[req]
+	return !ok || forwarded.uid != uid || count > forwarded.count
+}
+
+// isBeforeStart returns true if the event last occurred before the plugin was started. Those events
+// were forwarded before the restart already, as the forwarded events are only held in memory.
+func (e *eventForwarder) isBeforeStart(event *corev1.Event) bool {
+	lastOccurred := lastEventTime(event)
+	return !lastOccurred.IsZero() && lastOccurred.Before(e.state.started)
+}
+
+// forget removes a deleted event from the forwarded events
+func (e *eventForwarder) forget(req types.NamespacedName) {
+	e.state.m.Lock()
+	defer e.state.m.Unlock()
+
+	delete(e.state.forwarded, req)
+}
+
+// allow returns true if another event can be forwarded to the virtual object
+func (e *eventForwarder) allow(uid types.UID) bool {
+	e.state.m.Lock()
+	defer e.state.m.Unlock()
+
+	now := time.Now()
+	for otherUID, l := range e.state.limiters {
+		if now.Sub(l.lastUsed) > eventLimiterExpiry {
+			delete(e.state.limiters, otherUID)
+		}
+	}
+
+	l, ok := e.state.limiters[uid]
+	if !ok {
+		l = &eventLimiter{limiter: rate.NewLimiter(eventsPerObjectLimit, eventsPerObjectBurst)}
+		e.state.limiters[uid] = l
+	}
+
+	l.lastUsed = now
+	return l.limiter.AllowN(now, 1)
+}
+
+// eventCount returns how often the event occurred
+func eventCount(event *corev1.Event) int32 {
+	count := event.Count
+	if event.Series != nil && event.Series.Count > count {
+		count = event.Series.Count
+	}
+	if count == 0 {
+		count = 1
+	}
+
+	return count
+}
+
+// lastEventTime returns the last time the event occurred or zero if the event has no timestamps
+func lastEventTime(event *corev1.Event) time.Time {
+	last := event.LastTimestamp.Time
+	if event.EventTime.Time.After(last) {
+		last = event.EventTime.Time
+	}
+	if event.Series != nil && event.Series.LastObservedTime.Time.After(last) {
+		last = event.Series.LastObservedTime.Time
+	}
+
+	return last
+}
+
+// forwardedMessage returns the message of the forwarded event, which names the host component
+// that reported the event, as the virtual event is reported by the plugin
+func forwardedMessage(event *corev1.Event) string {
+	if event.Source.Component == "" {
+		return event.Message
+	}
+
+	return fmt.Sprintf("%s (reported by %s in the host cluster)", event.Message, event.Source.Component)
+}
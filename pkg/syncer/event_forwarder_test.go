@@ -0,0 +1,251 @@
+package syncer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/config"
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/namecache"
+	"github.com/loft-sh/vcluster-sdk/log"
+	synccontext "github.com/loft-sh/vcluster-sdk/syncer/context"
+	"github.com/loft-sh/vcluster-sdk/syncer/translator"
+	"github.com/loft-sh/vcluster-sdk/translate"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestEventForwarder(t *testing.T) {
+	ctx := context.Background()
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "vcluster", Name: "event", UID: "event-uid"},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: "v1",
+			Kind:       "Service",
+			Namespace:  "vcluster",
+			Name:       "host-service",
+		},
+		Type:          corev1.EventTypeWarning,
+		Reason:        "Failed",
+		Message:       "something failed",
+		Source:        corev1.EventSource{Component: "host-controller"},
+		Count:         1,
+		LastTimestamp: metav1.NewTime(time.Now().Add(time.Minute)),
+	}
+	vService := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "service", UID: "service-uid"}}
+
+	physicalClient := fake.NewClientBuilder().WithObjects(event).Build()
+	recorder := record.NewFakeRecorder(10)
+	e := &eventForwarder{
+		log:            log.New("test"),
+		physicalClient: physicalClient,
+		virtualClient:  fake.NewClientBuilder().WithObjects(vService).Build(),
+		eventRecorder:  recorder,
+		resolvers: map[schema.GroupVersionKind]hostObjectResolver{
+			{Version: "v1", Kind: "Service"}: func(_ context.Context, hostObject types.NamespacedName) (types.NamespacedName, error) {
+				if hostObject.String() == "vcluster/host-service" {
+					return types.NamespacedName{Namespace: "default", Name: "service"}, nil
+				}
+
+				return types.NamespacedName{}, nil
+			},
+		},
+		state: NewEventForwarderState(),
+	}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "vcluster", Name: "event"}}
+	reconcile := func() {
+		_, err := e.Reconcile(ctx, req)
+		assert.NilError(t, err)
+	}
+
+	// the event is forwarded once
+	reconcile()
+	reconcile()
+	assert.Equal(t, len(recorder.Events), 1)
+	assert.Equal(t, <-recorder.Events, "Warning Failed something failed (reported by host-controller in the host cluster)")
+
+	// the event is forwarded again if it occurred again
+	event.Count = 2
+	assert.NilError(t, physicalClient.Update(ctx, event))
+	reconcile()
+	assert.Equal(t, len(recorder.Events), 1)
+	<-recorder.Events
+
+	// events of objects that can't be resolved are not forwarded
+	event.InvolvedObject.Name = "other-service"
+	event.Count = 3
+	assert.NilError(t, physicalClient.Update(ctx, event))
+	reconcile()
+	assert.Equal(t, len(recorder.Events), 0)
+
+	// events that last occurred before the plugin was started were forwarded before the restart
+	e.state = NewEventForwarderState()
+	event.InvolvedObject.Name = "host-service"
+	event.LastTimestamp = metav1.NewTime(e.state.started.Add(-time.Minute))
+	assert.NilError(t, physicalClient.Update(ctx, event))
+	reconcile()
+	assert.Equal(t, len(recorder.Events), 0)
+
+	// and are forwarded again if they occur again after the start
+	event.Count = 4
+	event.LastTimestamp = metav1.NewTime(e.state.started.Add(2 * time.Minute))
+	assert.NilError(t, physicalClient.Update(ctx, event))
+	reconcile()
+	assert.Equal(t, len(recorder.Events), 1)
+	<-recorder.Events
+
+	// deleted events are forgotten
+	assert.NilError(t, physicalClient.Delete(ctx, event))
+	reconcile()
+	assert.Equal(t, len(e.state.forwarded), 0)
+}
+
+func TestEventForwarderFromVirtualMapping(t *testing.T) {
+	ctx := context.Background()
+
+	// a host certificate that was synced from the virtual cluster by a mapping without any reverse patches
+	pObj := &unstructured.Unstructured{}
+	pObj.SetGroupVersionKind(testGVK)
+	pObj.SetNamespace("vcluster")
+	pObj.SetName(translate.PhysicalName("certificate", "default"))
+	pObj.SetLabels(map[string]string{translate.MarkerLabel: translate.Suffix})
+	pObj.SetAnnotations(map[string]string{translator.NameAnnotation: "certificate", translator.NamespaceAnnotation: "default"})
+	vObj := &unstructured.Unstructured{}
+	vObj.SetGroupVersionKind(testGVK)
+	vObj.SetNamespace("default")
+	vObj.SetName("certificate")
+	vObj.SetUID("certificate-uid")
+
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "vcluster", Name: "event", UID: "event-uid"},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: testGVK.GroupVersion().String(),
+			Kind:       testGVK.Kind,
+			Namespace:  pObj.GetNamespace(),
+			Name:       pObj.GetName(),
+		},
+		Type:          corev1.EventTypeNormal,
+		Reason:        "Issuing",
+		Message:       "issuing certificate",
+		LastTimestamp: metav1.NewTime(time.Now().Add(time.Minute)),
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	e := &eventForwarder{
+		log:            log.New("test"),
+		physicalClient: fake.NewClientBuilder().WithObjects(event, pObj).Build(),
+		virtualClient:  fake.NewClientBuilder().WithObjects(vObj).Build(),
+		eventRecorder:  recorder,
+		resolvers:      map[schema.GroupVersionKind]hostObjectResolver{},
+		state:          NewEventForwarderState(),
+	}
+	e.addResolvers(&config.Config{Mappings: []config.Mapping{{
+		FromVirtualCluster: &config.FromVirtualCluster{
+			SyncBase: config.SyncBase{TypeInformation: config.TypeInformation{APIVersion: testGVK.GroupVersion().String(), Kind: testGVK.Kind}},
+		},
+	}}}, &fakeNameCache{})
+
+	_, err := e.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "vcluster", Name: "event"}})
+	assert.NilError(t, err)
+	assert.Equal(t, len(recorder.Events), 1)
+	assert.Equal(t, <-recorder.Events, "Normal Issuing issuing certificate")
+}
+
+func TestEventForwarderHostNamespaces(t *testing.T) {
+	ctx := context.Background()
+	configuration := &config.Config{Mappings: []config.Mapping{{
+		FromHostCluster: &config.FromHostCluster{
+			SyncBase: config.SyncBase{TypeInformation: config.TypeInformation{APIVersion: testGVK.GroupVersion().String(), Kind: testGVK.Kind}},
+			NameMapping: config.NameMapping{
+				RewriteName: config.RewriteNameTypeFromHostToVirtualNamespace,
+				Namespaces:  []config.NamespaceMapping{{Host: "other", Virtual: "mapped"}, {Host: "vcluster"}},
+			},
+		},
+	}}}
+
+	// events in the mapped host namespaces are read from the host event cache
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "event", UID: "event-uid"},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: testGVK.GroupVersion().String(),
+			Kind:       testGVK.Kind,
+			Namespace:  "other",
+			Name:       "certificate",
+		},
+		Type:          corev1.EventTypeNormal,
+		Reason:        "Issuing",
+		Message:       "issuing certificate",
+		LastTimestamp: metav1.NewTime(time.Now().Add(time.Minute)),
+	}
+	vObj := &unstructured.Unstructured{}
+	vObj.SetGroupVersionKind(testGVK)
+	vObj.SetNamespace("mapped")
+	vObj.SetName("certificate")
+	vObj.SetUID("certificate-uid")
+
+	recorder := record.NewFakeRecorder(10)
+	e := &eventForwarder{
+		log:             log.New("test"),
+		physicalClient:  fake.NewClientBuilder().Build(),
+		virtualClient:   fake.NewClientBuilder().WithObjects(vObj).Build(),
+		eventRecorder:   recorder,
+		resolvers:       map[schema.GroupVersionKind]hostObjectResolver{},
+		hostNamespaces:  map[string]bool{},
+		hostEventReader: fake.NewClientBuilder().WithObjects(event).Build(),
+		state:           NewEventForwarderState(),
+	}
+	e.addResolvers(configuration, &fakeNameCache{indices: map[schema.GroupVersionKind]map[string]map[string]string{
+		testGVK: {namecache.IndexHostToVirtualName: {"other/certificate": "mapped/certificate"}},
+	}})
+
+	// the target namespace is watched by the physical manager already
+	err := e.addHostNamespaces(&synccontext.RegisterContext{TargetNamespace: "vcluster"}, configuration)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, e.hostNamespaces, map[string]bool{"other": true})
+
+	_, err = e.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "other", Name: "event"}})
+	assert.NilError(t, err)
+	assert.Equal(t, len(recorder.Events), 1)
+	assert.Equal(t, <-recorder.Events, "Normal Issuing issuing certificate")
+}
+
+func TestEventForwarderRateLimit(t *testing.T) {
+	e := &eventForwarder{state: NewEventForwarderState()}
+
+	// a burst of events is forwarded, afterwards the events of the object are dropped
+	for i := 0; i < eventsPerObjectBurst; i++ {
+		assert.Assert(t, e.allow("first"), "event %d", i)
+	}
+	assert.Assert(t, !e.allow("first"))
+
+	// other objects have their own limit
+	assert.Assert(t, e.allow("second"))
+
+	// limiters of idle objects are removed
+	e.state.limiters["first"].lastUsed = time.Now().Add(-eventLimiterExpiry - time.Minute)
+	assert.Assert(t, e.allow("second"))
+	_, ok := e.state.limiters["first"]
+	assert.Assert(t, !ok)
+	assert.Assert(t, e.allow("first"))
+}
+
+func TestLastEventTime(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	assert.Assert(t, lastEventTime(&corev1.Event{}).IsZero())
+	assert.Equal(t, lastEventTime(&corev1.Event{LastTimestamp: metav1.NewTime(now)}), now)
+	assert.Equal(t, lastEventTime(&corev1.Event{LastTimestamp: metav1.NewTime(now), EventTime: metav1.NewMicroTime(now.Add(time.Second))}), now.Add(time.Second))
+	assert.Equal(t, lastEventTime(&corev1.Event{EventTime: metav1.NewMicroTime(now), Series: &corev1.EventSeries{LastObservedTime: metav1.NewMicroTime(now.Add(time.Minute))}}), now.Add(time.Minute))
+}
+
+func TestEventCount(t *testing.T) {
+	assert.Equal(t, eventCount(&corev1.Event{}), int32(1))
+	assert.Equal(t, eventCount(&corev1.Event{Count: 3}), int32(3))
+	assert.Equal(t, eventCount(&corev1.Event{Count: 1, Series: &corev1.EventSeries{Count: 5}}), int32(5))
+}
@@ -2,10 +2,14 @@ package syncer
 
 import (
 	"context"
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/config"
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/metrics"
 	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/namecache"
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/plugin"
 	"github.com/loft-sh/vcluster-sdk/log"
 	"github.com/loft-sh/vcluster-sdk/syncer"
 	synccontext "github.com/loft-sh/vcluster-sdk/syncer/context"
@@ -26,6 +30,63 @@ const (
 	ForceSyncAnnotation = "vcluster.loft.sh/force-sync"
 )
 
+// vclusterSyncer is a syncer of vcluster that can be enabled or disabled via the --sync flag
+type vclusterSyncer struct {
+	name             string
+	enabledByDefault bool
+
+	// alwaysSynced is true if vcluster syncs all objects of the kind, so they don't
+	// need the force sync annotation to be synced
+	alwaysSynced bool
+}
+
+// vclusterSyncers are the kinds vcluster syncs itself, only these can be force synced
+var vclusterSyncers = map[schema.GroupVersionKind]vclusterSyncer{
+	{Version: "v1", Kind: "ConfigMap"}:                                        {name: "configmaps", enabledByDefault: true},
+	{Version: "v1", Kind: "Secret"}:                                           {name: "secrets", enabledByDefault: true},
+	{Version: "v1", Kind: "Endpoints"}:                                        {name: "endpoints", enabledByDefault: true, alwaysSynced: true},
+	{Version: "v1", Kind: "Pod"}:                                              {name: "pods", enabledByDefault: true, alwaysSynced: true},
+	{Version: "v1", Kind: "Service"}:                                          {name: "services", enabledByDefault: true, alwaysSynced: true},
+	{Version: "v1", Kind: "PersistentVolumeClaim"}:                            {name: "persistentvolumeclaims", enabledByDefault: true, alwaysSynced: true},
+	{Version: "v1", Kind: "ServiceAccount"}:                                   {name: "serviceaccounts", enabledByDefault: false, alwaysSynced: true},
+	{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"}:              {name: "ingresses", enabledByDefault: true, alwaysSynced: true},
+	{Group: "networking.k8s.io", Version: "v1", Kind: "NetworkPolicy"}:        {name: "networkpolicies", enabledByDefault: false, alwaysSynced: true},
+	{Group: "policy", Version: "v1", Kind: "PodDisruptionBudget"}:             {name: "poddisruptionbudgets", enabledByDefault: false, alwaysSynced: true},
+	{Group: "snapshot.storage.k8s.io", Version: "v1", Kind: "VolumeSnapshot"}: {name: "volumesnapshots", enabledByDefault: false, alwaysSynced: true},
+}
+
+// NeedsForceSyncController returns true if vcluster only syncs objects of the kind that carry
+// the force sync annotation. Kinds that vcluster always syncs don't need a controller.
+func NeedsForceSyncController(gvk schema.GroupVersionKind) bool {
+	s, ok := vclusterSyncers[gvk]
+	return ok && !s.alwaysSynced
+}
+
+// ValidateForceSyncKind returns an error if the kind is not synced by vcluster
+// or the vcluster syncer for the kind is disabled
+func ValidateForceSyncKind(options *synccontext.VirtualClusterOptions, gvk schema.GroupVersionKind) error {
+	s, ok := vclusterSyncers[gvk]
+	if !ok {
+		return fmt.Errorf("the %s(%s) sync is being used in the configuration, but %s is not synced by vcluster", gvk.Kind, gvk.GroupVersion().String(), gvk.Kind)
+	}
+
+	// the last flag for a syncer wins
+	enabled := s.enabledByDefault
+	for _, c := range options.Controllers {
+		c = strings.TrimSpace(c)
+		if c == "-"+s.name {
+			enabled = false
+		} else if c == s.name || c == "+"+s.name || c == "*" {
+			enabled = true
+		}
+	}
+	if !enabled {
+		return fmt.Errorf("the %s sync is being used in the configuration, but vcluster %s syncer is disabled", gvk.Kind, s.name)
+	}
+
+	return nil
+}
+
 type ForceSyncConfig struct {
 	Parent config.FromVirtualCluster
 	Patch  config.Patch
@@ -38,6 +99,7 @@ func CreateForceSyncController(ctx *synccontext.RegisterContext, GVK schema.Grou
 		config:        config,
 		nameCache:     nameCache,
 		virtualClient: ctx.VirtualManager.GetClient(),
+		metrics:       metrics.NewController(metrics.ControllerForceSync, GVK, plugin.GetPluginName()),
 	}, nil
 }
 
@@ -47,6 +109,7 @@ type forceSyncController struct {
 	config        []ForceSyncConfig
 	nameCache     namecache.NameCache
 	virtualClient client.Client
+	metrics       *metrics.Controller
 }
 
 var _ syncer.ControllerStarter = &backSyncController{}
@@ -56,6 +119,13 @@ func (f *forceSyncController) Name() string {
 }
 
 func (f *forceSyncController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	start := time.Now()
+	result, err := f.reconcile(ctx, req)
+	f.metrics.ObserveReconcile(start, result, err)
+	return result, err
+}
+
+func (f *forceSyncController) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	obj := &unstructured.Unstructured{}
 	obj.SetGroupVersionKind(f.GVK)
 	err := f.virtualClient.Get(ctx, req.NamespacedName, obj)
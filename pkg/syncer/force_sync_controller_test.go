@@ -0,0 +1,119 @@
+package syncer
+
+import (
+	"testing"
+
+	synccontext "github.com/loft-sh/vcluster-sdk/syncer/context"
+	"gotest.tools/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestValidateForceSyncKind(t *testing.T) {
+	secretGVK := schema.GroupVersionKind{Version: "v1", Kind: "Secret"}
+
+	type testCase struct {
+		name        string
+		controllers []string
+		gvk         schema.GroupVersionKind
+		expectedErr string
+	}
+	testCases := []testCase{
+		{
+			name: "enabled by default",
+			gvk:  secretGVK,
+		},
+		{
+			name:        "disabled",
+			controllers: []string{"-secrets"},
+			gvk:         secretGVK,
+			expectedErr: "vcluster secrets syncer is disabled",
+		},
+		{
+			name:        "enabled again",
+			controllers: []string{"-secrets", " +secrets"},
+			gvk:         secretGVK,
+		},
+		{
+			name:        "enabled by wildcard",
+			controllers: []string{"-secrets", "*"},
+			gvk:         secretGVK,
+		},
+		{
+			name:        "other kind disabled",
+			controllers: []string{"-configmaps"},
+			gvk:         secretGVK,
+		},
+		{
+			name: "always synced by vcluster",
+			gvk:  schema.GroupVersionKind{Version: "v1", Kind: "Service"},
+		},
+		{
+			name:        "disabled by default",
+			gvk:         schema.GroupVersionKind{Version: "v1", Kind: "ServiceAccount"},
+			expectedErr: "vcluster serviceaccounts syncer is disabled",
+		},
+		{
+			name:        "enabled explicitly",
+			controllers: []string{"serviceaccounts"},
+			gvk:         schema.GroupVersionKind{Version: "v1", Kind: "ServiceAccount"},
+		},
+		{
+			name:        "not synced by vcluster",
+			gvk:         testGVK,
+			expectedErr: "Certificate is not synced by vcluster",
+		},
+		{
+			name:        "built-in kind not synced by vcluster",
+			gvk:         schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+			expectedErr: "Deployment is not synced by vcluster",
+		},
+		{
+			name:        "other version of a synced kind",
+			gvk:         schema.GroupVersionKind{Group: "policy", Version: "v1beta1", Kind: "PodDisruptionBudget"},
+			expectedErr: "PodDisruptionBudget(policy/v1beta1) sync is being used in the configuration, but PodDisruptionBudget is not synced by vcluster",
+		},
+	}
+
+	// every kind synced by vcluster is accepted as soon as its syncer is enabled
+	for gvk, s := range vclusterSyncers {
+		testCases = append(testCases, testCase{
+			name:        "synced by vcluster " + gvk.String(),
+			controllers: []string{s.name},
+			gvk:         gvk,
+		})
+	}
+
+	for _, testCase := range testCases {
+		err := ValidateForceSyncKind(&synccontext.VirtualClusterOptions{Controllers: testCase.controllers}, testCase.gvk)
+		if testCase.expectedErr != "" {
+			assert.ErrorContains(t, err, testCase.expectedErr, "test case %s", testCase.name)
+		} else {
+			assert.NilError(t, err, "test case %s", testCase.name)
+		}
+	}
+}
+
+func TestNeedsForceSyncController(t *testing.T) {
+	testCases := []struct {
+		gvk      schema.GroupVersionKind
+		expected bool
+	}{
+		{gvk: schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, expected: true},
+		{gvk: schema.GroupVersionKind{Version: "v1", Kind: "Secret"}, expected: true},
+		{gvk: schema.GroupVersionKind{Version: "v1", Kind: "Endpoints"}},
+		{gvk: schema.GroupVersionKind{Version: "v1", Kind: "Pod"}},
+		{gvk: schema.GroupVersionKind{Version: "v1", Kind: "Service"}},
+		{gvk: schema.GroupVersionKind{Version: "v1", Kind: "PersistentVolumeClaim"}},
+		{gvk: schema.GroupVersionKind{Version: "v1", Kind: "ServiceAccount"}},
+		{gvk: schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"}},
+		{gvk: schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "NetworkPolicy"}},
+		{gvk: schema.GroupVersionKind{Group: "policy", Version: "v1", Kind: "PodDisruptionBudget"}},
+		{gvk: schema.GroupVersionKind{Group: "snapshot.storage.k8s.io", Version: "v1", Kind: "VolumeSnapshot"}},
+		{gvk: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}},
+		{gvk: testGVK},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, NeedsForceSyncController(testCase.gvk), testCase.expected, "test case %s", testCase.gvk.String())
+	}
+}
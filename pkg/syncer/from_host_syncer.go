@@ -0,0 +1,732 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/config"
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/metrics"
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/namecache"
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/patches"
+	patchesregex "github.com/loft-sh/vcluster-generic-crd-plugin/pkg/patches/regex"
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/plugin"
+	"github.com/loft-sh/vcluster-sdk/log"
+	"github.com/loft-sh/vcluster-sdk/syncer"
+	synccontext "github.com/loft-sh/vcluster-sdk/syncer/context"
+	"github.com/loft-sh/vcluster-sdk/syncer/translator"
+	"github.com/loft-sh/vcluster-sdk/translate"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+func CreateFromHostSyncer(ctx *synccontext.RegisterContext, config *config.FromHostCluster, nc namecache.NameCache, dryRun bool) (syncer.Base, error) {
+	obj := &unstructured.Unstructured{}
+	obj.SetKind(config.Kind)
+	obj.SetAPIVersion(config.APIVersion)
+
+	err := validateFromHostConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration for %s(%s) mapping: %v", config.Kind, config.APIVersion, err)
+	}
+
+	var selector labels.Selector
+	if config.Selector != nil {
+		selector, err = metav1.LabelSelectorAsSelector(metav1.SetAsLabelSelector(config.Selector.LabelSelector))
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector in configuration for %s(%s) mapping: %v", config.Kind, config.APIVersion, err)
+		}
+	}
+
+	gvk := schema.FromAPIVersionAndKind(config.APIVersion, config.Kind)
+	mapping, err := ctx.PhysicalManager.GetRESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, fmt.Errorf("retrieve rest mapping for %s(%s): %v", config.Kind, config.APIVersion, err)
+	}
+
+	namespaced := mapping.Scope.Name() == meta.RESTScopeNameNamespace
+	rewriteName, err := validateNameMapping(config.NameMapping, namespaced)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nameMapping in configuration for %s(%s) mapping: %v", config.Kind, config.APIVersion, err)
+	}
+
+	statusIsSubresource, err := hasStatusSubresource(ctx, gvk, config.StatusSubresource)
+	if err != nil {
+		return nil, fmt.Errorf("check status subresource of %s(%s): %v", config.Kind, config.APIVersion, err)
+	}
+
+	f := &fromHostController{
+		log: log.New(config.Kind + "-from-host-syncer"),
+		patcher: &patcher{
+			fromClient:          ctx.PhysicalManager.GetClient(),
+			toClient:            ctx.VirtualManager.GetClient(),
+			statusIsSubresource: statusIsSubresource,
+			log:                 log.New(config.Kind + "-from-host-syncer"),
+			dryRun:              dryRun,
+		},
+
+		obj:         obj,
+		gvk:         gvk,
+		config:      config,
+		nameCache:   nc,
+		selector:    selector,
+		namespaced:  namespaced,
+		rewriteName: rewriteName,
+
+		hostToVirtualNamespaces: hostToVirtualNamespaces(config.NameMapping),
+		virtualToHostNamespaces: virtualToHostNamespaces(config.NameMapping),
+
+		targetNamespace: ctx.TargetNamespace,
+		physicalClient:  ctx.PhysicalManager.GetClient(),
+		hostReader:      ctx.PhysicalManager.GetClient(),
+
+		currentNamespace:       ctx.CurrentNamespace,
+		currentNamespaceClient: ctx.CurrentNamespaceClient,
+
+		virtualClient: ctx.VirtualManager.GetClient(),
+	}
+	f.metrics = metrics.NewController(metrics.ControllerFromHost, gvk, f.getControllerID())
+	f.patcher.metrics = f.metrics
+	return f, nil
+}
+
+type fromHostController struct {
+	patcher *patcher
+
+	log log.Logger
+	obj client.Object
+	gvk schema.GroupVersionKind
+
+	config      *config.FromHostCluster
+	nameCache   namecache.NameCache
+	selector    labels.Selector
+	namespaced  bool
+	rewriteName config.RewriteNameType
+
+	// hostToVirtualNamespaces and virtualToHostNamespaces hold the configured
+	// namespace mapping of RewriteNameTypeFromHostToVirtualNamespace
+	hostToVirtualNamespaces map[string]string
+	virtualToHostNamespaces map[string]string
+
+	targetNamespace string
+	physicalClient  client.Client
+
+	// hostReader is used to read host objects, which is a cache of the
+	// mapped namespaces if objects from other host namespaces are synced
+	hostReader client.Reader
+
+	currentNamespace       string
+	currentNamespaceClient client.Client
+
+	virtualClient client.Client
+
+	metrics *metrics.Controller
+}
+
+var _ syncer.ControllerStarter = &fromHostController{}
+
+func (f *fromHostController) Name() string {
+	return f.config.Kind + "-from-host-syncer"
+}
+
+func (f *fromHostController) resource() client.Object {
+	return f.obj.DeepCopyObject().(client.Object)
+}
+
+func (f *fromHostController) Register(ctx *synccontext.RegisterContext) error {
+	maxConcurrentReconciles := 1
+	controllerBuilder := ctrl.NewControllerManagedBy(ctx.PhysicalManager).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: maxConcurrentReconciles,
+		}).
+		Named(f.Name()).
+		Watches(source.NewKindWithCache(f.resource(), ctx.VirtualManager.GetCache()), &handler.Funcs{
+			CreateFunc: func(event event.CreateEvent, limitingInterface workqueue.RateLimitingInterface) {
+				f.enqueueVirtual(event.Object, limitingInterface)
+			},
+			UpdateFunc: func(event event.UpdateEvent, limitingInterface workqueue.RateLimitingInterface) {
+				f.enqueueVirtual(event.ObjectNew, limitingInterface)
+			},
+			DeleteFunc: func(event event.DeleteEvent, limitingInterface workqueue.RateLimitingInterface) {
+				f.enqueueVirtual(event.Object, limitingInterface)
+			},
+			GenericFunc: func(event event.GenericEvent, limitingInterface workqueue.RateLimitingInterface) {
+				f.enqueueVirtual(event.Object, limitingInterface)
+			},
+		}).
+		For(f.resource(), builder.WithPredicates(predicate.NewPredicateFuncs(func(object client.Object) bool {
+			return f.watchesHostNamespace(object.GetNamespace())
+		})))
+
+	if f.rewriteName == config.RewriteNameTypeFromHostToVirtualNamespace {
+		// the physical manager cache is limited to the target namespace, so we
+		// need a separate cache to watch the objects in the mapped host namespaces
+		hostNamespaces := []string{}
+		for _, namespace := range f.config.NameMapping.Namespaces {
+			hostNamespaces = append(hostNamespaces, namespace.Host)
+		}
+
+		hostCache, err := cache.MultiNamespacedCacheBuilder(hostNamespaces)(ctx.PhysicalManager.GetConfig(), cache.Options{
+			Scheme: ctx.PhysicalManager.GetScheme(),
+			Mapper: ctx.PhysicalManager.GetRESTMapper(),
+		})
+		if err != nil {
+			return fmt.Errorf("create host cache: %v", err)
+		}
+
+		err = ctx.PhysicalManager.Add(hostCache)
+		if err != nil {
+			return fmt.Errorf("start host cache: %v", err)
+		}
+
+		f.hostReader = hostCache
+		controllerBuilder = controllerBuilder.Watches(source.NewKindWithCache(f.resource(), hostCache), &handler.EnqueueRequestForObject{})
+	}
+
+	return controllerBuilder.Complete(f)
+}
+
+// watchesHostNamespace returns true if the host objects of the namespace are synced. With
+// RewriteNameTypeFromHostToVirtualNamespace only the mapped namespaces are held by the host cache.
+func (f *fromHostController) watchesHostNamespace(namespace string) bool {
+	if f.rewriteName != config.RewriteNameTypeFromHostToVirtualNamespace {
+		return true
+	}
+
+	_, ok := f.hostToVirtualNamespaces[namespace]
+	return ok
+}
+
+func (f *fromHostController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	start := time.Now()
+	result, err := f.reconcile(ctx, req)
+	f.metrics.ObserveReconcile(start, result, err)
+	return result, err
+}
+
+func (f *fromHostController) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.NewFromExisting(f.log.Base(), req.Name)
+	syncContext := &synccontext.SyncContext{
+		Context:                ctx,
+		Log:                    log,
+		TargetNamespace:        f.targetNamespace,
+		PhysicalClient:         f.physicalClient,
+		CurrentNamespace:       f.currentNamespace,
+		CurrentNamespaceClient: f.currentNamespaceClient,
+		VirtualClient:          f.virtualClient,
+	}
+
+	// get physical resource, objects of namespaces that aren't synced are treated
+	// as missing, so that their virtual objects are removed
+	pObj := f.resource()
+	if !f.watchesHostNamespace(req.Namespace) {
+		pObj = nil
+	} else {
+		err := f.hostReader.Get(ctx, req.NamespacedName, pObj)
+		if err != nil {
+			if !kerrors.IsNotFound(err) {
+				return ctrl.Result{}, err
+			}
+
+			pObj = nil
+		} else if !f.isManaged(pObj) {
+			return ctrl.Result{}, nil
+		}
+	}
+
+	// get virtual resource
+	vNN := f.hostToVirtual(req.NamespacedName)
+	if vNN.Name == "" {
+		// we skip early here, we cannot resolve the physical to virtual,
+		// which means it either doesn't matches or shouldn't get synced anymore
+		return ctrl.Result{}, nil
+	}
+	vObj := f.resource()
+	err := f.virtualClient.Get(ctx, vNN, vObj)
+	if err != nil {
+		if !kerrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+
+		vObj = nil
+	}
+
+	// check what function we should call
+	if vObj != nil && pObj == nil {
+		return f.syncDown(syncContext, vObj)
+	} else if vObj != nil && pObj != nil {
+		return f.sync(syncContext, pObj, vObj)
+	} else if vObj == nil && pObj != nil {
+		return f.syncUp(syncContext, pObj)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+func (f *fromHostController) syncDown(ctx *synccontext.SyncContext, vObj client.Object) (ctrl.Result, error) {
+	if f.isExcluded(vObj) {
+		return ctrl.Result{}, nil
+	}
+
+	// host object is gone, so we delete the virtual copy as well
+	return f.deleteVirtualObject(ctx, vObj, "physical is missing, but virtual object exists")
+}
+
+func (f *fromHostController) sync(ctx *synccontext.SyncContext, pObj client.Object, vObj client.Object) (ctrl.Result, error) {
+	if f.isExcluded(vObj) {
+		return ctrl.Result{}, nil
+	} else if !f.objectMatches(pObj) {
+		return f.deleteVirtualObject(ctx, vObj, "physical object is not selected anymore")
+	}
+
+	// apply reverse patches
+	result, err := f.patcher.ApplyReversePatches(ctx.Context, pObj, vObj, f.config.ReversePatches, f.virtualToHostNameResolver(vObj.GetNamespace()), f.templateContext(vObj.GetNamespace(), pObj.GetNamespace()))
+	if err != nil {
+		if kerrors.IsInvalid(err) {
+			ctx.Log.Infof("Warning: this message could indicate a timing issue with no significant impact, or a bug. Please report this if your resource never reaches the expected state. Error message: failed to patch physical %s %s/%s: %v", f.config.Kind, pObj.GetNamespace(), pObj.GetName(), err)
+			// this happens when some field is being removed shortly after being added, which suggest it's a timing issue
+			// it doesn't seem to have any negative consequence besides the logged error message
+			f.metrics.InvalidRequeue()
+			return ctrl.Result{Requeue: true}, nil
+		}
+
+		return ctrl.Result{}, fmt.Errorf("failed to patch physical %s %s/%s: %v", f.config.Kind, pObj.GetNamespace(), pObj.GetName(), err)
+	} else if result == controllerutil.OperationResultUpdated || result == controllerutil.OperationResultUpdatedStatus || result == controllerutil.OperationResultUpdatedStatusOnly {
+		// a change will trigger reconciliation anyway, and at that point we can make
+		// a more accurate updates(patches) to the virtual resource
+		return ctrl.Result{}, nil
+	}
+
+	// apply patches
+	_, err = f.patcher.ApplyPatches(ctx.Context, pObj, vObj, f.config.Patches, f.config.ReversePatches, f.translateMetadata, f.hostToVirtualNameResolver(pObj.GetNamespace(), vObj.GetNamespace()), f.templateContext(vObj.GetNamespace(), pObj.GetNamespace()))
+	if err != nil {
+		if kerrors.IsInvalid(err) {
+			ctx.Log.Infof("Warning: this message could indicate a timing issue with no significant impact, or a bug. Please report this if your resource never reaches the expected state. Error message: failed to patch virtual %s %s/%s: %v", f.config.Kind, vObj.GetNamespace(), vObj.GetName(), err)
+			// this happens when some field is being removed shortly after being added, which suggest it's a timing issue
+			// it doesn't seem to have any negative consequence besides the logged error message
+			f.metrics.InvalidRequeue()
+			return ctrl.Result{Requeue: true}, nil
+		}
+
+		return ctrl.Result{}, fmt.Errorf("error applying patches: %v", err)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+func (f *fromHostController) syncUp(ctx *synccontext.SyncContext, pObj client.Object) (ctrl.Result, error) {
+	if !f.objectMatches(pObj) {
+		return ctrl.Result{}, nil
+	}
+
+	vNN := f.hostToVirtual(types.NamespacedName{
+		Namespace: pObj.GetNamespace(),
+		Name:      pObj.GetName(),
+	})
+	if vNN.Namespace != "" {
+		exists, err := f.ensureVirtualNamespace(ctx, vNN.Namespace)
+		if err != nil {
+			return ctrl.Result{}, err
+		} else if !exists {
+			// the namespace was only created as dry run, so the object couldn't be created either
+			f.patcher.logDryRunSkip(f.config.Kind, vNN, "namespace "+vNN.Namespace+" would be created")
+			return ctrl.Result{}, nil
+		}
+	}
+
+	// apply object to virtual cluster
+	ctx.Log.Infof("Create virtual %s %s/%s, since it is missing, but physical object exists", f.config.Kind, vNN.Namespace, vNN.Name)
+	_, err := f.patcher.ApplyPatches(ctx.Context, pObj, nil, f.config.Patches, f.config.ReversePatches, f.translateMetadata, f.hostToVirtualNameResolver(pObj.GetNamespace(), vNN.Namespace), f.templateContext(vNN.Namespace, pObj.GetNamespace()))
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("error applying patches: %v", err)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// ensureVirtualNamespace creates the virtual namespace if it is missing and returns true if the
+// namespace exists afterwards, which is not the case if it was only created as dry run
+func (f *fromHostController) ensureVirtualNamespace(ctx *synccontext.SyncContext, namespace string) (bool, error) {
+	err := f.virtualClient.Get(ctx.Context, types.NamespacedName{Name: namespace}, &corev1.Namespace{})
+	if err == nil {
+		return true, nil
+	} else if !kerrors.IsNotFound(err) {
+		return false, err
+	}
+
+	ctx.Log.Infof("Create virtual namespace %s, since it is missing", namespace)
+	err = f.patcher.Create(ctx.Context, f.virtualClient, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})
+	if err != nil && !kerrors.IsAlreadyExists(err) {
+		return false, fmt.Errorf("error creating virtual namespace %s: %v", namespace, err)
+	}
+
+	return !f.patcher.dryRun || kerrors.IsAlreadyExists(err), nil
+}
+
+func (f *fromHostController) deleteVirtualObject(ctx *synccontext.SyncContext, vObj client.Object, reason string) (ctrl.Result, error) {
+	ctx.Log.Infof("delete virtual %s %s/%s, because %s", f.config.Kind, vObj.GetNamespace(), vObj.GetName(), reason)
+	err := f.patcher.Delete(ctx.Context, ctx.VirtualClient, vObj)
+	if err != nil && !kerrors.IsNotFound(err) {
+		ctx.Log.Infof("error deleting virtual %s %s/%s: %v", f.config.Kind, vObj.GetNamespace(), vObj.GetName(), err)
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+func (f *fromHostController) enqueueVirtual(obj client.Object, q workqueue.RateLimitingInterface) {
+	if obj == nil || f.isExcluded(obj) {
+		return
+	}
+
+	// prefer the host name that was stored on the virtual object during the sync
+	annotations := obj.GetAnnotations()
+	if annotations != nil && annotations[namecache.HostNameAnnotation] != "" {
+		q.Add(reconcile.Request{NamespacedName: types.NamespacedName{
+			Namespace: annotations[namecache.HostNamespaceAnnotation],
+			Name:      annotations[namecache.HostNameAnnotation],
+		}})
+		return
+	}
+
+	pNN := f.virtualToHost(types.NamespacedName{
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+	})
+	if pNN.Name != "" {
+		q.Add(reconcile.Request{NamespacedName: pNN})
+	}
+}
+
+func (f *fromHostController) templateContext(virtualNamespace, hostNamespace string) *patches.TemplateContext {
+	return &patches.TemplateContext{
+		VirtualNamespace: virtualNamespace,
+		HostNamespace:    hostNamespace,
+		PluginName:       plugin.GetPluginName(),
+		ControllerID:     f.getControllerID(),
+	}
+}
+
+func (f *fromHostController) getControllerID() string {
+	if f.config.ID != "" {
+		return f.config.ID
+	}
+	return plugin.GetPluginName()
+}
+
+func (f *fromHostController) isExcluded(vObj client.Object) bool {
+	labels := vObj.GetLabels()
+	return labels == nil || labels[controlledByLabel] != f.getControllerID()
+}
+
+func (f *fromHostController) objectMatches(pObj client.Object) bool {
+	return f.selector == nil || f.selector.Matches(labels.Set(pObj.GetLabels()))
+}
+
+// isManaged returns true for all host objects that are not synced from a virtual cluster.
+// The selector is evaluated during the sync, so that virtual objects are removed once the
+// host object doesn't match anymore.
+func (f *fromHostController) isManaged(pObj client.Object) bool {
+	return !translate.IsManaged(pObj) && !isControlled(pObj)
+}
+
+// hostToVirtual translates the name of a host object into the name of the virtual object
+// based on the configured name mapping. An empty name is returned if the host object
+// cannot be mapped into the virtual cluster.
+func (f *fromHostController) hostToVirtual(req types.NamespacedName) types.NamespacedName {
+	// objects that were synced already are found within the name cache
+	virtualName := f.nameCache.GetFirstByIndex(f.gvk, namecache.IndexHostToVirtualName, req.Namespace+"/"+req.Name)
+	if virtualName != "" {
+		return namecache.StringToNamespacedName(virtualName)
+	} else if !f.namespaced {
+		return types.NamespacedName{Name: req.Name}
+	}
+
+	switch f.rewriteName {
+	case config.RewriteNameTypeFromHostToVirtualNamespace:
+		namespace := f.hostToVirtualNamespaces[req.Namespace]
+		if namespace == "" {
+			return types.NamespacedName{}
+		}
+
+		return types.NamespacedName{Namespace: namespace, Name: req.Name}
+	case config.RewriteNameTypeFromVirtualToHostNamespace:
+		if req.Namespace != f.targetNamespace {
+			return types.NamespacedName{}
+		}
+
+		return VirtualNameFromPhysicalName(req.Name)
+	default:
+		if req.Namespace != f.targetNamespace {
+			return types.NamespacedName{}
+		}
+
+		return types.NamespacedName{
+			Namespace: f.config.NameMapping.Namespace,
+			Name:      req.Name,
+		}
+	}
+}
+
+// virtualToHost translates the name of a virtual object into the name of the host object
+// based on the configured name mapping. An empty name is returned if the virtual object
+// cannot be mapped to a host object.
+func (f *fromHostController) virtualToHost(req types.NamespacedName) types.NamespacedName {
+	// objects that were synced already are found within the name cache
+	hostName := f.nameCache.GetFirstByIndex(f.gvk, namecache.IndexVirtualToHostName, req.Namespace+"/"+req.Name)
+	if hostName != "" {
+		return namecache.StringToNamespacedName(hostName)
+	} else if !f.namespaced {
+		return types.NamespacedName{Name: req.Name}
+	}
+
+	switch f.rewriteName {
+	case config.RewriteNameTypeFromHostToVirtualNamespace:
+		namespace := f.virtualToHostNamespaces[req.Namespace]
+		if namespace == "" {
+			return types.NamespacedName{}
+		}
+
+		return types.NamespacedName{Namespace: namespace, Name: req.Name}
+	case config.RewriteNameTypeFromVirtualToHostNamespace:
+		return types.NamespacedName{
+			Namespace: f.targetNamespace,
+			Name:      translate.PhysicalName(req.Name, req.Namespace),
+		}
+	default:
+		if req.Namespace != f.config.NameMapping.Namespace {
+			return types.NamespacedName{}
+		}
+
+		return types.NamespacedName{
+			Namespace: f.targetNamespace,
+			Name:      req.Name,
+		}
+	}
+}
+
+// hostToVirtualNamespace translates a namespace referenced by a host object into the virtual
+// namespace based on the configured name mapping. With FromVirtualToHostNamespace all virtual
+// namespaces share the target namespace, so it is translated into the namespace of the virtual
+// object. An empty namespace is returned if the namespace cannot be mapped.
+func (f *fromHostController) hostToVirtualNamespace(namespace, virtualNamespace string) string {
+	switch f.rewriteName {
+	case config.RewriteNameTypeFromHostToVirtualNamespace:
+		return f.hostToVirtualNamespaces[namespace]
+	case config.RewriteNameTypeFromVirtualToHostNamespace:
+		if namespace != f.targetNamespace {
+			return ""
+		}
+
+		return virtualNamespace
+	default:
+		if namespace != f.targetNamespace {
+			return ""
+		}
+
+		return f.config.NameMapping.Namespace
+	}
+}
+
+// virtualToHostNamespace translates a namespace referenced by a virtual object into the host
+// namespace based on the configured name mapping. An empty namespace is returned if the
+// namespace cannot be mapped.
+func (f *fromHostController) virtualToHostNamespace(namespace string) string {
+	switch f.rewriteName {
+	case config.RewriteNameTypeFromHostToVirtualNamespace:
+		return f.virtualToHostNamespaces[namespace]
+	case config.RewriteNameTypeFromVirtualToHostNamespace:
+		return f.targetNamespace
+	default:
+		if namespace == "" || namespace != f.config.NameMapping.Namespace {
+			return ""
+		}
+
+		return f.targetNamespace
+	}
+}
+
+func (f *fromHostController) hostToVirtualNameResolver(hostNamespace, virtualNamespace string) *fromHostNameResolver {
+	return &fromHostNameResolver{
+		namespace:     hostNamespace,
+		translateFunc: f.hostToVirtual,
+		translateNamespaceFunc: func(namespace string) string {
+			return f.hostToVirtualNamespace(namespace, virtualNamespace)
+		},
+	}
+}
+
+func (f *fromHostController) virtualToHostNameResolver(virtualNamespace string) *fromHostNameResolver {
+	return &fromHostNameResolver{
+		namespace:              virtualNamespace,
+		translateFunc:          f.virtualToHost,
+		translateNamespaceFunc: f.virtualToHostNamespace,
+	}
+}
+
+// VirtualNameFromPhysicalName reverses translate.PhysicalName, which is only possible
+// if the physical name wasn't shortened. As name and namespace might contain the separator
+// as well, the split that translates back into the physical name is used.
+func VirtualNameFromPhysicalName(physicalName string) types.NamespacedName {
+	separator := "-x-"
+	trimmed := strings.TrimSuffix(physicalName, separator+translate.Suffix)
+	if trimmed == physicalName {
+		return types.NamespacedName{}
+	}
+
+	for idx := strings.LastIndex(trimmed, separator); idx > 0; idx = strings.LastIndex(trimmed[:idx], separator) {
+		name, namespace := trimmed[:idx], trimmed[idx+len(separator):]
+		if translate.PhysicalName(name, namespace) == physicalName {
+			return types.NamespacedName{Namespace: namespace, Name: name}
+		}
+	}
+
+	return types.NamespacedName{}
+}
+
+// translateMetadata converts the physical object into a virtual object
+func (f *fromHostController) translateMetadata(pObj client.Object) (client.Object, error) {
+	vNN := f.hostToVirtual(types.NamespacedName{
+		Namespace: pObj.GetNamespace(),
+		Name:      pObj.GetName(),
+	})
+	if vNN.Name == "" {
+		return nil, fmt.Errorf("couldn't translate %s/%s into virtual object", pObj.GetNamespace(), pObj.GetName())
+	}
+
+	newObj := pObj.DeepCopyObject().(client.Object)
+	translator.ResetObjectMetadata(newObj)
+	newObj.SetNamespace(vNN.Namespace)
+	newObj.SetName(vNN.Name)
+
+	// set annotations
+	annotations := newObj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[namecache.HostNameAnnotation] = pObj.GetName()
+	if pObj.GetNamespace() != "" {
+		annotations[namecache.HostNamespaceAnnotation] = pObj.GetNamespace()
+	}
+	newObj.SetAnnotations(annotations)
+
+	// set labels
+	labels := newObj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[controlledByLabel] = f.getControllerID()
+	newObj.SetLabels(labels)
+
+	return newObj, nil
+}
+
+// fromHostNameResolver translates names between host and virtual cluster
+// based on the name mapping of a fromHostCluster mapping. Labels are copied
+// as they are, so label keys and selectors don't need any translation.
+type fromHostNameResolver struct {
+	namespace              string
+	translateFunc          func(req types.NamespacedName) types.NamespacedName
+	translateNamespaceFunc func(namespace string) string
+}
+
+func (r *fromHostNameResolver) TranslateName(name string, regex *regexp.Regexp, _ string) (string, error) {
+	return r.TranslateNameWithNamespace(name, r.namespace, regex, "")
+}
+
+func (r *fromHostNameResolver) TranslateNameWithNamespace(name string, namespace string, regex *regexp.Regexp, _ string) (string, error) {
+	if regex != nil {
+		return patchesregex.ProcessRegex(regex, name, func(name, ns string) types.NamespacedName {
+			// if the regex match doesn't contain namespace - use the namespace set in this resolver
+			if ns == "" {
+				ns = namespace
+			}
+			return r.translateFunc(types.NamespacedName{Namespace: ns, Name: name})
+		}), nil
+	}
+
+	n := r.translateFunc(types.NamespacedName{Namespace: namespace, Name: name})
+	if n.Name == "" {
+		return "", fmt.Errorf("could not translate %s/%s with the configured name mapping", namespace, name)
+	}
+
+	return n.Name, nil
+}
+
+func (r *fromHostNameResolver) TranslateLabelKey(key string) (string, error) {
+	return key, nil
+}
+
+func (r *fromHostNameResolver) TranslateLabelExpressionsSelector(selector *metav1.LabelSelector) (*metav1.LabelSelector, error) {
+	return selector, nil
+}
+
+func (r *fromHostNameResolver) TranslateLabelSelector(selector map[string]string) (map[string]string, error) {
+	return selector, nil
+}
+
+func (r *fromHostNameResolver) TranslateNamespaceRef(namespace string) (string, error) {
+	translated := r.translateNamespaceFunc(namespace)
+	if translated == "" {
+		return "", fmt.Errorf("could not translate namespace %s with the configured name mapping", namespace)
+	}
+
+	return translated, nil
+}
+
+func validateNameMapping(nameMapping config.NameMapping, namespaced bool) (config.RewriteNameType, error) {
+	rewriteName := nameMapping.RewriteName
+	if rewriteName == "" {
+		rewriteName = config.RewriteNameTypeKeepName
+	}
+
+	if !namespaced && rewriteName != config.RewriteNameTypeKeepName {
+		return "", fmt.Errorf("rewriteName %s is not supported for cluster scoped objects", rewriteName)
+	} else if namespaced && rewriteName == config.RewriteNameTypeKeepName && nameMapping.Namespace == "" {
+		return "", fmt.Errorf("namespace is required for namespaced objects")
+	}
+
+	return rewriteName, nil
+}
+
+// hostToVirtualNamespaces returns the configured host namespaces mapped to their virtual namespaces
+func hostToVirtualNamespaces(nameMapping config.NameMapping) map[string]string {
+	namespaces := map[string]string{}
+	for _, namespace := range nameMapping.Namespaces {
+		namespaces[namespace.Host] = namespace.VirtualNamespace()
+	}
+	return namespaces
+}
+
+// virtualToHostNamespaces returns the configured virtual namespaces mapped to their host namespaces
+func virtualToHostNamespaces(nameMapping config.NameMapping) map[string]string {
+	namespaces := map[string]string{}
+	for _, namespace := range nameMapping.Namespaces {
+		namespaces[namespace.VirtualNamespace()] = namespace.Host
+	}
+	return namespaces
+}
+
+func validateFromHostConfig(config *config.FromHostCluster) error {
+	return preparePatchesRegex(append(config.Patches, config.ReversePatches...))
+}
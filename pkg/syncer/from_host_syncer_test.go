@@ -0,0 +1,429 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/config"
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/metrics"
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/namecache"
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/plugin"
+	"github.com/loft-sh/vcluster-sdk/log"
+	"github.com/loft-sh/vcluster-sdk/translate"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var testGVK = schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Certificate"}
+
+// fakeNameCache is a name cache with fixed index contents
+type fakeNameCache struct {
+	// indices maps GVK -> Index -> Lookup Key -> Value
+	indices map[schema.GroupVersionKind]map[string]map[string]string
+	watched map[schema.GroupVersionKind]bool
+}
+
+func (f *fakeNameCache) GetFirstByIndex(gvk schema.GroupVersionKind, index, key string) string {
+	return f.indices[gvk][index][key]
+}
+
+func (f *fakeNameCache) ResolveName(gvk schema.GroupVersionKind, hostName string) types.NamespacedName {
+	return namecache.StringToNamespacedName(f.GetFirstByIndex(gvk, namecache.IndexPhysicalToVirtualName, hostName))
+}
+
+func (f *fakeNameCache) ResolveNamePath(gvk schema.GroupVersionKind, hostName string, path string) types.NamespacedName {
+	return namecache.StringToNamespacedName(f.GetFirstByIndex(gvk, namecache.IndexPhysicalToVirtualNamePath, hostName+"/"+path))
+}
+
+func (f *fakeNameCache) AddChangeHook(schema.GroupVersionKind, string, namecache.HookFunc) {}
+
+func (f *fakeNameCache) Watches(gvk schema.GroupVersionKind) bool {
+	return f.watched[gvk]
+}
+
+func (f *fakeNameCache) ExchangeMapping(schema.GroupVersionKind, *namecache.IndexMappings) {}
+
+func (f *fakeNameCache) RemoveMapping(schema.GroupVersionKind, string) {}
+
+func newTestFromHostController(rewriteName config.RewriteNameType, namespace string, namespaced bool) *fromHostController {
+	nameMapping := config.NameMapping{RewriteName: rewriteName, Namespace: namespace}
+	if rewriteName == config.RewriteNameTypeFromHostToVirtualNamespace {
+		nameMapping.Namespaces = []config.NamespaceMapping{{Host: "other", Virtual: "mapped"}, {Host: "same"}}
+	}
+
+	return &fromHostController{
+		gvk: testGVK,
+		config: &config.FromHostCluster{
+			NameMapping: nameMapping,
+		},
+		nameCache:               &fakeNameCache{},
+		namespaced:              namespaced,
+		rewriteName:             rewriteName,
+		hostToVirtualNamespaces: hostToVirtualNamespaces(nameMapping),
+		virtualToHostNamespaces: virtualToHostNamespaces(nameMapping),
+		targetNamespace:         "vcluster",
+	}
+}
+
+func TestVirtualNameFromPhysicalName(t *testing.T) {
+	testCases := []types.NamespacedName{
+		{Namespace: "default", Name: "test"},
+		{Namespace: "default", Name: "name-x-with-x-separators"},
+		{Namespace: "x", Name: "x"},
+	}
+	for _, expected := range testCases {
+		actual := VirtualNameFromPhysicalName(translate.PhysicalName(expected.Name, expected.Namespace))
+		assert.Equal(t, actual, expected, "translate %s back", expected.String())
+	}
+
+	for _, physicalName := range []string{"", "test", "test-x-default", "test-x-default-x-other"} {
+		assert.Equal(t, VirtualNameFromPhysicalName(physicalName), types.NamespacedName{}, "translate %s back", physicalName)
+	}
+}
+
+func TestFromHostNameMapping(t *testing.T) {
+	type testCase struct {
+		name        string
+		rewriteName config.RewriteNameType
+		namespace   string
+		namespaced  bool
+
+		host    types.NamespacedName
+		virtual types.NamespacedName
+	}
+
+	testCases := []testCase{
+		{
+			name:        "keep name",
+			rewriteName: config.RewriteNameTypeKeepName,
+			namespace:   "synced",
+			namespaced:  true,
+			host:        types.NamespacedName{Namespace: "vcluster", Name: "test"},
+			virtual:     types.NamespacedName{Namespace: "synced", Name: "test"},
+		},
+		{
+			name:        "keep name cluster scoped",
+			rewriteName: config.RewriteNameTypeKeepName,
+			host:        types.NamespacedName{Name: "test"},
+			virtual:     types.NamespacedName{Name: "test"},
+		},
+		{
+			name:        "from virtual to host namespace",
+			rewriteName: config.RewriteNameTypeFromVirtualToHostNamespace,
+			namespaced:  true,
+			host:        types.NamespacedName{Namespace: "vcluster", Name: translate.PhysicalName("test", "default")},
+			virtual:     types.NamespacedName{Namespace: "default", Name: "test"},
+		},
+		{
+			name:        "from host to virtual namespace",
+			rewriteName: config.RewriteNameTypeFromHostToVirtualNamespace,
+			namespaced:  true,
+			host:        types.NamespacedName{Namespace: "other", Name: "test"},
+			virtual:     types.NamespacedName{Namespace: "mapped", Name: "test"},
+		},
+		{
+			name:        "from host to virtual namespace with the same name",
+			rewriteName: config.RewriteNameTypeFromHostToVirtualNamespace,
+			namespaced:  true,
+			host:        types.NamespacedName{Namespace: "same", Name: "test"},
+			virtual:     types.NamespacedName{Namespace: "same", Name: "test"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		f := newTestFromHostController(testCase.rewriteName, testCase.namespace, testCase.namespaced)
+		assert.Equal(t, f.hostToVirtual(testCase.host), testCase.virtual, "host to virtual in test case %s", testCase.name)
+		assert.Equal(t, f.virtualToHost(testCase.virtual), testCase.host, "virtual to host in test case %s", testCase.name)
+	}
+
+	// host objects outside of the mapped namespaces are not mapped
+	f := newTestFromHostController(config.RewriteNameTypeFromHostToVirtualNamespace, "", true)
+	assert.Equal(t, f.hostToVirtual(types.NamespacedName{Namespace: "unmapped", Name: "test"}), types.NamespacedName{})
+	assert.Equal(t, f.virtualToHost(types.NamespacedName{Namespace: "other", Name: "test"}), types.NamespacedName{})
+
+	// host objects outside of the target namespace are not mapped
+	f = newTestFromHostController(config.RewriteNameTypeKeepName, "synced", true)
+	assert.Equal(t, f.hostToVirtual(types.NamespacedName{Namespace: "other", Name: "test"}), types.NamespacedName{})
+	assert.Equal(t, f.virtualToHost(types.NamespacedName{Namespace: "other", Name: "test"}), types.NamespacedName{})
+
+	// names that were synced already are resolved through the name cache
+	f.nameCache = &fakeNameCache{indices: map[schema.GroupVersionKind]map[string]map[string]string{
+		testGVK: {
+			namecache.IndexHostToVirtualName: {"vcluster/host": "synced/virtual"},
+			namecache.IndexVirtualToHostName: {"synced/virtual": "vcluster/host"},
+		},
+	}}
+	assert.Equal(t, f.hostToVirtual(types.NamespacedName{Namespace: "vcluster", Name: "host"}), types.NamespacedName{Namespace: "synced", Name: "virtual"})
+	assert.Equal(t, f.virtualToHost(types.NamespacedName{Namespace: "synced", Name: "virtual"}), types.NamespacedName{Namespace: "vcluster", Name: "host"})
+}
+
+func TestFromHostNameResolverTranslateNamespaceRef(t *testing.T) {
+	type testCase struct {
+		name        string
+		rewriteName config.RewriteNameType
+		namespace   string
+		namespaced  bool
+
+		hostNamespace    string
+		virtualNamespace string
+		expectedErr      string
+	}
+
+	testCases := []testCase{
+		{
+			name:             "keep name",
+			rewriteName:      config.RewriteNameTypeKeepName,
+			namespace:        "synced",
+			namespaced:       true,
+			hostNamespace:    "vcluster",
+			virtualNamespace: "synced",
+		},
+		{
+			name:             "keep name cluster scoped",
+			rewriteName:      config.RewriteNameTypeKeepName,
+			namespace:        "synced",
+			hostNamespace:    "vcluster",
+			virtualNamespace: "synced",
+		},
+		{
+			name:             "keep name cluster scoped without namespace",
+			rewriteName:      config.RewriteNameTypeKeepName,
+			hostNamespace:    "vcluster",
+			virtualNamespace: "default",
+			expectedErr:      "could not translate namespace",
+		},
+		{
+			name:             "from virtual to host namespace",
+			rewriteName:      config.RewriteNameTypeFromVirtualToHostNamespace,
+			namespaced:       true,
+			hostNamespace:    "vcluster",
+			virtualNamespace: "default",
+		},
+		{
+			name:             "from host to virtual namespace",
+			rewriteName:      config.RewriteNameTypeFromHostToVirtualNamespace,
+			namespaced:       true,
+			hostNamespace:    "other",
+			virtualNamespace: "mapped",
+		},
+	}
+
+	for _, testCase := range testCases {
+		f := newTestFromHostController(testCase.rewriteName, testCase.namespace, testCase.namespaced)
+
+		// the virtual object is in the default namespace for all test cases
+		translated, err := f.hostToVirtualNameResolver(testCase.hostNamespace, "default").TranslateNamespaceRef(testCase.hostNamespace)
+		if testCase.expectedErr != "" {
+			assert.ErrorContains(t, err, testCase.expectedErr, "host to virtual in test case %s", testCase.name)
+			continue
+		}
+		assert.NilError(t, err, "host to virtual in test case %s", testCase.name)
+		assert.Equal(t, translated, testCase.virtualNamespace, "host to virtual in test case %s", testCase.name)
+
+		translated, err = f.virtualToHostNameResolver(testCase.virtualNamespace).TranslateNamespaceRef(testCase.virtualNamespace)
+		assert.NilError(t, err, "virtual to host in test case %s", testCase.name)
+		assert.Equal(t, translated, testCase.hostNamespace, "virtual to host in test case %s", testCase.name)
+	}
+
+	// namespaces outside of the target namespace are not mapped
+	f := newTestFromHostController(config.RewriteNameTypeFromVirtualToHostNamespace, "", true)
+	_, err := f.hostToVirtualNameResolver("vcluster", "default").TranslateNamespaceRef("other")
+	assert.ErrorContains(t, err, "could not translate namespace other")
+
+	// namespaces that are not mapped are not translated
+	f = newTestFromHostController(config.RewriteNameTypeFromHostToVirtualNamespace, "", true)
+	_, err = f.hostToVirtualNameResolver("other", "mapped").TranslateNamespaceRef("unmapped")
+	assert.ErrorContains(t, err, "could not translate namespace unmapped")
+}
+
+// applyClient emulates server side apply, which the fake client doesn't support, by creating
+// missing objects and merging the applied fields into existing objects
+type applyClient struct {
+	client.Client
+}
+
+func (c *applyClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch.Type() != types.ApplyPatchType {
+		return c.Client.Patch(ctx, obj, patch, opts...)
+	}
+
+	err := c.Client.Get(ctx, client.ObjectKeyFromObject(obj), obj.DeepCopyObject().(client.Object))
+	if kerrors.IsNotFound(err) {
+		return c.Client.Create(ctx, obj, &client.CreateOptions{DryRun: (&client.PatchOptions{}).ApplyOptions(opts).DryRun})
+	} else if err != nil {
+		return err
+	}
+
+	data, err := patch.Data(obj)
+	if err != nil {
+		return err
+	}
+
+	return c.Client.Patch(ctx, obj, client.RawPatch(types.MergePatchType, data), opts...)
+}
+
+func (c *applyClient) Status() client.StatusWriter {
+	return &applyStatusWriter{StatusWriter: c.Client.Status()}
+}
+
+type applyStatusWriter struct {
+	client.StatusWriter
+}
+
+func (w *applyStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch.Type() == types.ApplyPatchType {
+		data, err := patch.Data(obj)
+		if err != nil {
+			return err
+		}
+
+		patch = client.RawPatch(types.MergePatchType, data)
+	}
+
+	return w.StatusWriter.Patch(ctx, obj, patch, opts...)
+}
+
+// namespacedReader only reads objects of the given namespaces like a multi namespaced cache
+type namespacedReader struct {
+	client.Reader
+	namespaces []string
+}
+
+func (r *namespacedReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	for _, namespace := range r.namespaces {
+		if namespace == key.Namespace {
+			return r.Reader.Get(ctx, key, obj)
+		}
+	}
+
+	return fmt.Errorf("unable to get: %v because of unknown namespace for the cache", key)
+}
+
+// newTestFromHostReconciler returns a controller that syncs ConfigMaps from the host into the virtual cluster
+func newTestFromHostReconciler(rewriteName config.RewriteNameType, namespace string, hostObjects, virtualObjects []client.Object) *fromHostController {
+	f := newTestFromHostController(rewriteName, namespace, true)
+	f.gvk = corev1.SchemeGroupVersion.WithKind("ConfigMap")
+	f.config.TypeInformation = config.TypeInformation{APIVersion: "v1", Kind: "ConfigMap"}
+	f.obj = &unstructured.Unstructured{}
+	f.obj.GetObjectKind().SetGroupVersionKind(f.gvk)
+	f.log = log.New("test")
+	f.metrics = metrics.NewController(metrics.ControllerFromHost, f.gvk, f.getControllerID())
+
+	f.physicalClient = fake.NewClientBuilder().WithObjects(hostObjects...).Build()
+	f.hostReader = f.physicalClient
+	f.virtualClient = &applyClient{Client: fake.NewClientBuilder().WithObjects(virtualObjects...).Build()}
+	f.patcher = &patcher{
+		fromClient: f.physicalClient,
+		toClient:   f.virtualClient,
+		log:        f.log,
+		metrics:    f.metrics,
+	}
+	return f
+}
+
+// reconcileFromHost reconciles the host object and returns the virtual object or nil if it doesn't exist
+func reconcileFromHost(t *testing.T, f *fromHostController, host, virtual types.NamespacedName) *corev1.ConfigMap {
+	_, err := f.Reconcile(context.Background(), ctrl.Request{NamespacedName: host})
+	assert.NilError(t, err)
+
+	vObj := &corev1.ConfigMap{}
+	err = f.virtualClient.Get(context.Background(), virtual, vObj)
+	if kerrors.IsNotFound(err) {
+		return nil
+	}
+	assert.NilError(t, err)
+	return vObj
+}
+
+func TestFromHostReconcileMappedNamespaces(t *testing.T) {
+	f := newTestFromHostReconciler(config.RewriteNameTypeFromHostToVirtualNamespace, "", []client.Object{
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "test"}, Data: map[string]string{"key": "value"}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "vcluster", Name: "test"}},
+	}, nil)
+	f.hostReader = &namespacedReader{Reader: f.physicalClient, namespaces: []string{"other", "same"}}
+
+	// objects of the mapped host namespaces are synced
+	vObj := reconcileFromHost(t, f, types.NamespacedName{Namespace: "other", Name: "test"}, types.NamespacedName{Namespace: "mapped", Name: "test"})
+	assert.Assert(t, vObj != nil)
+	assert.DeepEqual(t, vObj.Data, map[string]string{"key": "value"})
+
+	// objects of the target namespace aren't read from the host cache
+	assert.Assert(t, !f.watchesHostNamespace("vcluster"))
+	vObj = reconcileFromHost(t, f, types.NamespacedName{Namespace: "vcluster", Name: "test"}, types.NamespacedName{Namespace: "vcluster", Name: "test"})
+	assert.Assert(t, vObj == nil)
+}
+
+func TestFromHostReconcile(t *testing.T) {
+	ctx := context.Background()
+	host := types.NamespacedName{Namespace: "vcluster", Name: "test"}
+	virtual := types.NamespacedName{Namespace: "synced", Name: "test"}
+	pObj := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: host.Namespace, Name: host.Name}, Data: map[string]string{"key": "value"}}
+	f := newTestFromHostReconciler(config.RewriteNameTypeKeepName, "synced", []client.Object{pObj}, nil)
+
+	// the virtual object and its namespace are created
+	vObj := reconcileFromHost(t, f, host, virtual)
+	assert.Assert(t, vObj != nil)
+	assert.DeepEqual(t, vObj.Data, map[string]string{"key": "value"})
+	assert.Equal(t, vObj.Labels[controlledByLabel], f.getControllerID())
+	assert.Equal(t, vObj.Annotations[namecache.HostNameAnnotation], host.Name)
+	assert.Equal(t, vObj.Annotations[namecache.HostNamespaceAnnotation], host.Namespace)
+	assert.NilError(t, f.virtualClient.Get(ctx, types.NamespacedName{Name: virtual.Namespace}, &corev1.Namespace{}))
+
+	// changes of the host object are synced
+	pObj.Data["key"] = "changed"
+	assert.NilError(t, f.physicalClient.Update(ctx, pObj))
+	vObj = reconcileFromHost(t, f, host, virtual)
+	assert.Assert(t, vObj != nil)
+	assert.DeepEqual(t, vObj.Data, map[string]string{"key": "changed"})
+
+	// the virtual object is deleted with the host object
+	assert.NilError(t, f.physicalClient.Delete(ctx, pObj))
+	vObj = reconcileFromHost(t, f, host, virtual)
+	assert.Assert(t, vObj == nil)
+}
+
+func TestFromHostReconcileOrphaned(t *testing.T) {
+	host := types.NamespacedName{Namespace: "vcluster", Name: "test"}
+	virtual := types.NamespacedName{Namespace: "synced", Name: "test"}
+	controlled := map[string]string{controlledByLabel: plugin.GetPluginName()}
+
+	// virtual objects without host object are deleted
+	f := newTestFromHostReconciler(config.RewriteNameTypeKeepName, "synced", nil, []client.Object{
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: virtual.Namespace, Name: virtual.Name, Labels: controlled}},
+	})
+	assert.Assert(t, reconcileFromHost(t, f, host, virtual) == nil)
+
+	// virtual objects that weren't created by the mapping are kept
+	f = newTestFromHostReconciler(config.RewriteNameTypeKeepName, "synced", nil, []client.Object{
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: virtual.Namespace, Name: virtual.Name}},
+	})
+	assert.Assert(t, reconcileFromHost(t, f, host, virtual) != nil)
+
+	// virtual objects of host objects that aren't selected anymore are deleted
+	f = newTestFromHostReconciler(config.RewriteNameTypeKeepName, "synced", []client.Object{
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: host.Namespace, Name: host.Name}},
+	}, []client.Object{
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: virtual.Namespace, Name: virtual.Name, Labels: controlled}},
+	})
+	f.selector = labels.SelectorFromSet(labels.Set{"sync": "true"})
+	assert.Assert(t, reconcileFromHost(t, f, host, virtual) == nil)
+
+	// host objects that aren't selected aren't synced
+	assert.Assert(t, reconcileFromHost(t, f, host, virtual) == nil)
+
+	// host objects that were synced from a virtual cluster aren't synced back
+	f = newTestFromHostReconciler(config.RewriteNameTypeKeepName, "synced", []client.Object{
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: host.Namespace, Name: host.Name, Labels: map[string]string{translate.MarkerLabel: translate.Suffix}}},
+	}, nil)
+	assert.Assert(t, reconcileFromHost(t, f, host, virtual) == nil)
+}
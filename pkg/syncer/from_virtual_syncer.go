@@ -3,9 +3,12 @@ package syncer
 import (
 	"fmt"
 	"regexp"
+	"time"
 
 	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/config"
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/metrics"
 	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/namecache"
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/patches"
 	patchesregex "github.com/loft-sh/vcluster-generic-crd-plugin/pkg/patches/regex"
 	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/plugin"
 	"github.com/loft-sh/vcluster-sdk/log"
@@ -24,7 +27,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
-func CreateFromVirtualSyncer(ctx *synccontext.RegisterContext, config *config.FromVirtualCluster, nc namecache.NameCache) (syncer.Syncer, error) {
+func CreateFromVirtualSyncer(ctx *synccontext.RegisterContext, config *config.FromVirtualCluster, nc namecache.NameCache, dryRun bool) (syncer.Syncer, error) {
 	obj := &unstructured.Unstructured{}
 	obj.SetKind(config.Kind)
 	obj.SetAPIVersion(config.APIVersion)
@@ -42,23 +45,29 @@ func CreateFromVirtualSyncer(ctx *synccontext.RegisterContext, config *config.Fr
 		}
 	}
 
-	statusIsSubresource := true
-	// TODO: [low priority] check if config.Kind + config.APIVersion has status subresource
+	statusIsSubresource, err := hasStatusSubresource(ctx, schema.FromAPIVersionAndKind(config.APIVersion, config.Kind), config.StatusSubresource)
+	if err != nil {
+		return nil, fmt.Errorf("check status subresource of %s(%s): %v", config.Kind, config.APIVersion, err)
+	}
 
-	return &fromVirtualController{
-		NamespacedTranslator: translator.NewNamespacedTranslator(ctx, config.Kind+"-from-virtual-syncer", obj),
+	f := &fromVirtualController{
+		NamespacedTranslator: translator.NewNamespacedTranslator(ctx, config.Kind+"-from-virtual-syncer", obj, SyncStatusAnnotation, TracePatchesAnnotation),
 		patcher: &patcher{
 			fromClient:          ctx.VirtualManager.GetClient(),
 			toClient:            ctx.PhysicalManager.GetClient(),
 			statusIsSubresource: statusIsSubresource,
 			log:                 log.New(config.Kind + "-from-virtual-syncer"),
+			dryRun:              dryRun,
 		},
 		gvk:             schema.FromAPIVersionAndKind(config.APIVersion, config.Kind),
 		config:          config,
 		nameCache:       nc,
 		selector:        selector,
 		targetNamespace: ctx.TargetNamespace,
-	}, nil
+	}
+	f.metrics = metrics.NewController(metrics.ControllerFromVirtual, f.gvk, f.getControllerID())
+	f.patcher.metrics = f.metrics
+	return f, nil
 }
 
 type fromVirtualController struct {
@@ -71,9 +80,31 @@ type fromVirtualController struct {
 	nameCache       namecache.NameCache
 	selector        labels.Selector
 	targetNamespace string
+
+	metrics *metrics.Controller
 }
 
 func (f *fromVirtualController) SyncDown(ctx *synccontext.SyncContext, vObj client.Object) (ctrl.Result, error) {
+	start := time.Now()
+	result, err := f.syncDown(ctx, vObj)
+	f.metrics.ObserveReconcile(start, result, err)
+	if !isControlled(vObj) && f.objectMatches(vObj) {
+		f.patcher.updateSyncStatus(ctx, vObj, types.NamespacedName{Namespace: f.targetNamespace, Name: translate.PhysicalName(vObj.GetName(), vObj.GetNamespace())}, err)
+	}
+	return result, err
+}
+
+func (f *fromVirtualController) Sync(ctx *synccontext.SyncContext, pObj client.Object, vObj client.Object) (ctrl.Result, error) {
+	start := time.Now()
+	result, err := f.sync(ctx, pObj, vObj)
+	f.metrics.ObserveReconcile(start, result, err)
+	if !isControlled(vObj) && !f.isExcluded(pObj) && f.objectMatches(vObj) {
+		f.patcher.updateSyncStatus(ctx, vObj, types.NamespacedName{Namespace: pObj.GetNamespace(), Name: pObj.GetName()}, err)
+	}
+	return result, err
+}
+
+func (f *fromVirtualController) syncDown(ctx *synccontext.SyncContext, vObj client.Object) (ctrl.Result, error) {
 	// check if selector matches
 	if isControlled(vObj) || !f.objectMatches(vObj) {
 		return ctrl.Result{}, nil
@@ -83,7 +114,7 @@ func (f *fromVirtualController) SyncDown(ctx *synccontext.SyncContext, vObj clie
 	ctx.Log.Infof("Create physical %s %s/%s, since it is missing, but virtual object exists", f.config.Kind, vObj.GetNamespace(), vObj.GetName())
 	_, err := f.patcher.ApplyPatches(ctx.Context, vObj, nil, f.config.Patches, f.config.ReversePatches, func(vObj client.Object) (client.Object, error) {
 		return f.TranslateMetadata(vObj), nil
-	}, &virtualToHostNameResolver{namespace: vObj.GetNamespace(), targetNamespace: f.targetNamespace})
+	}, &virtualToHostNameResolver{namespace: vObj.GetNamespace(), targetNamespace: f.targetNamespace}, f.templateContext(vObj.GetNamespace()))
 	if err != nil {
 		f.EventRecorder().Eventf(vObj, "Warning", "SyncError", "Error syncing to physical cluster: %v", err)
 		return ctrl.Result{}, fmt.Errorf("error applying patches: %v", err)
@@ -96,12 +127,12 @@ func (f *fromVirtualController) isExcluded(pObj client.Object) bool {
 	return labels == nil || labels[controlledByLabel] != f.getControllerID()
 }
 
-func (f *fromVirtualController) Sync(ctx *synccontext.SyncContext, pObj client.Object, vObj client.Object) (ctrl.Result, error) {
+func (f *fromVirtualController) sync(ctx *synccontext.SyncContext, pObj client.Object, vObj client.Object) (ctrl.Result, error) {
 	if isControlled(vObj) || f.isExcluded(pObj) {
 		return ctrl.Result{}, nil
 	} else if !f.objectMatches(vObj) {
 		ctx.Log.Infof("delete physical %s %s/%s, because it is not used anymore", f.config.Kind, pObj.GetNamespace(), pObj.GetName())
-		err := ctx.PhysicalClient.Delete(ctx.Context, pObj)
+		err := f.patcher.Delete(ctx.Context, ctx.PhysicalClient, pObj)
 		if err != nil {
 			ctx.Log.Infof("error deleting physical %s %s/%s in physical cluster: %v", f.config.Kind, pObj.GetNamespace(), pObj.GetName(), err)
 			return ctrl.Result{}, err
@@ -111,12 +142,13 @@ func (f *fromVirtualController) Sync(ctx *synccontext.SyncContext, pObj client.O
 	}
 
 	// apply reverse patches
-	result, err := f.patcher.ApplyReversePatches(ctx.Context, vObj, pObj, f.config.ReversePatches, &hostToVirtualNameResolver{nameCache: f.nameCache, gvk: f.gvk})
+	result, err := f.patcher.ApplyReversePatches(ctx.Context, vObj, pObj, f.config.ReversePatches, &hostToVirtualNameResolver{HostToVirtualTranslator: NewHostToVirtualTranslator(vObj.GetNamespace(), f.targetNamespace, vObj), nameCache: f.nameCache, gvk: f.gvk}, f.templateContext(vObj.GetNamespace()))
 	if err != nil {
 		if kerrors.IsInvalid(err) {
 			ctx.Log.Infof("Warning: this message could indicate a timing issue with no significant impact, or a bug. Please report this if your resource never reaches the expected state. Error message: failed to patch virtual %s %s/%s: %v", f.config.Kind, vObj.GetNamespace(), vObj.GetName(), err)
 			// this happens when some field is being removed shortly after being added, which suggest it's a timing issue
 			// it doesn't seem to have any negative consequence besides the logged error message
+			f.metrics.InvalidRequeue()
 			return ctrl.Result{Requeue: true}, nil
 		}
 
@@ -131,12 +163,13 @@ func (f *fromVirtualController) Sync(ctx *synccontext.SyncContext, pObj client.O
 	// apply patches
 	_, err = f.patcher.ApplyPatches(ctx.Context, vObj, pObj, f.config.Patches, f.config.ReversePatches, func(vObj client.Object) (client.Object, error) {
 		return f.TranslateMetadata(vObj), nil
-	}, &virtualToHostNameResolver{namespace: vObj.GetNamespace(), targetNamespace: f.targetNamespace})
+	}, &virtualToHostNameResolver{namespace: vObj.GetNamespace(), targetNamespace: f.targetNamespace}, f.templateContext(vObj.GetNamespace()))
 	if err != nil {
 		if kerrors.IsInvalid(err) {
 			ctx.Log.Infof("Warning: this message could indicate a timing issue with no significant impact, or a bug. Please report this if your resource never reaches the expected state. Error message: failed to patch physical %s %s/%s: %v", f.config.Kind, vObj.GetNamespace(), vObj.GetName(), err)
 			// this happens when some field is being removed shortly after being added, which suggest it's a timing issue
 			// it doesn't seem to have any negative consequence besides the logged error message
+			f.metrics.InvalidRequeue()
 			return ctrl.Result{Requeue: true}, nil
 		}
 
@@ -150,12 +183,35 @@ func (f *fromVirtualController) Sync(ctx *synccontext.SyncContext, pObj client.O
 var _ syncer.UpSyncer = &fromVirtualController{}
 
 func (f *fromVirtualController) SyncUp(ctx *synccontext.SyncContext, pObj client.Object) (ctrl.Result, error) {
+	start := time.Now()
+	result, err := f.syncUp(ctx, pObj)
+	f.metrics.ObserveReconcile(start, result, err)
+	return result, err
+}
+
+func (f *fromVirtualController) syncUp(ctx *synccontext.SyncContext, pObj client.Object) (ctrl.Result, error) {
 	if !translate.IsManaged(pObj) || f.isExcluded(pObj) {
 		return ctrl.Result{}, nil
 	}
 
 	// delete physical object because virtual one is missing
-	return syncer.DeleteObject(ctx, pObj)
+	ctx.Log.Infof("delete physical %s %s/%s, because virtual object was deleted", f.config.Kind, pObj.GetNamespace(), pObj.GetName())
+	err := f.patcher.Delete(ctx.Context, ctx.PhysicalClient, pObj)
+	if err != nil && !kerrors.IsNotFound(err) {
+		ctx.Log.Infof("error deleting physical %s %s/%s in physical cluster: %v", f.config.Kind, pObj.GetNamespace(), pObj.GetName(), err)
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+func (f *fromVirtualController) templateContext(virtualNamespace string) *patches.TemplateContext {
+	return &patches.TemplateContext{
+		VirtualNamespace: virtualNamespace,
+		HostNamespace:    f.targetNamespace,
+		PluginName:       plugin.GetPluginName(),
+		ControllerID:     f.getControllerID(),
+	}
 }
 
 func (f *fromVirtualController) getControllerID() string {
@@ -193,6 +249,12 @@ func (f *fromVirtualController) objectMatches(obj client.Object) bool {
 	return f.selector == nil || !f.selector.Matches(labels.Set(obj.GetLabels()))
 }
 
+// NewVirtualToHostNameResolver returns the name resolver that is used to translate names
+// of virtual objects in the given namespace into names of host objects
+func NewVirtualToHostNameResolver(namespace, targetNamespace string) patches.NameResolver {
+	return &virtualToHostNameResolver{namespace: namespace, targetNamespace: targetNamespace}
+}
+
 type virtualToHostNameResolver struct {
 	namespace       string
 	targetNamespace string
@@ -260,8 +322,14 @@ func (r *virtualToHostNameResolver) TranslateNamespaceRef(namespace string) (str
 }
 
 type hostToVirtualNameResolver struct {
+	HostToVirtualTranslator
+
 	gvk schema.GroupVersionKind
 
+	// byName resolves the names of the objects themselves instead of the references at
+	// the patch path, as the objects of a patch target are indexed under their own paths
+	byName bool
+
 	nameCache namecache.NameCache
 }
 
@@ -269,18 +337,10 @@ func (r *hostToVirtualNameResolver) TranslateName(name string, regex *regexp.Reg
 	var n types.NamespacedName
 	if regex != nil {
 		return patchesregex.ProcessRegex(regex, name, func(name, namespace string) types.NamespacedName {
-			if path == "" {
-				return r.nameCache.ResolveName(r.gvk, name)
-			} else {
-				return r.nameCache.ResolveNamePath(r.gvk, name, path)
-			}
+			return r.resolve(name, path)
 		}), nil
 	} else {
-		if path == "" {
-			n = r.nameCache.ResolveName(r.gvk, name)
-		} else {
-			n = r.nameCache.ResolveNamePath(r.gvk, name, path)
-		}
+		n = r.resolve(name, path)
 	}
 	if n.Name == "" {
 		return "", fmt.Errorf("could not translate %s host resource name to vcluster resource name", name)
@@ -288,24 +348,63 @@ func (r *hostToVirtualNameResolver) TranslateName(name string, regex *regexp.Reg
 
 	return n.Name, nil
 }
+
 func (r *hostToVirtualNameResolver) TranslateNameWithNamespace(name string, namespace string, regex *regexp.Regexp, path string) (string, error) {
-	return "", fmt.Errorf("translation not supported from host to virtual object")
-}
-func (r *hostToVirtualNameResolver) TranslateLabelKey(key string) (string, error) {
-	return "", fmt.Errorf("translation not supported from host to virtual object")
+	if regex != nil {
+		return r.TranslateName(name, regex, path)
+	}
+
+	n := r.resolve(name, path)
+	if n.Name == "" {
+		return "", fmt.Errorf("could not translate %s/%s host resource name to vcluster resource name", namespace, name)
+	}
+
+	err := r.ValidateResolvedNamespace(name, namespace, n.Namespace)
+	if err != nil {
+		return "", err
+	}
+
+	return n.Name, nil
 }
-func (r *hostToVirtualNameResolver) TranslateLabelExpressionsSelector(selector *metav1.LabelSelector) (*metav1.LabelSelector, error) {
-	return nil, fmt.Errorf("translation not supported from host to virtual object")
+
+func (r *hostToVirtualNameResolver) TranslateNamespacedName(name string, namespace string, path string) (types.NamespacedName, error) {
+	err := r.ValidateHostNamespace(name, namespace)
+	if err != nil {
+		return types.NamespacedName{}, err
+	}
+
+	n := r.resolve(name, path)
+	if n.Name == "" {
+		return types.NamespacedName{}, fmt.Errorf("could not translate %s/%s host resource name to vcluster resource name", namespace, name)
+	}
+
+	return n, nil
 }
-func (r *hostToVirtualNameResolver) TranslateLabelSelector(selector map[string]string) (map[string]string, error) {
-	return nil, fmt.Errorf("translation not supported from host to virtual object")
+
+func (r *hostToVirtualNameResolver) ForTarget(gvk schema.GroupVersionKind) (patches.NameResolver, error) {
+	if !r.nameCache.Watches(gvk) {
+		return NewPhysicalNameResolver(r.HostToVirtualTranslator), nil
+	}
+
+	return &hostToVirtualNameResolver{HostToVirtualTranslator: r.HostToVirtualTranslator, gvk: gvk, byName: true, nameCache: r.nameCache}, nil
 }
-func (r *hostToVirtualNameResolver) TranslateNamespaceRef(namespace string) (string, error) {
-	return "", fmt.Errorf("translation not supported from host to virtual object")
+
+func (r *hostToVirtualNameResolver) resolve(name, path string) types.NamespacedName {
+	if r.byName {
+		return r.nameCache.ResolveNamePath(r.gvk, name, namecache.MetadataFieldPath)
+	} else if path == "" {
+		return r.nameCache.ResolveName(r.gvk, name)
+	}
+
+	return r.nameCache.ResolveNamePath(r.gvk, name, path)
 }
 
 func validateFromVirtualConfig(config *config.FromVirtualCluster) error {
-	for _, p := range append(config.Patches, config.ReversePatches...) {
+	return preparePatchesRegex(append(config.Patches, config.ReversePatches...))
+}
+
+func preparePatchesRegex(patches []*config.Patch) error {
+	for _, p := range patches {
 		if p.Regex != "" {
 			parsed, err := patchesregex.PrepareRegex(p.Regex)
 			if err != nil {
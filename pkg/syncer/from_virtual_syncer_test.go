@@ -0,0 +1,76 @@
+package syncer
+
+import (
+	"testing"
+
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/config"
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/namecache"
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/patches"
+	"github.com/loft-sh/vcluster-sdk/translate"
+	"gotest.tools/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var requestGVK = schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "CertificateRequest"}
+
+func TestHostToVirtualNameResolverForTarget(t *testing.T) {
+	// the certificate request mapping indexes its own name and an issuer reference, as
+	// the name cache handler of a fromVirtualCluster mapping would
+	requestHostName := translate.PhysicalName("request", "default")
+	issuerHostName := translate.PhysicalName("issuer", "default")
+	certificateHostName := translate.PhysicalName("certificate", "default")
+	nc := &fakeNameCache{
+		indices: map[schema.GroupVersionKind]map[string]map[string]string{
+			testGVK: {
+				namecache.IndexPhysicalToVirtualNamePath: {
+					certificateHostName + "/" + namecache.MetadataFieldPath: "default/certificate",
+				},
+			},
+			requestGVK: {
+				namecache.IndexPhysicalToVirtualNamePath: {
+					requestHostName + "/" + namecache.MetadataFieldPath: "default/request",
+					issuerHostName + "/spec.issuerRef.name":             "default/issuer",
+				},
+			},
+		},
+		watched: map[schema.GroupVersionKind]bool{testGVK: true, requestGVK: true},
+	}
+	resolver := &hostToVirtualNameResolver{
+		HostToVirtualTranslator: NewHostToVirtualTranslator("default", "vcluster"),
+		gvk:                     testGVK,
+		nameCache:               nc,
+	}
+
+	// the path of the patch differs from all paths of the target mapping
+	patch := &config.Patch{
+		Operation: config.PatchTypeRewriteName,
+		Path:      "status.requestName",
+		Target:    &config.TypeInformation{APIVersion: requestGVK.GroupVersion().String(), Kind: requestGVK.Kind},
+	}
+	applyPatch := func(requestName string) (string, error) {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(testGVK)
+		err := unstructured.SetNestedField(obj.Object, requestName, "status", "requestName")
+		assert.NilError(t, err)
+
+		err = patches.ApplyPatches(obj, nil, []*config.Patch{patch}, nil, resolver, nil)
+		if err != nil {
+			return "", err
+		}
+
+		requestName, _, err = unstructured.NestedString(obj.Object, "status", "requestName")
+		assert.NilError(t, err)
+		return requestName, nil
+	}
+
+	requestName, err := applyPatch(requestHostName)
+	assert.NilError(t, err)
+	assert.Equal(t, requestName, "request")
+
+	// names of other kinds and references within the target objects are not resolved
+	_, err = applyPatch(certificateHostName)
+	assert.ErrorContains(t, err, "could not translate")
+	_, err = applyPatch(issuerHostName)
+	assert.ErrorContains(t, err, "could not translate")
+}
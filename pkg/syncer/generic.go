@@ -3,13 +3,23 @@ package syncer
 import (
 	"context"
 	"fmt"
+	"strings"
+
 	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/config"
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/metrics"
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/namecache"
 	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/patches"
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/util/diff"
 	"github.com/loft-sh/vcluster-sdk/log"
+	synccontext "github.com/loft-sh/vcluster-sdk/syncer/context"
 	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
@@ -17,7 +27,7 @@ import (
 var (
 	fieldManager = "vcluster-syncer"
 
-	controlledByLabel = "vcluster.loft.sh/controlled-by"
+	controlledByLabel = namecache.ControlledByLabel
 )
 
 type patcher struct {
@@ -26,9 +36,13 @@ type patcher struct {
 
 	statusIsSubresource bool
 	log                 log.Logger
+	metrics             *metrics.Controller
+
+	// dryRun only runs the writes as server side dry run and logs the resulting changes
+	dryRun bool
 }
 
-func (s *patcher) ApplyPatches(ctx context.Context, fromObj, toObj client.Object, patchesConfig, reversePatchesConfig []*config.Patch, translateMetadata func(vObj client.Object) (client.Object, error), nameResolver patches.NameResolver) (client.Object, error) {
+func (s *patcher) ApplyPatches(ctx context.Context, fromObj, toObj client.Object, patchesConfig, reversePatchesConfig []*config.Patch, translateMetadata func(vObj client.Object) (client.Object, error), nameResolver patches.NameResolver, templateContext *patches.TemplateContext) (client.Object, error) {
 	translatedObject, err := translateMetadata(fromObj)
 	if err != nil {
 		return nil, errors.Wrap(err, "translate object")
@@ -41,28 +55,24 @@ func (s *patcher) ApplyPatches(ctx context.Context, fromObj, toObj client.Object
 	toObjCopied := toObjBase.DeepCopy()
 
 	// apply patches on from object
-	err = patches.ApplyPatches(toObjCopied, toObj, patchesConfig, reversePatchesConfig, nameResolver)
+	trace := newPatchTrace(fromObj, toObj)
+	err = patches.ApplyPatchesWithTrace(toObjCopied, toObj, patchesConfig, reversePatchesConfig, nameResolver, templateContext, trace)
+	s.logPatchTrace(trace, toObjCopied)
 	if err != nil {
+		s.recordPatchFailure(err)
 		return nil, fmt.Errorf("error applying patches: %v", err)
 	}
 
-	// compare status
+	// split off status, as it is applied separately through the subresource
+	var statusObject *unstructured.Unstructured
 	if s.statusIsSubresource {
 		_, hasAfterStatus, err := unstructured.NestedFieldCopy(toObjCopied.Object, "status")
 		if err != nil {
 			return nil, err
 		}
 
-		// always apply status if it's there
-		if hasAfterStatus {
-			s.log.Infof("Apply status of %s during patching", toObjCopied.GetName())
-			err = s.toClient.Status().Patch(ctx, toObjCopied.DeepCopy(), client.Apply, client.ForceOwnership, client.FieldOwner(fieldManager))
-			if err != nil {
-				return nil, errors.Wrap(err, "apply status")
-			}
-		}
-
 		if hasAfterStatus {
+			statusObject = toObjCopied.DeepCopy()
 			unstructured.RemoveNestedField(toObjCopied.Object, "status")
 		}
 	}
@@ -70,15 +80,38 @@ func (s *patcher) ApplyPatches(ctx context.Context, fromObj, toObj client.Object
 	// always apply object
 	s.log.Infof("Apply %s during patching", toObjCopied.GetName())
 	outObject := toObjCopied.DeepCopy()
-	err = s.toClient.Patch(ctx, outObject, client.Apply, client.ForceOwnership, client.FieldOwner(fieldManager))
+	err = s.toClient.Patch(ctx, outObject, client.Apply, s.patchOptions(client.ForceOwnership, client.FieldOwner(fieldManager))...)
 	if err != nil {
 		return nil, errors.Wrap(err, "apply object")
 	}
+	if s.dryRun {
+		s.logDryRun("apply", toObj, outObject)
+	}
+
+	// always apply status if it's there, this needs to happen after the object
+	// was applied, because the status of a missing object cannot be applied
+	if statusObject != nil {
+		if s.dryRun && toObj == nil {
+			s.logDryRun("skip status of missing object", nil, statusObject)
+			return outObject, nil
+		}
+
+		s.log.Infof("Apply status of %s during patching", statusObject.GetName())
+		err = s.toClient.Status().Patch(ctx, statusObject, client.Apply, s.patchOptions(client.ForceOwnership, client.FieldOwner(fieldManager))...)
+		if err != nil {
+			return nil, errors.Wrap(err, "apply status")
+		}
+		if s.dryRun {
+			s.logDryRun("apply status", toObj, statusObject)
+		}
+
+		return statusObject, nil
+	}
 
 	return outObject, nil
 }
 
-func (s *patcher) ApplyReversePatches(ctx context.Context, fromObj, otherObj client.Object, reversePatchConfig []*config.Patch, nameResolver patches.NameResolver) (controllerutil.OperationResult, error) {
+func (s *patcher) ApplyReversePatches(ctx context.Context, fromObj, otherObj client.Object, reversePatchConfig []*config.Patch, nameResolver patches.NameResolver, templateContext *patches.TemplateContext) (controllerutil.OperationResult, error) {
 	originalUnstructured, err := toUnstructured(fromObj)
 	if err != nil {
 		return controllerutil.OperationResultNone, err
@@ -86,8 +119,11 @@ func (s *patcher) ApplyReversePatches(ctx context.Context, fromObj, otherObj cli
 	fromCopied := originalUnstructured.DeepCopy()
 
 	// apply patches on from object
-	err = patches.ApplyPatches(fromCopied, otherObj, reversePatchConfig, nil, nameResolver)
+	trace := newPatchTrace(fromObj, otherObj)
+	err = patches.ApplyPatchesWithTrace(fromCopied, otherObj, reversePatchConfig, nil, nameResolver, templateContext, trace)
+	s.logPatchTrace(trace, fromCopied)
 	if err != nil {
+		s.recordPatchFailure(err)
 		return controllerutil.OperationResultNone, fmt.Errorf("error applying reverse patches: %v", err)
 	}
 
@@ -105,12 +141,18 @@ func (s *patcher) ApplyReversePatches(ctx context.Context, fromObj, otherObj cli
 		// update status
 		if (hasBeforeStatus || hasAfterStatus) && !equality.Semantic.DeepEqual(beforeStatus, afterStatus) {
 			s.log.Infof("Update status of %s during reverse patching", fromCopied.GetName())
-			err = s.fromClient.Status().Update(ctx, fromCopied)
+			statusObject := fromCopied.DeepCopy()
+			err = s.fromClient.Status().Update(ctx, statusObject, s.updateOptions()...)
 			if err != nil {
+				s.recordUpdateConflict(err)
 				return controllerutil.OperationResultNone, errors.Wrap(err, "update reverse status")
+			} else if !s.dryRun {
+				return controllerutil.OperationResultUpdatedStatusOnly, nil
 			}
 
-			return controllerutil.OperationResultUpdatedStatusOnly, nil
+			// the status update was only dry run and won't trigger another reconcile,
+			// so the rest of the object is compared right away
+			s.logDryRun("update status", fromObj, statusObject)
 		}
 
 		if hasBeforeStatus {
@@ -124,9 +166,13 @@ func (s *patcher) ApplyReversePatches(ctx context.Context, fromObj, otherObj cli
 	// compare rest of the object
 	if !equality.Semantic.DeepEqual(originalUnstructured, fromCopied) {
 		s.log.Infof("Update %s during reverse patching", fromCopied.GetName())
-		err = s.fromClient.Update(ctx, fromCopied)
+		err = s.fromClient.Update(ctx, fromCopied, s.updateOptions()...)
 		if err != nil {
+			s.recordUpdateConflict(err)
 			return controllerutil.OperationResultNone, errors.Wrap(err, "update reverse")
+		} else if s.dryRun {
+			s.logDryRun("update", fromObj, fromCopied)
+			return controllerutil.OperationResultNone, nil
 		}
 
 		return controllerutil.OperationResultUpdated, nil
@@ -135,6 +181,148 @@ func (s *patcher) ApplyReversePatches(ctx context.Context, fromObj, otherObj cli
 	return controllerutil.OperationResultNone, nil
 }
 
+// Delete deletes the object through the given client, which is only dry run in dry run mode
+func (s *patcher) Delete(ctx context.Context, c client.Client, obj client.Object) error {
+	if !s.dryRun {
+		return c.Delete(ctx, obj)
+	}
+
+	err := c.Delete(ctx, obj, client.DryRunAll)
+	if err != nil {
+		return err
+	}
+
+	s.logDryRun("delete", obj, nil)
+	return nil
+}
+
+// Create creates the object through the given client, which is only dry run in dry run mode
+func (s *patcher) Create(ctx context.Context, c client.Client, obj client.Object) error {
+	if !s.dryRun {
+		return c.Create(ctx, obj)
+	}
+
+	err := c.Create(ctx, obj, client.DryRunAll)
+	if err != nil {
+		return err
+	}
+
+	s.logDryRun("create", nil, obj)
+	return nil
+}
+
+// Patch patches the object through the given client, which is only dry run in dry run mode.
+// The original object is the object before it was changed and is only used for the dry run diff.
+func (s *patcher) Patch(ctx context.Context, c client.Client, originalObj, obj client.Object, patch client.Patch) error {
+	if !s.dryRun {
+		return c.Patch(ctx, obj, patch)
+	}
+
+	err := c.Patch(ctx, obj, patch, client.DryRunAll)
+	if err != nil {
+		return err
+	}
+
+	s.logDryRun("patch", originalObj, obj)
+	return nil
+}
+
+func (s *patcher) patchOptions(opts ...client.PatchOption) []client.PatchOption {
+	if s.dryRun {
+		opts = append(opts, client.DryRunAll)
+	}
+
+	return opts
+}
+
+func (s *patcher) updateOptions() []client.UpdateOption {
+	if s.dryRun {
+		return []client.UpdateOption{client.DryRunAll}
+	}
+
+	return nil
+}
+
+// logDryRun logs the decision about a write that was skipped or only dry run, together with the
+// diff of the object before and after the write. Before is nil for creates and after for deletes.
+func (s *patcher) logDryRun(decision string, before, after client.Object) {
+	obj := after
+	if obj == nil {
+		obj = before
+	}
+
+	objectDiff, err := diff.Objects(before, after)
+	if err != nil {
+		s.log.Infof("error computing dry run diff of %s/%s: %v", obj.GetNamespace(), obj.GetName(), err)
+	}
+
+	s.log.Base().Info("dry run", "decision", decision, "kind", obj.GetObjectKind().GroupVersionKind().Kind, "namespace", obj.GetNamespace(), "name", obj.GetName(), "diff", objectDiff)
+}
+
+// logDryRunSkip logs an object that is not changed in dry run mode, because it depends on a
+// change that was only dry run
+func (s *patcher) logDryRunSkip(kind string, name types.NamespacedName, reason string) {
+	s.log.Base().Info("dry run", "decision", "skip", "kind", kind, "namespace", name.Namespace, "name", name.Name, "reason", reason)
+}
+
+// recordPatchFailure counts the failed patch by its operation
+func (s *patcher) recordPatchFailure(err error) {
+	patchErr := &patches.PatchError{}
+	if errors.As(err, &patchErr) {
+		s.metrics.PatchFailed(string(patchErr.Operation))
+	}
+}
+
+// recordUpdateConflict counts updates that failed, because the object was changed in the meantime.
+// Applies are not counted, as they force the ownership and never conflict.
+func (s *patcher) recordUpdateConflict(err error) {
+	if kerrors.IsConflict(err) {
+		s.metrics.UpdateConflict()
+	}
+}
+
+// hasStatusSubresource returns if the status of the given kind is a subresource. The override
+// from the configuration is used if set, otherwise this is looked up via discovery in the host cluster.
+func hasStatusSubresource(ctx *synccontext.RegisterContext, gvk schema.GroupVersionKind, override *bool) (bool, error) {
+	if override != nil {
+		return *override, nil
+	}
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(ctx.PhysicalManager.GetConfig())
+	if err != nil {
+		return false, errors.Wrap(err, "create discovery client")
+	}
+
+	return discoverStatusSubresource(discoveryClient, gvk)
+}
+
+// discoverStatusSubresource looks up if the resource of the given kind has a status subresource
+func discoverStatusSubresource(discoveryClient discovery.ServerResourcesInterface, gvk schema.GroupVersionKind) (bool, error) {
+	resources, err := discoveryClient.ServerResourcesForGroupVersion(gvk.GroupVersion().String())
+	if err != nil {
+		return false, errors.Wrapf(err, "discover resources of %s", gvk.GroupVersion().String())
+	}
+
+	resource := ""
+	for _, r := range resources.APIResources {
+		if r.Kind == gvk.Kind && !strings.Contains(r.Name, "/") {
+			resource = r.Name
+			break
+		}
+	}
+	if resource == "" {
+		return false, fmt.Errorf("couldn't find kind %s in %s", gvk.Kind, gvk.GroupVersion().String())
+	}
+
+	for _, r := range resources.APIResources {
+		if r.Name == resource+"/status" {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
 func toUnstructured(obj client.Object) (*unstructured.Unstructured, error) {
 	fromCopied, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj.DeepCopyObject())
 	if err != nil {
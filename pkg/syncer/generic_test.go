@@ -0,0 +1,266 @@
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/go-logr/logr/funcr"
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/config"
+	"github.com/loft-sh/vcluster-sdk/log"
+	synccontext "github.com/loft-sh/vcluster-sdk/syncer/context"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// newTestPatcher returns a patcher that records the dry run decisions it logs
+func newTestPatcher(dryRun bool) (*patcher, *[]map[string]interface{}) {
+	decisions := &[]map[string]interface{}{}
+	logger := funcr.NewJSON(func(obj string) {
+		entry := map[string]interface{}{}
+		if json.Unmarshal([]byte(obj), &entry) == nil && entry["msg"] == "dry run" {
+			*decisions = append(*decisions, entry)
+		}
+	}, funcr.Options{})
+
+	return &patcher{log: log.NewFromExisting(logger, "test"), dryRun: dryRun}, decisions
+}
+
+func TestPatcherDryRun(t *testing.T) {
+	ctx := context.Background()
+	existing := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "existing"}}
+	c := fake.NewClientBuilder().WithObjects(existing).Build()
+	p, decisions := newTestPatcher(true)
+
+	// writes are only dry run and logged
+	err := p.Create(ctx, c, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "new"}})
+	assert.NilError(t, err)
+	err = c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "new"}, &corev1.ConfigMap{})
+	assert.Assert(t, kerrors.IsNotFound(err))
+
+	original := existing.DeepCopy()
+	existing.Data = map[string]string{"key": "value"}
+	err = p.Patch(ctx, c, original, existing, client.MergeFrom(original))
+	assert.NilError(t, err)
+	actual := &corev1.ConfigMap{}
+	assert.NilError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "existing"}, actual))
+	assert.Equal(t, len(actual.Data), 0)
+
+	err = p.Delete(ctx, c, actual)
+	assert.NilError(t, err)
+	assert.NilError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "existing"}, &corev1.ConfigMap{}))
+
+	assert.Equal(t, len(*decisions), 3)
+	for i, decision := range []string{"create", "patch", "delete"} {
+		assert.Equal(t, (*decisions)[i]["decision"], decision)
+	}
+	assert.Equal(t, (*decisions)[0]["name"], "new")
+	assert.Assert(t, (*decisions)[1]["diff"] != "", "patch decision should contain a diff")
+
+	// writes are executed without dry run
+	p, decisions = newTestPatcher(false)
+	err = p.Create(ctx, c, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "new"}})
+	assert.NilError(t, err)
+	assert.NilError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "new"}, &corev1.ConfigMap{}))
+	assert.Equal(t, len(*decisions), 0)
+}
+
+func TestEnsureVirtualNamespaceDryRun(t *testing.T) {
+	existing := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "existing"}}
+	c := fake.NewClientBuilder().WithObjects(existing).Build()
+	ctx := &synccontext.SyncContext{Context: context.Background(), Log: log.New("test")}
+
+	f := newTestFromHostController("", "", true)
+	f.virtualClient = c
+	f.patcher, _ = newTestPatcher(true)
+
+	exists, err := f.ensureVirtualNamespace(ctx, "existing")
+	assert.NilError(t, err)
+	assert.Equal(t, exists, true)
+
+	// a missing namespace would only be created, so objects within it can't be created either
+	exists, err = f.ensureVirtualNamespace(ctx, "missing")
+	assert.NilError(t, err)
+	assert.Equal(t, exists, false)
+	err = c.Get(ctx.Context, types.NamespacedName{Name: "missing"}, &corev1.Namespace{})
+	assert.Assert(t, kerrors.IsNotFound(err))
+
+	f.patcher, _ = newTestPatcher(false)
+	exists, err = f.ensureVirtualNamespace(ctx, "missing")
+	assert.NilError(t, err)
+	assert.Equal(t, exists, true)
+	assert.NilError(t, c.Get(ctx.Context, types.NamespacedName{Name: "missing"}, &corev1.Namespace{}))
+}
+
+func TestApplyReversePatchesDryRun(t *testing.T) {
+	ctx := context.Background()
+	podType := metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"}
+	vObj := &corev1.Pod{TypeMeta: podType, ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "test"}}
+	pObj := &corev1.Pod{
+		TypeMeta:   podType,
+		ObjectMeta: metav1.ObjectMeta{Namespace: "vcluster", Name: "test-x-test-x-suffix", Labels: map[string]string{"a": "b"}},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	c := fake.NewClientBuilder().WithObjects(vObj).Build()
+	reversePatches := []*config.Patch{
+		{Operation: config.PatchTypeCopyFromObject, FromPath: "status.phase", Path: "status.phase"},
+		{Operation: config.PatchTypeCopyFromObject, FromPath: "metadata.labels", Path: "metadata.labels"},
+	}
+
+	// the object is compared after the status update, as the dry run doesn't trigger another reconcile
+	p, decisions := newTestPatcher(true)
+	p.fromClient = c
+	p.statusIsSubresource = true
+	result, err := p.ApplyReversePatches(ctx, vObj, pObj, reversePatches, nil, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, result, controllerutil.OperationResultNone)
+	assert.Equal(t, len(*decisions), 2)
+	assert.Equal(t, (*decisions)[0]["decision"], "update status")
+	assert.Equal(t, (*decisions)[1]["decision"], "update")
+
+	actual := &corev1.Pod{TypeMeta: podType}
+	assert.NilError(t, c.Get(ctx, types.NamespacedName{Namespace: "test", Name: "test"}, actual))
+	assert.Equal(t, actual.Status.Phase, corev1.PodPhase(""))
+	assert.Equal(t, len(actual.Labels), 0)
+
+	// without dry run the status is updated first and the object in the next reconcile
+	p, decisions = newTestPatcher(false)
+	p.fromClient = c
+	p.statusIsSubresource = true
+	result, err = p.ApplyReversePatches(ctx, actual, pObj, reversePatches, nil, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, result, controllerutil.OperationResultUpdatedStatusOnly)
+	assert.Equal(t, len(*decisions), 0)
+}
+
+func TestHasStatusSubresource(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Certificate"}
+	enabled, disabled := true, false
+
+	// the override is used without discovery
+	isSubresource, err := hasStatusSubresource(nil, gvk, &enabled)
+	assert.NilError(t, err)
+	assert.Equal(t, isSubresource, true)
+	isSubresource, err = hasStatusSubresource(nil, gvk, &disabled)
+	assert.NilError(t, err)
+	assert.Equal(t, isSubresource, false)
+
+	discoveryClient := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{Resources: []*metav1.APIResourceList{
+		{
+			GroupVersion: "cert-manager.io/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "certificates", Kind: "Certificate"},
+				{Name: "certificates/status", Kind: "Certificate"},
+				{Name: "issuers", Kind: "Issuer"},
+			},
+		},
+	}}}
+	testCases := []struct {
+		name                  string
+		gvk                   schema.GroupVersionKind
+		expectedSubresource   bool
+		expectedErrorContains string
+	}{
+		{
+			name:                "status subresource",
+			gvk:                 gvk,
+			expectedSubresource: true,
+		},
+		{
+			name: "no status subresource",
+			gvk:  schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Issuer"},
+		},
+		{
+			name:                  "unknown kind",
+			gvk:                   schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Order"},
+			expectedErrorContains: "couldn't find kind Order",
+		},
+		{
+			name:                  "unknown group version",
+			gvk:                   schema.GroupVersionKind{Group: "acme.cert-manager.io", Version: "v1", Kind: "Order"},
+			expectedErrorContains: "discover resources of acme.cert-manager.io/v1",
+		},
+	}
+
+	for _, testCase := range testCases {
+		isSubresource, err := discoverStatusSubresource(discoveryClient, testCase.gvk)
+		if testCase.expectedErrorContains != "" {
+			assert.ErrorContains(t, err, testCase.expectedErrorContains, "in test case %s", testCase.name)
+			continue
+		}
+
+		assert.NilError(t, err, "in test case %s", testCase.name)
+		assert.Equal(t, isSubresource, testCase.expectedSubresource, "in test case %s", testCase.name)
+	}
+}
+
+// recordingClient records the applies of objects and their status
+type recordingClient struct {
+	client.Client
+	applies *[]string
+}
+
+func (c *recordingClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	*c.applies = append(*c.applies, "object")
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+func (c *recordingClient) Status() client.StatusWriter {
+	return &recordingStatusWriter{StatusWriter: c.Client.Status(), applies: c.applies}
+}
+
+type recordingStatusWriter struct {
+	client.StatusWriter
+	applies *[]string
+}
+
+func (w *recordingStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	*w.applies = append(*w.applies, "status")
+	return w.StatusWriter.Patch(ctx, obj, patch, opts...)
+}
+
+func TestApplyPatchesStatusSubresource(t *testing.T) {
+	ctx := context.Background()
+	podType := metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"}
+	vObj := &corev1.Pod{
+		TypeMeta:   podType,
+		ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "test"},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	translate := func(obj client.Object) (client.Object, error) {
+		pObj := obj.DeepCopyObject().(client.Object)
+		pObj.SetNamespace("vcluster")
+		return pObj, nil
+	}
+
+	// the object is applied before its status, as the status of a missing object cannot be applied
+	applies := &[]string{}
+	c := &applyClient{Client: fake.NewClientBuilder().Build()}
+	p, _ := newTestPatcher(false)
+	p.toClient = &recordingClient{Client: c, applies: applies}
+	p.statusIsSubresource = true
+	_, err := p.ApplyPatches(ctx, vObj, nil, nil, nil, translate, nil, nil)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, *applies, []string{"object", "status"})
+
+	actual := &corev1.Pod{}
+	assert.NilError(t, c.Get(ctx, types.NamespacedName{Namespace: "vcluster", Name: "test"}, actual))
+	assert.Equal(t, actual.Status.Phase, corev1.PodRunning)
+
+	// without status subresource the status is applied with the object
+	applies = &[]string{}
+	p.toClient = &recordingClient{Client: &applyClient{Client: fake.NewClientBuilder().Build()}, applies: applies}
+	p.statusIsSubresource = false
+	_, err = p.ApplyPatches(ctx, vObj, nil, nil, nil, translate, nil, nil)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, *applies, []string{"object"})
+}
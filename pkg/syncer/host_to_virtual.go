@@ -0,0 +1,229 @@
+package syncer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/patches"
+	patchesregex "github.com/loft-sh/vcluster-generic-crd-plugin/pkg/patches/regex"
+	"github.com/loft-sh/vcluster-sdk/syncer/translator"
+	"github.com/loft-sh/vcluster-sdk/translate"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// HostToVirtualTranslator reverses the label and namespace translations of the
+// virtualToHostNameResolver. It is embedded by the name resolvers that translate
+// host objects back into virtual objects.
+//
+// Converted label keys are hashes of the original key, so they can only be reversed
+// if the original key is known. The known keys are collected from the virtual objects
+// the translator is created with, e.g. the spec.selector of the virtual object when
+// translating the status.selector of the host object.
+type HostToVirtualTranslator struct {
+	namespace       string
+	targetNamespace string
+
+	// labelKeys maps converted label keys to the original keys
+	labelKeys map[string]string
+}
+
+// NewHostToVirtualTranslator returns a translator for host objects in the target namespace,
+// which translates namespace references into the given virtual namespace
+func NewHostToVirtualTranslator(namespace, targetNamespace string, vObjs ...client.Object) HostToVirtualTranslator {
+	t := HostToVirtualTranslator{
+		namespace:       namespace,
+		targetNamespace: targetNamespace,
+		labelKeys:       map[string]string{},
+	}
+	for _, vObj := range vObjs {
+		if vObj == nil {
+			continue
+		}
+
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(vObj)
+		if err != nil {
+			continue
+		}
+
+		t.addLabelKeys(content)
+	}
+
+	return t
+}
+
+// addLabelKeys registers all map keys of the given value as possible label keys,
+// as label keys might be used anywhere within the object, e.g. in selectors
+func (t *HostToVirtualTranslator) addLabelKeys(value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			t.labelKeys[translator.ConvertLabelKey(key)] = key
+			t.addLabelKeys(child)
+		}
+	case []interface{}:
+		for _, child := range v {
+			t.addLabelKeys(child)
+		}
+	}
+}
+
+// isVClusterLabel returns true for the labels that are added to selectors by the
+// virtualToHostNameResolver and therefore need to be stripped
+func isVClusterLabel(key string) bool {
+	return key == translate.NamespaceLabel || key == translate.MarkerLabel
+}
+
+func (t *HostToVirtualTranslator) TranslateLabelKey(key string) (string, error) {
+	if !strings.HasPrefix(key, translator.LabelPrefix) {
+		return key, nil
+	}
+
+	original, ok := t.labelKeys[key]
+	if !ok {
+		return "", fmt.Errorf("could not translate %s host label key to vcluster label key, because the original key is unknown", key)
+	}
+
+	return original, nil
+}
+
+func (t *HostToVirtualTranslator) TranslateLabelSelector(selector map[string]string) (map[string]string, error) {
+	s := map[string]string{}
+	for k, v := range selector {
+		if isVClusterLabel(k) {
+			continue
+		}
+
+		key, err := t.TranslateLabelKey(k)
+		if err != nil {
+			return nil, err
+		}
+
+		s[key] = v
+	}
+	return s, nil
+}
+
+func (t *HostToVirtualTranslator) TranslateLabelExpressionsSelector(selector *metav1.LabelSelector) (*metav1.LabelSelector, error) {
+	if selector == nil {
+		return nil, nil
+	}
+
+	matchLabels, err := t.TranslateLabelSelector(selector.MatchLabels)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &metav1.LabelSelector{MatchLabels: matchLabels}
+	for _, r := range selector.MatchExpressions {
+		if isVClusterLabel(r.Key) {
+			continue
+		}
+
+		key, err := t.TranslateLabelKey(r.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		s.MatchExpressions = append(s.MatchExpressions, metav1.LabelSelectorRequirement{
+			Key:      key,
+			Operator: r.Operator,
+			Values:   r.Values,
+		})
+	}
+	return s, nil
+}
+
+func (t *HostToVirtualTranslator) TranslateNamespaceRef(namespace string) (string, error) {
+	if namespace != t.targetNamespace {
+		return "", fmt.Errorf("could not translate namespace %s, because only objects in namespace %s are synced", namespace, t.targetNamespace)
+	} else if t.namespace == "" {
+		return "", fmt.Errorf("could not translate namespace %s, because the virtual namespace is unknown", namespace)
+	}
+
+	return t.namespace, nil
+}
+
+// ValidateResolvedNamespace makes sure a name that was resolved together with a namespace
+// reference ends up in the namespace the reference is translated into
+func (t *HostToVirtualTranslator) ValidateResolvedNamespace(name, namespace, resolvedNamespace string) error {
+	err := t.ValidateHostNamespace(name, namespace)
+	if err != nil {
+		return err
+	} else if resolvedNamespace != "" && resolvedNamespace != t.namespace {
+		return fmt.Errorf("could not translate %s/%s host resource name to vcluster resource name, because it belongs to namespace %s instead of %s", namespace, name, resolvedNamespace, t.namespace)
+	}
+
+	return nil
+}
+
+// ValidateHostNamespace makes sure a referenced host object is in the target namespace,
+// as only objects in there can be resolved into virtual objects
+func (t *HostToVirtualTranslator) ValidateHostNamespace(name, namespace string) error {
+	if namespace != t.targetNamespace {
+		return fmt.Errorf("could not translate %s/%s host resource name to vcluster resource name, because only objects in namespace %s are synced", namespace, name, t.targetNamespace)
+	}
+
+	return nil
+}
+
+// NewPhysicalNameResolver returns a name resolver for host objects whose names were translated
+// by vcluster itself, e.g. Secrets or ConfigMaps. Names are translated back by reversing the
+// physical name format, which fails for names that were shortened.
+func NewPhysicalNameResolver(translator HostToVirtualTranslator) patches.NameResolver {
+	return &physicalNameResolver{HostToVirtualTranslator: translator}
+}
+
+type physicalNameResolver struct {
+	HostToVirtualTranslator
+}
+
+func (r *physicalNameResolver) TranslateName(name string, regex *regexp.Regexp, _ string) (string, error) {
+	if regex != nil {
+		return patchesregex.ProcessRegex(regex, name, func(name, _ string) types.NamespacedName {
+			return VirtualNameFromPhysicalName(name)
+		}), nil
+	}
+
+	n := VirtualNameFromPhysicalName(name)
+	if n.Name == "" {
+		return "", fmt.Errorf("could not translate %s host resource name to vcluster resource name", name)
+	}
+
+	return n.Name, nil
+}
+
+func (r *physicalNameResolver) TranslateNameWithNamespace(name string, namespace string, regex *regexp.Regexp, path string) (string, error) {
+	if regex != nil {
+		return r.TranslateName(name, regex, path)
+	}
+
+	n, err := r.TranslateNamespacedName(name, namespace, path)
+	if err != nil {
+		return "", err
+	}
+
+	err = r.ValidateResolvedNamespace(name, namespace, n.Namespace)
+	if err != nil {
+		return "", err
+	}
+
+	return n.Name, nil
+}
+
+func (r *physicalNameResolver) TranslateNamespacedName(name string, namespace string, _ string) (types.NamespacedName, error) {
+	err := r.ValidateHostNamespace(name, namespace)
+	if err != nil {
+		return types.NamespacedName{}, err
+	}
+
+	n := VirtualNameFromPhysicalName(name)
+	if n.Name == "" {
+		return types.NamespacedName{}, fmt.Errorf("could not translate %s/%s host resource name to vcluster resource name", namespace, name)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,141 @@
+package syncer
+
+import (
+	"testing"
+
+	"github.com/loft-sh/vcluster-sdk/syncer/translator"
+	"github.com/loft-sh/vcluster-sdk/translate"
+	"gotest.tools/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// newTestHostToVirtualTranslator returns a translator that knows the label keys of the selector
+// of a virtual object
+func newTestHostToVirtualTranslator(t *testing.T) HostToVirtualTranslator {
+	vObj := &unstructured.Unstructured{}
+	vObj.SetGroupVersionKind(testGVK)
+	vObj.SetNamespace("default")
+	vObj.SetName("test")
+	err := unstructured.SetNestedStringMap(vObj.Object, map[string]string{"app": "test", "example.com/tier": "backend"}, "spec", "selector", "matchLabels")
+	assert.NilError(t, err)
+
+	return NewHostToVirtualTranslator("default", "vcluster", vObj, nil)
+}
+
+func TestTranslateLabelKey(t *testing.T) {
+	hostToVirtual := newTestHostToVirtualTranslator(t)
+
+	type testCase struct {
+		name        string
+		key         string
+		expected    string
+		expectedErr string
+	}
+	testCases := []testCase{
+		{
+			name:     "key that is not converted",
+			key:      "app",
+			expected: "app",
+		},
+		{
+			name:     "converted key",
+			key:      translator.ConvertLabelKey("example.com/tier"),
+			expected: "example.com/tier",
+		},
+		{
+			name:        "converted key of an unknown key",
+			key:         translator.ConvertLabelKey("example.com/unknown"),
+			expectedErr: "because the original key is unknown",
+		},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := hostToVirtual.TranslateLabelKey(testCase.key)
+		if testCase.expectedErr != "" {
+			assert.ErrorContains(t, err, testCase.expectedErr, "test case %s", testCase.name)
+			continue
+		}
+
+		assert.NilError(t, err, "test case %s", testCase.name)
+		assert.Equal(t, actual, testCase.expected, "test case %s", testCase.name)
+	}
+}
+
+func TestTranslateLabelSelector(t *testing.T) {
+	hostToVirtual := newTestHostToVirtualTranslator(t)
+
+	type testCase struct {
+		name        string
+		selector    map[string]string
+		expected    map[string]string
+		expectedErr string
+	}
+	testCases := []testCase{
+		{
+			name:     "empty selector",
+			expected: map[string]string{},
+		},
+		{
+			name: "converted keys and vcluster labels",
+			selector: map[string]string{
+				"app": "test",
+				translator.ConvertLabelKey("example.com/tier"): "backend",
+				translate.NamespaceLabel:                       "default",
+				translate.MarkerLabel:                          translate.Suffix,
+			},
+			expected: map[string]string{"app": "test", "example.com/tier": "backend"},
+		},
+		{
+			name: "unknown converted key",
+			selector: map[string]string{
+				translator.ConvertLabelKey("example.com/unknown"): "value",
+			},
+			expectedErr: "because the original key is unknown",
+		},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := hostToVirtual.TranslateLabelSelector(testCase.selector)
+		if testCase.expectedErr != "" {
+			assert.ErrorContains(t, err, testCase.expectedErr, "test case %s", testCase.name)
+			continue
+		}
+
+		assert.NilError(t, err, "test case %s", testCase.name)
+		assert.DeepEqual(t, actual, testCase.expected)
+	}
+}
+
+func TestTranslateLabelExpressionsSelector(t *testing.T) {
+	hostToVirtual := newTestHostToVirtualTranslator(t)
+
+	actual, err := hostToVirtual.TranslateLabelExpressionsSelector(nil)
+	assert.NilError(t, err)
+	assert.Assert(t, actual == nil)
+
+	actual, err = hostToVirtual.TranslateLabelExpressionsSelector(&metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			translator.ConvertLabelKey("example.com/tier"): "backend",
+			translate.NamespaceLabel:                       "default",
+		},
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: translator.ConvertLabelKey("example.com/tier"), Operator: metav1.LabelSelectorOpIn, Values: []string{"backend"}},
+			{Key: translate.MarkerLabel, Operator: metav1.LabelSelectorOpExists},
+		},
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, actual, &metav1.LabelSelector{
+		MatchLabels: map[string]string{"example.com/tier": "backend"},
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: "example.com/tier", Operator: metav1.LabelSelectorOpIn, Values: []string{"backend"}},
+		},
+	})
+
+	_, err = hostToVirtual.TranslateLabelExpressionsSelector(&metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: translator.ConvertLabelKey("example.com/unknown"), Operator: metav1.LabelSelectorOpExists},
+		},
+	})
+	assert.ErrorContains(t, err, "because the original key is unknown")
+}
@@ -0,0 +1,42 @@
+package syncer
+
+import (
+	"github.com/loft-sh/vcluster-generic-crd-plugin/pkg/patches"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// TracePatchesAnnotation enables the patch trace of a single virtual object if set to true
+	TracePatchesAnnotation = "vcluster.loft.sh/trace-patches"
+
+	// PatchTraceLevel is the log verbosity at which the patch traces of all objects are logged
+	PatchTraceLevel = 4
+)
+
+// newPatchTrace returns a trace if the patches applied between the given objects should be
+// traced, otherwise nil. Objects might be nil, e.g. if the target object doesn't exist yet.
+func newPatchTrace(objs ...client.Object) *patches.Trace {
+	if klog.V(PatchTraceLevel) {
+		return &patches.Trace{}
+	}
+
+	for _, obj := range objs {
+		if obj != nil && obj.GetAnnotations()[TracePatchesAnnotation] == "true" {
+			return &patches.Trace{}
+		}
+	}
+
+	return nil
+}
+
+// logPatchTrace logs every step of the trace as structured output
+func (s *patcher) logPatchTrace(trace *patches.Trace, obj client.Object) {
+	if trace == nil {
+		return
+	}
+
+	for i, step := range trace.Steps {
+		s.log.Base().Info("patch trace", "namespace", obj.GetNamespace(), "name", obj.GetName(), "step", i, "op", step.Operation, "path", step.Path, "conditionsMatched", step.ConditionsMatched, "matches", step.Matches, "diff", step.Diff, "error", step.Error)
+	}
+}
@@ -0,0 +1,76 @@
+package syncer
+
+import (
+	"encoding/json"
+	"time"
+
+	synccontext "github.com/loft-sh/vcluster-sdk/syncer/context"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// SyncStatusAnnotation holds the SyncStatus of a virtual object as json
+	SyncStatusAnnotation = "vcluster.loft.sh/sync-status"
+)
+
+// SyncStatus is the result of the last sync of a virtual object. It is stored on the
+// virtual object, so that sync errors are visible within the virtual cluster.
+type SyncStatus struct {
+	// LastSyncTime is the time the status was last changed
+	LastSyncTime metav1.Time `json:"lastSyncTime"`
+
+	// HostObject is the NAMESPACE/NAME of the host object
+	HostObject string `json:"hostObject"`
+
+	// LastError is the error of the last sync, it is empty if the sync succeeded
+	LastError string `json:"lastError,omitempty"`
+
+	// ObservedGeneration is the generation of the virtual object that was synced
+	ObservedGeneration int64 `json:"observedGeneration"`
+}
+
+// updateSyncStatus records the result of a sync in the SyncStatusAnnotation of the virtual object.
+// The annotation is only changed if the host object, the error or the generation changed, as
+// every change triggers another reconcile of the virtual object. In dry run mode the annotation
+// is only patched as dry run.
+func (s *patcher) updateSyncStatus(ctx *synccontext.SyncContext, vObj client.Object, hostObject types.NamespacedName, syncErr error) {
+	status := SyncStatus{
+		LastSyncTime:       metav1.NewTime(time.Now()),
+		HostObject:         hostObject.String(),
+		ObservedGeneration: vObj.GetGeneration(),
+	}
+	if syncErr != nil {
+		status.LastError = syncErr.Error()
+	}
+
+	annotations := vObj.GetAnnotations()
+	if annotations != nil && annotations[SyncStatusAnnotation] != "" {
+		oldStatus := SyncStatus{}
+		err := json.Unmarshal([]byte(annotations[SyncStatusAnnotation]), &oldStatus)
+		if err == nil && oldStatus.HostObject == status.HostObject && oldStatus.LastError == status.LastError && oldStatus.ObservedGeneration == status.ObservedGeneration {
+			return
+		}
+	}
+
+	rawStatus, err := json.Marshal(status)
+	if err != nil {
+		ctx.Log.Infof("error encoding sync status of %s/%s: %v", vObj.GetNamespace(), vObj.GetName(), err)
+		return
+	}
+
+	newObj := vObj.DeepCopyObject().(client.Object)
+	newAnnotations := newObj.GetAnnotations()
+	if newAnnotations == nil {
+		newAnnotations = map[string]string{}
+	}
+	newAnnotations[SyncStatusAnnotation] = string(rawStatus)
+	newObj.SetAnnotations(newAnnotations)
+
+	err = s.Patch(ctx.Context, ctx.VirtualClient, vObj, newObj, client.MergeFrom(vObj))
+	if err != nil && !kerrors.IsNotFound(err) {
+		ctx.Log.Infof("error updating sync status of %s/%s: %v", vObj.GetNamespace(), vObj.GetName(), err)
+	}
+}
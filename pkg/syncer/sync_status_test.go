@@ -0,0 +1,73 @@
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/loft-sh/vcluster-sdk/log"
+	synccontext "github.com/loft-sh/vcluster-sdk/syncer/context"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestUpdateSyncStatus(t *testing.T) {
+	vObj := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "test", Generation: 1}}
+	c := fake.NewClientBuilder().WithObjects(vObj).Build()
+	ctx := &synccontext.SyncContext{Context: context.Background(), Log: log.New("test"), VirtualClient: c}
+	hostObject := types.NamespacedName{Namespace: "vcluster", Name: "test-x-test-x-suffix"}
+	p, decisions := newTestPatcher(false)
+
+	getStatus := func() (*corev1.ConfigMap, *SyncStatus) {
+		actual := &corev1.ConfigMap{}
+		assert.NilError(t, c.Get(ctx.Context, types.NamespacedName{Namespace: "test", Name: "test"}, actual))
+		if actual.Annotations[SyncStatusAnnotation] == "" {
+			return actual, nil
+		}
+
+		status := &SyncStatus{}
+		assert.NilError(t, json.Unmarshal([]byte(actual.Annotations[SyncStatusAnnotation]), status))
+		return actual, status
+	}
+
+	// the error is recorded
+	p.updateSyncStatus(ctx, vObj, hostObject, fmt.Errorf("sync failed"))
+	actual, status := getStatus()
+	assert.Assert(t, status != nil)
+	assert.Equal(t, status.HostObject, hostObject.String())
+	assert.Equal(t, status.LastError, "sync failed")
+	assert.Equal(t, status.ObservedGeneration, int64(1))
+
+	// an unchanged status is not written again
+	resourceVersion := actual.ResourceVersion
+	p.updateSyncStatus(ctx, actual, hostObject, fmt.Errorf("sync failed"))
+	actual, _ = getStatus()
+	assert.Equal(t, actual.ResourceVersion, resourceVersion)
+
+	// the error is cleared after a successful sync
+	p.updateSyncStatus(ctx, actual, hostObject, nil)
+	actual, status = getStatus()
+	assert.Assert(t, actual.ResourceVersion != resourceVersion)
+	assert.Equal(t, status.LastError, "")
+	assert.Equal(t, status.HostObject, hostObject.String())
+
+	// a new generation is recorded
+	actual.Generation = 2
+	p.updateSyncStatus(ctx, actual, hostObject, nil)
+	_, status = getStatus()
+	assert.Equal(t, status.ObservedGeneration, int64(2))
+	assert.Equal(t, len(*decisions), 0)
+
+	// in dry run mode the status is only logged
+	p, decisions = newTestPatcher(true)
+	actual, _ = getStatus()
+	p.updateSyncStatus(ctx, actual, hostObject, fmt.Errorf("sync failed"))
+	_, status = getStatus()
+	assert.Equal(t, status.LastError, "")
+	assert.Equal(t, len(*decisions), 1)
+	assert.Equal(t, (*decisions)[0]["decision"], "patch")
+}
@@ -0,0 +1,60 @@
+package diff
+
+import (
+	"github.com/pmezard/go-difflib/difflib"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// Unified returns a unified diff of the given texts, which is empty if they are equal
+func Unified(before, after string) (string, error) {
+	if before == after {
+		return "", nil
+	}
+
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(before),
+		B:        difflib.SplitLines(after),
+		FromFile: "before",
+		ToFile:   "after",
+		Context:  3,
+	})
+}
+
+// Objects returns a unified diff of the yaml representations of the given objects. Either
+// object might be nil, e.g. if it is created or deleted. The managed fields are left out,
+// as they change with every write.
+func Objects(before, after client.Object) (string, error) {
+	beforeYAML, err := objectToYAML(before)
+	if err != nil {
+		return "", err
+	}
+	afterYAML, err := objectToYAML(after)
+	if err != nil {
+		return "", err
+	}
+
+	return Unified(beforeYAML, afterYAML)
+}
+
+func objectToYAML(obj client.Object) (string, error) {
+	if obj == nil {
+		return "", nil
+	}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj.DeepCopyObject())
+	if err != nil {
+		return "", err
+	}
+	if metadata, ok := content["metadata"].(map[string]interface{}); ok {
+		delete(metadata, "managedFields")
+	}
+
+	out, err := yaml.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+
+	return string(out), nil
+}
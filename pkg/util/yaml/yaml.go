@@ -59,3 +59,42 @@ func prettifyError(data []byte, err error) error {
 
 	return err
 }
+
+// Lines returns the line of every key and list item within the yaml document, indexed
+// by its path in the document, e.g. mappings[0].fromVirtualCluster.patches[1].path
+func Lines(data []byte) (map[string]int, error) {
+	var node yaml.Node
+	err := yaml.Unmarshal(data, &node)
+	if err != nil {
+		return nil, prettifyError(data, err)
+	}
+
+	lines := map[string]int{}
+	collectLines(&node, "", lines)
+	return lines, nil
+}
+
+func collectLines(node *yaml.Node, path string, lines map[string]int) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			collectLines(child, path, lines)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			childPath := node.Content[i].Value
+			if path != "" {
+				childPath = path + "." + childPath
+			}
+
+			lines[childPath] = node.Content[i].Line
+			collectLines(node.Content[i+1], childPath, lines)
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			childPath := fmt.Sprintf("%s[%d]", path, i)
+			lines[childPath] = child.Line
+			collectLines(child, childPath, lines)
+		}
+	}
+}